@@ -0,0 +1,442 @@
+package main
+
+// cmd/cm-gc/controller.go — long-running controller mode.
+// Instead of listing every namespace on a schedule, `cm-gc controller` keeps
+// Rollouts, ReplicaSets and the metadata of ConfigMaps / Secrets in shared
+// informer caches and re-plans a single Rollout whenever it, one of its
+// ReplicaSets, or one of its candidates changes. Each sync snapshots the
+// Rollout's namespace from the caches and runs the same planner as a one-shot
+// run, restricted to the families the Rollout owns.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/yujen77300/configmap-collector/internal/config"
+	"github.com/yujen77300/configmap-collector/internal/k8s"
+	"github.com/yujen77300/configmap-collector/internal/metrics"
+	"github.com/yujen77300/configmap-collector/internal/naming"
+)
+
+// newControllerCommand returns the `cm-gc controller` subcommand, accepting
+// the same flags as the root command.
+func newControllerCommand(flags *cliFlags, rootFlags *pflag.FlagSet) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "controller",
+		Short: "Run continuously, collecting a Rollout's stale objects as soon as they change",
+		Long: `cm-gc controller watches Rollouts, their ReplicaSets and versioned ConfigMaps
+(and Secrets, with --resource-kinds) through shared informers, and re-plans a
+Rollout whenever any of them changes. Planning, retention, Rollout filters and
+dry-run behave exactly as in a one-shot run; only Rollouts are collected.
+
+The watched namespaces are --namespace, or every namespace matching
+--namespace-selector with --all-namespaces, minus --exclude-namespaces.
+At most --concurrency Rollouts are planned at a time, each within
+--namespace-timeout. Every Rollout is re-planned each --resync-period so
+age-based retention catches up; failed syncs are retried with backoff.
+
+Prometheus metrics are served on --metrics-addr. Deletions, failed deletions
+and dry-run decisions are recorded as Events on the Rollout (--emit-events).
+
+With --leader-elect, replicas elect a leader through a Lease
+(--lease-name, --lease-namespace, default: the namespace cm-gc runs in); only
+the leader plans and deletes, the others keep their caches warm and stand by.
+A leader that fails to renew its Lease within --renew-deadline exits with 1.
+A single deletion may then take at most --lease-duration minus
+--renew-deadline, so that it ends before a standby can take over.
+
+The controller stops on SIGTERM or SIGINT once in-flight deletions finish,
+releasing the Lease it holds.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runController(cmd, flags)
+		},
+		SilenceUsage: true,
+	}
+	cmd.Flags().AddFlagSet(rootFlags)
+	return cmd
+}
+
+// runController is the entry point of the controller subcommand.
+func runController(cmd *cobra.Command, flags *cliFlags) error {
+	cfg, logger := loadConfig(cmd, flags)
+	defer logger.Sync() //nolint:errcheck
+
+	logger.Info("starting cm-gc controller",
+		zap.Strings("namespaces", cfg.Namespaces),
+		zap.Bool("all_namespaces", cfg.AllNamespaces),
+		zap.String("namespace_selector", cfg.NamespaceSelector),
+		zap.Strings("exclude_namespaces", cfg.ExcludeNamespaces),
+		zap.String("app_label", cfg.AppLabel),
+		zap.Int("keep_last", cfg.KeepLast),
+		zap.Int("keep_days", cfg.KeepDays),
+		zap.Bool("dry_run", cfg.DryRun),
+		zap.Strings("resource_kinds", cfg.ResourceKinds),
+		zap.String("retention_mode", cfg.RetentionMode),
+		zap.Strings("rollout_phases", cfg.RolloutPhases),
+		zap.Strings("include_rollouts", cfg.IncludeRollouts),
+		zap.Strings("exclude_rollouts", cfg.ExcludeRollouts),
+		zap.String("rollout_selector", cfg.RolloutSelector),
+		zap.Int("concurrency", cfg.Concurrency),
+		zap.Duration("namespace_timeout", cfg.NamespaceTimeout),
+		zap.Duration("resync_period", cfg.ResyncPeriod),
+		zap.Bool("leader_elect", cfg.LeaderElect),
+		zap.String("lease_name", cfg.LeaseName),
+		zap.String("lease_namespace", cfg.LeaseNamespace),
+		zap.String("metrics_addr", cfg.MetricsAddr),
+		zap.Bool("emit_events", cfg.EmitEvents),
+	)
+	if others := slices.DeleteFunc(slices.Clone(cfg.WorkloadKinds), func(kind string) bool { return kind == k8s.KindRollout }); len(others) > 0 {
+		logger.Warn("controller mode only collects Rollouts — ignoring other workload kinds",
+			zap.Strings("workload_kinds", others),
+		)
+	}
+	if cfg.Report != "" {
+		logger.Warn("controller mode writes no plan report — ignoring --report", zap.String("report", cfg.Report))
+	}
+	if cfg.DryRun {
+		logger.Info("[DRY-RUN] mode enabled — no ConfigMaps or Secrets will be deleted")
+	}
+
+	clients, err := k8s.NewClients()
+	if err != nil {
+		logger.Error("failed to initialise kubernetes clients", zap.Error(err))
+		os.Exit(1)
+	}
+	clients.LabelSelector = cfg.AppLabel
+	clients.PageSize = int64(cfg.PageSize)
+	templates := nameTemplates(cfg, logger)
+
+	events := newEventRecorder(cfg, clients.Kube, logger)
+	c, err := newController(cfg, clients, templates, events, logger)
+	if err != nil {
+		logger.Error("failed to initialise controller", zap.Error(err))
+		os.Exit(1)
+	}
+	// SIGTERM/SIGINT cancel ctx: workers finish their current Rollout and stop.
+	ctx := withSignals(context.Background(), logger)
+	serveMetrics(ctx, cfg, logger)
+	if err := c.startInformers(ctx); err != nil {
+		logger.Error("controller failed", zap.Error(err))
+		os.Exit(1)
+	}
+	if !cfg.LeaderElect {
+		c.work(ctx)
+	} else {
+		err = runLeaderElection(ctx, cfg, clients.Kube, logger, c.work)
+	}
+	flushEvents(events, logger)
+	if err != nil {
+		logger.Error("controller failed", zap.Error(err))
+		os.Exit(1)
+	}
+	logger.Info("controller stopped")
+	return nil
+}
+
+// controller re-plans Rollouts queued by informer events.
+type controller struct {
+	cfg       *config.Config
+	templates map[string]*naming.Template
+	// events records GC Events on Rollouts; nil when disabled.
+	events *k8s.EventRecorder
+	logger *zap.Logger
+	// informers holds the informers of each watched namespace, or a single
+	// entry for metav1.NamespaceAll when namespaces are discovered.
+	informers map[string]*k8s.Informers
+	// namespaces caches the Namespaces matching cfg.NamespaceSelector; nil
+	// unless namespaces are discovered.
+	namespaces         corelisters.NamespaceLister
+	namespacesInformer cache.SharedIndexInformer
+	// queue holds "namespace/name" keys of Rollouts to re-plan.
+	queue workqueue.RateLimitingInterface
+}
+
+// newController creates the informers for the namespaces cfg selects and
+// wires their event handlers to the queue.
+func newController(cfg *config.Config, clients *k8s.Clients, templates map[string]*naming.Template, events *k8s.EventRecorder, logger *zap.Logger) (*controller, error) {
+	c := &controller{
+		cfg:       cfg,
+		templates: templates,
+		events:    events,
+		logger:    logger,
+		informers: make(map[string]*k8s.Informers),
+		queue: workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(),
+			workqueue.RateLimitingQueueConfig{Name: "rollouts"}),
+	}
+	namespaces := cfg.Namespaces
+	if cfg.Discover() {
+		namespaces = []string{metav1.NamespaceAll}
+		factory := informers.NewSharedInformerFactoryWithOptions(clients.Kube, cfg.ResyncPeriod,
+			informers.WithTweakListOptions(func(opts *metav1.ListOptions) { opts.LabelSelector = cfg.NamespaceSelector }))
+		c.namespaces = factory.Core().V1().Namespaces().Lister()
+		c.namespacesInformer = factory.Core().V1().Namespaces().Informer()
+	}
+	for _, ns := range namespaces {
+		if ns != metav1.NamespaceAll && cfg.Excluded(ns) {
+			logger.Info("excluding namespace", zap.String("namespace", ns))
+			continue
+		}
+		inf, err := k8s.NewInformers(clients, ns, cfg.ResourceKinds, cfg.ResyncPeriod)
+		if err != nil {
+			return nil, err
+		}
+		c.addEventHandlers(inf)
+		c.informers[ns] = inf
+	}
+	return c, nil
+}
+
+// addEventHandlers queues a Rollout when it is added or updated (including
+// resyncs), when one of its ReplicaSets is added, deleted or changed in a way
+// a sync depends on (see replicaSetChanged), and when an object of one of its
+// families is added, updated or deleted, since a deletion changes which
+// objects keep-last retains. Deleted Rollouts need no sync: nothing of theirs
+// is left to collect.
+func (c *controller) addEventHandlers(inf *k8s.Informers) {
+	inf.Rollouts.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueueRollout,
+		UpdateFunc: func(_, obj interface{}) { c.enqueueRollout(obj) },
+	})
+	inf.ReplicaSets.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueReplicaSetOwner,
+		UpdateFunc: func(old, obj interface{}) {
+			if replicaSetChanged(old, obj) {
+				c.enqueueReplicaSetOwner(old)
+				c.enqueueReplicaSetOwner(obj)
+			}
+		},
+		DeleteFunc: c.enqueueReplicaSetOwner,
+	})
+	for _, kind := range c.cfg.ResourceKinds {
+		res, tpl := k8s.ConfigMapResource, c.templates[kind]
+		if kind == k8s.ResourceSecret {
+			res = k8s.SecretResource
+		}
+		enqueue := func(obj interface{}) { c.enqueueFamilyOwners(inf, res, tpl, obj) }
+		inf.Candidates[kind].AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    enqueue,
+			UpdateFunc: func(_, obj interface{}) { enqueue(obj) },
+			DeleteFunc: enqueue,
+		})
+	}
+}
+
+// enqueueRollout queues a Rollout.
+func (c *controller) enqueueRollout(obj interface{}) {
+	key, err := cache.MetaNamespaceKeyFunc(obj)
+	if err != nil {
+		c.logger.Warn("failed to queue rollout", zap.Error(err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueReplicaSetOwner queues the Rollout controlling a ReplicaSet, if any.
+func (c *controller) enqueueReplicaSetOwner(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	rs, ok := obj.(*appsv1.ReplicaSet)
+	if !ok {
+		return
+	}
+	if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == k8s.KindRollout {
+		c.queue.Add(rs.Namespace + "/" + owner.Name)
+	}
+}
+
+// replicaSetChanged reports whether a ReplicaSet update changed what a sync
+// reads from it: its pod template, labels, annotations (revision, scale-down
+// deadline) or ownerReferences. Status updates, the bulk of ReplicaSet
+// traffic, and resyncs are ignored.
+func replicaSetChanged(old, obj interface{}) bool {
+	oldRS, ok := old.(*appsv1.ReplicaSet)
+	if !ok {
+		return true
+	}
+	rs, ok := obj.(*appsv1.ReplicaSet)
+	if !ok {
+		return true
+	}
+	return !equality.Semantic.DeepEqual(oldRS.Spec.Template, rs.Spec.Template) ||
+		!maps.Equal(oldRS.Labels, rs.Labels) ||
+		!maps.Equal(oldRS.Annotations, rs.Annotations) ||
+		!equality.Semantic.DeepEqual(oldRS.OwnerReferences, rs.OwnerReferences)
+}
+
+// enqueueFamilyOwners queues every cached Rollout in the object's namespace
+// owning a family the object belongs to. Families are derived from the
+// Rollout's name and desired pod template, read through the same cached
+// RolloutSource a sync uses so that spec.workloadRef Deployments count too.
+func (c *controller) enqueueFamilyOwners(inf *k8s.Informers, res k8s.VersionedResource, tpl *naming.Template, obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	object, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	workloads, err := inf.RolloutSource().ListWorkloads(context.Background(), object.GetNamespace())
+	if err != nil {
+		c.logger.Warn("failed to look up rollouts for candidate", zap.String("name", object.GetName()), zap.Error(err))
+		return
+	}
+	for _, w := range workloads {
+		for _, family := range k8s.WorkloadFamilies(w, res, tpl, c.cfg.StemReferences) {
+			if _, ok := family.Match(object.GetName()); ok {
+				c.queue.Add(object.GetNamespace() + "/" + w.Name)
+				break
+			}
+		}
+	}
+}
+
+// startInformers starts the informers and waits for their caches to sync.
+// Events are queued from then on, whether or not workers run.
+func (c *controller) startInformers(ctx context.Context) error {
+	stop := ctx.Done()
+	if c.namespacesInformer != nil {
+		go c.namespacesInformer.Run(stop)
+		if !cache.WaitForCacheSync(stop, c.namespacesInformer.HasSynced) {
+			return cacheSyncError(ctx)
+		}
+	}
+	for ns, inf := range c.informers {
+		inf.Start(stop)
+		c.logger.Info("waiting for informer caches to sync", zap.String("namespace", ns))
+		if !inf.WaitForCacheSync(stop) {
+			return cacheSyncError(ctx)
+		}
+	}
+	c.logger.Info("informer caches synced")
+	return nil
+}
+
+// work processes the queue on cfg.Concurrency workers until ctx is done, then
+// shuts the queue down once in-flight syncs return.
+func (c *controller) work(ctx context.Context) {
+	defer c.queue.ShutDown()
+	c.logger.Info("processing rollouts", zap.Int("workers", c.cfg.Concurrency))
+	var wg sync.WaitGroup
+	for w := 0; w < c.cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c.processNextItem(ctx) {
+			}
+		}()
+	}
+	<-ctx.Done()
+	c.queue.ShutDown()
+	wg.Wait()
+}
+
+// cacheSyncError returns nil when the caches did not sync because ctx was
+// cancelled by a signal, and an error otherwise.
+func cacheSyncError(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), errInterrupted) {
+		return nil
+	}
+	return fmt.Errorf("informer caches did not sync: %w", context.Cause(ctx))
+}
+
+// processNextItem syncs the next queued Rollout, re-queueing it with backoff
+// when the sync fails. Returns false once the queue is shut down.
+func (c *controller) processNextItem(ctx context.Context) bool {
+	item, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(item)
+	key := item.(string)
+	if err := c.sync(ctx, key); err != nil {
+		c.logger.Warn("rollout sync failed — retrying with backoff",
+			zap.String("rollout", key),
+			zap.Int("retries", c.queue.NumRequeues(item)),
+			zap.Error(err),
+		)
+		c.queue.AddRateLimited(item)
+		return true
+	}
+	c.queue.Forget(item)
+	return true
+}
+
+// sync snapshots the Rollout's namespace from the caches and collects the
+// families the Rollout owns. The other Rollouts of the namespace are part of
+// the snapshot, so they still protect the families they share.
+func (c *controller) sync(ctx context.Context, key string) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return nil
+	}
+	inf, ok := c.informers[ns]
+	if !ok {
+		inf, ok = c.informers[metav1.NamespaceAll]
+	}
+	if !ok || !c.watched(ns) {
+		return nil
+	}
+	obj, exists, err := inf.Rollouts.GetIndexer().GetByKey(key)
+	if err != nil || !exists {
+		return err
+	}
+	r := obj.(*rolloutsv1alpha1.Rollout)
+	logger := c.logger.With(zap.String("namespace", ns), zap.String("rollout", name))
+	defer func(start time.Time) { metrics.SyncDuration.Observe(time.Since(start).Seconds()) }(time.Now())
+
+	candidateClients, err := inf.CandidateClients(c.cfg.ResourceKinds)
+	if err != nil {
+		return err
+	}
+	syncCtx, cancel := withTimeout(ctx, c.cfg.NamespaceTimeout)
+	defer cancel()
+	snap, err := k8s.TakeSnapshot(syncCtx, ns, []k8s.WorkloadSource{inf.RolloutSource()}, candidateClients)
+	if err != nil {
+		return err
+	}
+	if _, failed := collectSnapshot(syncCtx, snap, c.cfg, candidateClients, c.templates, r.UID, c.events, nil, logger); failed {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("gc of rollout %s did not complete", key)
+	}
+	return nil
+}
+
+// watched reports whether a namespace is processed: with discovery, it must
+// match the namespace selector, not be terminating and not be excluded.
+// Namespaces given explicitly were filtered in newController.
+func (c *controller) watched(ns string) bool {
+	if c.namespaces == nil {
+		return true
+	}
+	namespace, err := c.namespaces.Get(ns)
+	if err != nil {
+		return false
+	}
+	return namespace.Status.Phase != corev1.NamespaceTerminating && !c.cfg.Excluded(ns)
+}
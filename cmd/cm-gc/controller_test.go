@@ -0,0 +1,220 @@
+package main
+
+// Tests for controller.go: informer events queue the owning Rollout, a sync
+// collects its families from the caches, and failed syncs are retried.
+// Informers run against fake clientsets for the core API, the metadata API
+// and Argo Rollouts.
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/yujen77300/configmap-collector/internal/k8s"
+)
+
+const seatKey = testNamespace + "/seat"
+
+// seatConfigMaps are the names of the seat family, oldest first; the newest
+// is the one the seat Rollout mounts.
+var seatConfigMaps = []string{"seat-config-00000001", "seat-config-00000002", "seat-config-00000003"}
+
+// controllerFixture runs a controller over fake clientsets.
+type controllerFixture struct {
+	*gcFixture
+	meta *metadatafake.FakeMetadataClient
+	c    *controller
+}
+
+// newControllerFixture starts the informers of a controller watching
+// testNamespace, which holds the seat Rollout and, in the metadata cache, the
+// seatConfigMaps. kubeObjects are what deletions reach.
+func newControllerFixture(t *testing.T, kubeObjects ...runtime.Object) *controllerFixture {
+	t.Helper()
+	var metas []runtime.Object
+	for i, name := range seatConfigMaps {
+		metas = append(metas, &metav1.PartialObjectMetadata{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:         testNamespace,
+				Name:              name,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Duration(len(seatConfigMaps)-i) * 24 * time.Hour)),
+			},
+		})
+	}
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	f := &controllerFixture{
+		gcFixture: &gcFixture{cfg: testConfig(t), kube: fake.NewSimpleClientset(kubeObjects...)},
+		meta:      metadatafake.NewSimpleMetadataClient(scheme, metas...),
+	}
+	f.clients = &k8s.Clients{
+		Kube:     f.kube,
+		Rollout:  rolloutfake.NewSimpleClientset(makeRollout("seat", "uid-seat", seatConfigMaps[2], "00000003")),
+		Metadata: f.meta,
+	}
+	logger := zap.NewNop()
+	c, err := newController(f.cfg, f.clients, nameTemplates(f.cfg, logger), nil, logger)
+	require.NoError(t, err)
+	f.c = c
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	require.NoError(t, c.startInformers(ctx))
+	return f
+}
+
+// drain removes every queued key and returns them.
+func (f *controllerFixture) drain() []string {
+	var keys []string
+	for f.c.queue.Len() > 0 {
+		item, _ := f.c.queue.Get()
+		keys = append(keys, item.(string))
+		f.c.queue.Done(item)
+		f.c.queue.Forget(item)
+	}
+	return keys
+}
+
+// seatObjects returns the seatConfigMaps as API objects.
+func seatObjects() []runtime.Object {
+	var objects []runtime.Object
+	for _, name := range seatConfigMaps {
+		objects = append(objects, makeConfigMap(name, 0))
+	}
+	return objects
+}
+
+// TestController_WorkCollectsQueuedRollout verifies end to end that a cached
+// Rollout is queued and synced by the workers, deleting its stale ConfigMaps.
+func TestController_WorkCollectsQueuedRollout(t *testing.T) {
+	f := newControllerFixture(t, seatObjects()...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f.c.work(ctx)
+	}()
+
+	assert.Eventually(t, func() bool { return len(f.deleted()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.Equal(t, seatConfigMaps[:2], f.deleted())
+}
+
+// TestController_CandidateDeleteQueuesOwner verifies that deleting an object
+// of a Rollout's family queues the Rollout.
+func TestController_CandidateDeleteQueuesOwner(t *testing.T) {
+	f := newControllerFixture(t)
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, []string{seatKey}, f.drain(), "the cached Rollout is queued once synced")
+	assert.Never(t, func() bool { return f.c.queue.Len() > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+
+	configMaps := f.meta.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(testNamespace)
+	require.NoError(t, configMaps.Delete(context.Background(), seatConfigMaps[0], metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond,
+		"deleting a candidate re-plans its family")
+	assert.Equal(t, []string{seatKey}, f.drain())
+}
+
+// TestController_CandidateQueuesWorkloadRefOwner verifies that an object is
+// mapped back to a Rollout mounting its family through spec.workloadRef.
+func TestController_CandidateQueuesWorkloadRefOwner(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "cart-deploy"},
+		Spec:       appsv1.DeploymentSpec{Template: makeRollout("", "", seatConfigMaps[2], "00000003").Spec.Template},
+	}
+	f := newControllerFixture(t, deploy)
+	cart := makeRollout("cart", "uid-cart", "", "")
+	cart.Spec.Template = corev1.PodTemplateSpec{}
+	cart.Spec.WorkloadRef = &rolloutsv1alpha1.ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Name: deploy.Name}
+	_, err := f.clients.Rollout.ArgoprojV1alpha1().Rollouts(testNamespace).Create(context.Background(), cart, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 2 }, 5*time.Second, 10*time.Millisecond)
+	f.drain()
+
+	configMaps := f.meta.Resource(corev1.SchemeGroupVersion.WithResource("configmaps")).Namespace(testNamespace)
+	require.NoError(t, configMaps.Delete(context.Background(), seatConfigMaps[0], metav1.DeleteOptions{}))
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 2 }, 5*time.Second, 10*time.Millisecond,
+		"the candidate queues the Rollout whose workloadRef Deployment mounts its family")
+	keys := f.drain()
+	slices.Sort(keys)
+	assert.Equal(t, []string{testNamespace + "/cart", seatKey}, keys)
+}
+
+// TestController_ReplicaSetUpdatesQueueOwner verifies that a ReplicaSet
+// update queues its Rollout only when the template, labels, annotations or
+// ownerReferences changed, not on status updates.
+func TestController_ReplicaSetUpdatesQueueOwner(t *testing.T) {
+	f := newControllerFixture(t)
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond)
+	f.drain()
+	owner := makeRollout("seat", "uid-seat", "", "")
+	rs := makeReplicaSet(owner, 3, seatConfigMaps[2])
+	rs.OwnerReferences[0].Controller = ptr.To(true)
+	replicaSets := f.kube.AppsV1().ReplicaSets(testNamespace)
+	rs, err := replicaSets.Create(context.Background(), rs, metav1.CreateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond,
+		"a new ReplicaSet re-plans its owner")
+	f.drain()
+
+	rs.Status.Replicas, rs.Status.ReadyReplicas = 3, 3
+	rs, err = replicaSets.UpdateStatus(context.Background(), rs, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Never(t, func() bool { return f.c.queue.Len() > 0 }, 200*time.Millisecond, 10*time.Millisecond,
+		"status updates do not re-plan")
+
+	rs.Annotations["scale-down-deadline"] = time.Now().Format(time.RFC3339)
+	_, err = replicaSets.Update(context.Background(), rs, metav1.UpdateOptions{})
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return f.c.queue.Len() == 1 }, 5*time.Second, 10*time.Millisecond,
+		"an annotation change re-plans the owner")
+	assert.Equal(t, []string{seatKey}, f.drain())
+}
+
+// TestController_SyncTreatsMissingCandidatesAsDeleted verifies that a
+// candidate still cached but already gone from the API server does not fail
+// the sync.
+func TestController_SyncTreatsMissingCandidatesAsDeleted(t *testing.T) {
+	f := newControllerFixture(t)
+
+	require.NoError(t, f.c.sync(context.Background(), seatKey))
+	assert.Equal(t, seatConfigMaps[:2], f.deleted(), "both deletions were attempted")
+}
+
+// TestController_ProcessNextItemRetries verifies that a failed sync is
+// re-queued with backoff, and forgotten once a retry succeeds.
+func TestController_ProcessNextItemRetries(t *testing.T) {
+	f := newControllerFixture(t, seatObjects()...)
+	failing := true
+	f.kube.PrependReactor("delete", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		if failing {
+			return true, nil, errors.New("etcdserver: request timed out")
+		}
+		return false, nil, nil
+	})
+
+	require.True(t, f.c.processNextItem(context.Background()))
+	assert.Equal(t, 1, f.c.queue.NumRequeues(seatKey))
+
+	failing = false
+	require.True(t, f.c.processNextItem(context.Background()), "the retry is queued after its backoff")
+	assert.Equal(t, 0, f.c.queue.NumRequeues(seatKey))
+	assert.Equal(t, 0, f.c.queue.Len())
+}
@@ -0,0 +1,156 @@
+package main
+
+// cmd/cm-gc/leader.go — leader election for controller mode.
+// With --leader-elect, controller replicas compete for a coordination.k8s.io
+// Lease: only the holder runs the workers that plan and delete, while the
+// others keep their informer caches warm and stand by, ready to take over
+// when the Lease is not renewed within --lease-duration.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"go.uber.org/zap"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+
+	"github.com/yujen77300/configmap-collector/internal/config"
+	"github.com/yujen77300/configmap-collector/internal/k8s"
+)
+
+// errLeadershipLost is returned when the Lease could not be renewed: the
+// process exits so that it restarts as a standby.
+var errLeadershipLost = errors.New("leadership lost")
+
+// runLeaderElection calls lead once this instance holds the Lease, with a
+// context cancelled as soon as ctx is done or the Lease is lost. It returns
+// when ctx is done or leadership is lost, always after lead has returned; a
+// held Lease is released only then.
+func runLeaderElection(ctx context.Context, cfg *config.Config, kube kubernetes.Interface, logger *zap.Logger, lead func(context.Context)) error {
+	identity, err := leaderIdentity()
+	if err != nil {
+		return err
+	}
+	namespace := cfg.LeaseNamespace
+	if namespace == "" {
+		namespace = k8s.CurrentNamespace()
+	}
+	logger = logger.With(
+		zap.String("lease", namespace+"/"+cfg.LeaseName),
+		zap.String("identity", identity),
+	)
+
+	// The elector calls OnStartedLeading on its own goroutine and does not
+	// wait for it, and releases the Lease as soon as it stops renewing, before
+	// cancelling the context it gave OnStartedLeading. lead is registered in
+	// running before it starts, never once the Lease is being released, and
+	// the release stops and waits for it: in-flight deletions finish before
+	// another instance can take over.
+	var (
+		mu        sync.Mutex
+		leading   bool
+		releasing bool
+		stopLead  context.CancelFunc = func() {}
+		running   sync.WaitGroup
+	)
+	lock := &releaseAfterLock{
+		Interface: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Namespace: namespace, Name: cfg.LeaseName},
+			Client:     kube.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		wait: func() {
+			mu.Lock()
+			releasing = true
+			stopLead()
+			mu.Unlock()
+			running.Wait()
+		},
+	}
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		LeaseDuration:   cfg.LeaseDuration,
+		RenewDeadline:   cfg.RenewDeadline,
+		RetryPeriod:     cfg.RetryPeriod,
+		ReleaseOnCancel: true,
+		Name:            cfg.LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				mu.Lock()
+				if releasing || ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				ctx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				leading, stopLead = true, cancel
+				running.Add(1)
+				mu.Unlock()
+				defer running.Done()
+				logger.Info("acquired leadership — processing rollouts")
+				lead(ctx)
+			},
+			// Called whenever Run returns, whether or not this instance led.
+			OnStoppedLeading: func() {
+				mu.Lock()
+				defer mu.Unlock()
+				if !leading {
+					return
+				}
+				if ctx.Err() != nil {
+					logger.Info("released leadership")
+					return
+				}
+				logger.Warn("lost leadership — stopping")
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logger.Info("standing by — another instance is leading", zap.String("leader", leader))
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("invalid leader election configuration: %w", err)
+	}
+	logger.Info("waiting for leadership")
+	elector.Run(ctx)
+	lock.wait()
+	// Run returns before ctx is done only when the Lease was lost.
+	if ctx.Err() == nil {
+		return errLeadershipLost
+	}
+	return nil
+}
+
+// releaseAfterLock is a resource lock whose release, an update clearing the
+// holder, first calls wait.
+type releaseAfterLock struct {
+	resourcelock.Interface
+	wait func()
+}
+
+// Update implements resourcelock.Interface.
+func (l *releaseAfterLock) Update(ctx context.Context, record resourcelock.LeaderElectionRecord) error {
+	if record.HolderIdentity == "" {
+		l.wait()
+	}
+	return l.Interface.Update(ctx, record)
+}
+
+// leaderIdentity returns a Lease holder identity unique to this process: the
+// pod name (the hostname, in-cluster) plus a random suffix, so a restarted pod
+// never mistakes its predecessor's Lease for its own.
+func leaderIdentity() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname for leader election: %w", err)
+	}
+	return host + "_" + string(uuid.NewUUID()), nil
+}
@@ -0,0 +1,156 @@
+package main
+
+// Tests for leader.go: an instance leads only while it holds the Lease, and
+// returns, releasing the Lease, only after lead has returned. The Lease lives
+// in a fake clientset.
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/yujen77300/configmap-collector/internal/config"
+)
+
+// leaderConfig returns a configuration electing through a Lease in
+// testNamespace, with durations short enough for tests.
+func leaderConfig(t *testing.T) *config.Config {
+	cfg := testConfig(t)
+	cfg.LeaderElect = true
+	cfg.LeaseNamespace = testNamespace
+	cfg.LeaseDuration = 1500 * time.Millisecond
+	cfg.RenewDeadline = time.Second
+	cfg.RetryPeriod = 100 * time.Millisecond
+	return cfg
+}
+
+// leaseHolder returns the holder of the Lease, "" once released.
+func leaseHolder(t *testing.T, kube *fake.Clientset, cfg *config.Config) string {
+	t.Helper()
+	lease, err := kube.CoordinationV1().Leases(cfg.LeaseNamespace).Get(context.Background(), cfg.LeaseName, metav1.GetOptions{})
+	require.NoError(t, err)
+	return ptr.Deref(lease.Spec.HolderIdentity, "")
+}
+
+// runElection runs runLeaderElection in the background and returns its
+// result channel.
+func runElection(ctx context.Context, cfg *config.Config, kube *fake.Clientset, lead func(context.Context)) <-chan error {
+	result := make(chan error, 1)
+	go func() { result <- runLeaderElection(ctx, cfg, kube, zap.NewNop(), lead) }()
+	return result
+}
+
+// TestRunLeaderElection_ReleasesAfterLead verifies that on shutdown the
+// leader keeps the Lease until lead has returned, and returns only then.
+func TestRunLeaderElection_ReleasesAfterLead(t *testing.T) {
+	cfg := leaderConfig(t)
+	kube := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	started, finish := make(chan struct{}), make(chan struct{})
+	result := runElection(ctx, cfg, kube, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		<-finish // an in-flight deletion
+	})
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lead was not called")
+	}
+	holder := leaseHolder(t, kube, cfg)
+	assert.NotEmpty(t, holder)
+
+	cancel()
+	select {
+	case <-result:
+		t.Fatal("returned while lead was running")
+	case <-time.After(300 * time.Millisecond):
+	}
+	assert.Equal(t, holder, leaseHolder(t, kube, cfg), "the Lease is held while lead runs")
+
+	close(finish)
+	select {
+	case err := <-result:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not return once lead returned")
+	}
+	assert.Empty(t, leaseHolder(t, kube, cfg), "the Lease is released")
+}
+
+// TestRunLeaderElection_LostLeadership verifies that a leader that cannot
+// renew its Lease stops lead, keeps the Lease until lead has returned, and
+// reports errLeadershipLost.
+func TestRunLeaderElection_LostLeadership(t *testing.T) {
+	cfg := leaderConfig(t)
+	kube := fake.NewSimpleClientset()
+	// Once failing is set renewals fail; the release itself goes through.
+	var failing atomic.Bool
+	kube.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		lease := action.(k8stesting.UpdateAction).GetObject().(*coordinationv1.Lease)
+		if !failing.Load() || ptr.Deref(lease.Spec.HolderIdentity, "") == "" {
+			return false, nil, nil
+		}
+		return true, nil, errors.New("etcdserver: request timed out")
+	})
+	started := make(chan struct{})
+	leading := make(chan bool, 1)
+	result := runElection(context.Background(), cfg, kube, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(200 * time.Millisecond) // an in-flight deletion
+		leading <- leaseHolder(t, kube, cfg) != ""
+	})
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("lead was not called")
+	}
+	failing.Store(true)
+
+	select {
+	case err := <-result:
+		assert.ErrorIs(t, err, errLeadershipLost)
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not stop after failing to renew")
+	}
+	assert.True(t, <-leading, "the Lease was held until lead returned")
+	assert.Empty(t, leaseHolder(t, kube, cfg))
+}
+
+// TestRunLeaderElection_Standby verifies that an instance never leads while
+// another holds the Lease, and returns when ctx is done.
+func TestRunLeaderElection_Standby(t *testing.T) {
+	cfg := leaderConfig(t)
+	now := metav1.NewMicroTime(time.Now())
+	kube := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Namespace: cfg.LeaseNamespace, Name: cfg.LeaseName},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("other"),
+			LeaseDurationSeconds: ptr.To(int32(3600)),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	led := false
+	require.NoError(t, runLeaderElection(ctx, cfg, kube, zap.NewNop(), func(context.Context) { led = true }))
+	assert.False(t, led)
+	assert.Equal(t, "other", leaseHolder(t, kube, cfg))
+}
@@ -9,31 +9,75 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
 	"sync"
-	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/yujen77300/configmap-collector/internal/config"
 	"github.com/yujen77300/configmap-collector/internal/k8s"
+	"github.com/yujen77300/configmap-collector/internal/metrics"
+	"github.com/yujen77300/configmap-collector/internal/naming"
 	"github.com/yujen77300/configmap-collector/internal/planner"
+	"github.com/yujen77300/configmap-collector/internal/report"
 )
 
 // cliFlags mirrors config.Config so that Cobra flag values can override the
 // values that Viper loads from the environment.
 type cliFlags struct {
-	namespace string
-	appLabel  string
-	keepLast  int
-	keepDays  int
-	dryRun    bool
-	logLevel  string
-	logFormat string
+	namespace     string
+	appLabel      string
+	keepLast      int
+	keepDays      int
+	dryRun        bool
+	logLevel      string
+	logFormat     string
+	workloadKinds string
+	resourceKinds string
+	retentionMode string
+
+	configMapNameTemplate string
+	secretNameTemplate    string
+	hashLength            int
+	hashAlphabet          string
+	rolloutPhases         string
+	pageSize              int
+	concurrency           int
+	namespaceTimeout      time.Duration
+	runTimeout            time.Duration
+	allNamespaces         bool
+	namespaceSelector     string
+	excludeNamespaces     string
+	includeRollouts       string
+	excludeRollouts       string
+	rolloutSelector       string
+	resyncPeriod          time.Duration
+	leaderElect           bool
+	leaseName             string
+	leaseNamespace        string
+	leaseDuration         time.Duration
+	renewDeadline         time.Duration
+	retryPeriod           time.Duration
+	metricsAddr           string
+	metricsFile           string
+	metricsPushURL        string
+	emitEvents            bool
+	stemReferences        bool
+	report                string
+	reportFormat          string
 }
 
 func main() {
@@ -50,7 +94,79 @@ actual deletions.
 
 Multiple namespaces can be specified as a comma-separated string:
   --namespace=mwpcloud,staging-ns,prod-ns
-or via the NAMESPACE environment variable.`,
+or via the NAMESPACE environment variable.
+
+A cluster-wide run covers every namespace, or those whose labels match a
+selector, minus exclusions (names or glob patterns):
+  --all-namespaces --exclude-namespaces='monitoring,*-sandbox'
+  --namespace-selector='team,env!=sandbox'
+kube-system, kube-public and kube-node-lease are never processed.
+
+Argo Rollouts are collected by default. Deployments, StatefulSets and
+DaemonSets using the same checksum pattern can be enabled with:
+  --workload-kinds=Rollout,Deployment,StatefulSet,DaemonSet
+
+Versioned Secrets ({app}-secret-{hash8}, checksum/secret annotation) are
+collected with the same rules when enabled:
+  --resource-kinds=ConfigMap,Secret
+
+With --retention-mode=revision-history each workload keeps exactly its own
+spec.revisionHistoryLimit + 1 newest objects (keep-last/keep-days are ignored),
+so a rollback always finds its ConfigMap and nothing older is retained.
+
+Object names are parsed with a naming template; {workload} (alias {rollout},
+{name}) is the owning workload and {hash} / {hashN} the content hash:
+  --configmap-name-template='{workload}-cm-{hash10}'
+  --configmap-name-template='{workload}-{hash10}' --hash-alphabet=bcdfghkmt2456789
+Only names that match the template exactly are considered.
+
+Every object referenced by a workload's own pod template whose name matches
+the template is also collected as a family of its own, even when it is not
+named after the workload. With --stem-references, any referenced name ending
+in a hash is a family too, with the hash suffix stripped: a workload mounting
+foo-env-{hash} and shared-nginx-{hash} gets both families.
+
+A run can be scoped to a subset of apps with a label selector, evaluated by
+the API server on workloads and candidates alike; ReplicaSets and
+ControllerRevisions are always listed in full, so they protect what they
+reference whatever their labels:
+  --app-label='app in (seat,order),tier!=batch'
+
+Rollouts that are mid-canary, paused or degraded are left alone: only families
+whose Rollouts are Healthy are collected. Other phases can be opted into with:
+  --rollout-phases=Healthy,Paused
+
+Rollouts can be left alone for good by name glob, by label selector, or by
+annotating the Rollout itself with cm-gc/exclude=true:
+  --include-rollouts='seat-*,order' --exclude-rollouts='*-legacy'
+  --rollout-selector='gc!=off'
+Excluded Rollouts are listed in the run summary.
+
+At most --concurrency namespaces are processed at a time. A namespace that
+exceeds --namespace-timeout, or is still pending or running when --run-timeout
+expires, is abandoned and reported as timed out; the run then exits with 2.
+
+On SIGTERM or SIGINT, deletions already sent to the API server finish, no new
+ones start, a summary is logged and the run exits with 3. A second signal
+terminates immediately.
+
+Prometheus metrics are served on --metrics-addr while the run lasts, and can
+be written at exit to --metrics-file (node exporter textfile collector) or
+pushed to --metrics-pushgateway.
+
+--report writes the plan — every candidate per namespace and rollout, with its
+age, size, in-use status, decision and reason — to a file (- for stdout) as
+--report-format json, yaml, table or markdown, so plans can be diffed between
+runs. Planned deletions are reported with their outcome: deleted, failed or
+not-attempted (run stopped), or delete in a dry run. Sizing lists each
+namespace's ConfigMaps or Secrets once.
+
+Deletions, failed deletions and dry-run "would delete" decisions are recorded
+as Kubernetes Events on the owning workloads, one per family and outcome, so
+they show in kubectl describe rollout (--emit-events=false to disable).
+
+To collect continuously instead of once per invocation, run the controller
+subcommand: cm-gc controller --help.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return run(cmd, flags)
 		},
@@ -60,12 +176,46 @@ or via the NAMESPACE environment variable.`,
 	// Register flags; all have env-var equivalents loaded via Viper in config.Load().
 	// --namespace accepts a comma-separated list: "mwpcloud,staging-ns,prod-ns"
 	rootCmd.Flags().StringVar(&flags.namespace, "namespace", "", "Comma-separated target namespaces (env: NAMESPACE, default: mwpcloud)")
-	rootCmd.Flags().StringVar(&flags.appLabel, "app-label", "", "App label value to match (env: APP_LABEL, default: xzk0-seat)")
+	rootCmd.Flags().BoolVar(&flags.allNamespaces, "all-namespaces", false, "Process every namespace in the cluster instead of --namespace (env: ALL_NAMESPACES, default: false)")
+	rootCmd.Flags().StringVar(&flags.namespaceSelector, "namespace-selector", "", "Label selector on Namespace objects; processes the matching namespaces instead of --namespace (env: NAMESPACE_SELECTOR)")
+	rootCmd.Flags().StringVar(&flags.excludeNamespaces, "exclude-namespaces", "", "Comma-separated namespace names or glob patterns never processed (env: EXCLUDE_NAMESPACES)")
+	rootCmd.Flags().StringVar(&flags.appLabel, "app-label", "", "Label selector scoping workloads and candidates, e.g. 'app in (seat,order)'; a bare value means app=<value> (env: APP_LABEL, default: all)")
 	rootCmd.Flags().IntVar(&flags.keepLast, "keep-last", 0, "Keep N newest ConfigMaps regardless of age (env: KEEP_LAST, default: 5)")
 	rootCmd.Flags().IntVar(&flags.keepDays, "keep-days", 0, "Keep ConfigMaps newer than N days (env: KEEP_DAYS, default: 7)")
 	rootCmd.Flags().BoolVar(&flags.dryRun, "dry-run", true, "Log actions without deleting (env: DRY_RUN, default: true)")
 	rootCmd.Flags().StringVar(&flags.logLevel, "log-level", "", "Log level: debug|info|warn|error (env: LOG_LEVEL, default: info)")
 	rootCmd.Flags().StringVar(&flags.logFormat, "log-format", "", "Log format: text|json (env: LOG_FORMAT, default: text)")
+	rootCmd.Flags().StringVar(&flags.workloadKinds, "workload-kinds", "", "Comma-separated workload kinds to collect: Rollout,Deployment,StatefulSet,DaemonSet (env: WORKLOAD_KINDS, default: Rollout)")
+	rootCmd.Flags().StringVar(&flags.resourceKinds, "resource-kinds", "", "Comma-separated resource kinds to collect: ConfigMap,Secret (env: RESOURCE_KINDS, default: ConfigMap)")
+	rootCmd.Flags().StringVar(&flags.retentionMode, "retention-mode", "", "Retention mode: keep-last|revision-history (env: RETENTION_MODE, default: keep-last)")
+	rootCmd.Flags().StringVar(&flags.configMapNameTemplate, "configmap-name-template", "", "ConfigMap naming template (env: CONFIGMAP_NAME_TEMPLATE, default: {workload}-config-{hash})")
+	rootCmd.Flags().StringVar(&flags.secretNameTemplate, "secret-name-template", "", "Secret naming template (env: SECRET_NAME_TEMPLATE, default: {workload}-secret-{hash})")
+	rootCmd.Flags().IntVar(&flags.hashLength, "hash-length", 0, "Hash length for templates using a bare {hash} (env: HASH_LENGTH, default: 8)")
+	rootCmd.Flags().StringVar(&flags.hashAlphabet, "hash-alphabet", "", "Characters a hash may consist of (env: HASH_ALPHABET, default: 0123456789abcdef)")
+	rootCmd.Flags().BoolVar(&flags.stemReferences, "stem-references", false, "Collect every referenced name ending in a hash as a family, even when it does not match the naming template (env: STEM_REFERENCES, default: false)")
+	rootCmd.Flags().IntVar(&flags.pageSize, "page-size", 0, "Objects fetched per List request, 0 to disable pagination (env: PAGE_SIZE, default: 500)")
+	rootCmd.Flags().StringVar(&flags.includeRollouts, "include-rollouts", "", "Comma-separated Rollout name globs to collect; others are left alone (env: ROLLOUT_INCLUDE, default: all)")
+	rootCmd.Flags().StringVar(&flags.excludeRollouts, "exclude-rollouts", "", "Comma-separated Rollout name globs never collected (env: ROLLOUT_EXCLUDE)")
+	rootCmd.Flags().StringVar(&flags.rolloutSelector, "rollout-selector", "", "Label selector Rollouts must match to be collected (env: ROLLOUT_SELECTOR, default: all)")
+	rootCmd.Flags().IntVar(&flags.concurrency, "concurrency", 0, "Namespaces processed in parallel (env: CONCURRENCY, default: 10)")
+	rootCmd.Flags().DurationVar(&flags.namespaceTimeout, "namespace-timeout", 0, "Time limit per namespace, 0 for none (env: NAMESPACE_TIMEOUT, default: 5m)")
+	rootCmd.Flags().DurationVar(&flags.runTimeout, "run-timeout", 0, "Time limit for the whole run, 0 for none (env: RUN_TIMEOUT, default: 30m)")
+	rootCmd.Flags().DurationVar(&flags.resyncPeriod, "resync-period", 0, "Controller mode: how often every Rollout is re-planned, 0 for never (env: RESYNC_PERIOD, default: 1h)")
+	rootCmd.Flags().BoolVar(&flags.leaderElect, "leader-elect", false, "Controller mode: elect a leader through a Lease so only one replica deletes (env: LEADER_ELECT, default: false)")
+	rootCmd.Flags().StringVar(&flags.leaseName, "lease-name", "", "Controller mode: name of the leader election Lease (env: LEASE_NAME, default: cm-gc)")
+	rootCmd.Flags().StringVar(&flags.leaseNamespace, "lease-namespace", "", "Controller mode: namespace of the leader election Lease (env: LEASE_NAMESPACE, default: the namespace cm-gc runs in)")
+	rootCmd.Flags().DurationVar(&flags.leaseDuration, "lease-duration", 0, "Controller mode: how long standbys wait before taking over an unrenewed Lease (env: LEASE_DURATION, default: 15s)")
+	rootCmd.Flags().DurationVar(&flags.renewDeadline, "renew-deadline", 0, "Controller mode: how long the leader retries renewing its Lease before stopping (env: RENEW_DEADLINE, default: 10s)")
+	rootCmd.Flags().DurationVar(&flags.retryPeriod, "retry-period", 0, "Controller mode: wait between Lease acquire and renew attempts (env: RETRY_PERIOD, default: 2s)")
+	rootCmd.Flags().StringVar(&flags.metricsAddr, "metrics-addr", "", "Address to serve Prometheus /metrics on, e.g. :9090 (env: METRICS_ADDR, default: disabled)")
+	rootCmd.Flags().StringVar(&flags.metricsFile, "metrics-file", "", "File the metrics are written to at exit, for the node exporter textfile collector (env: METRICS_FILE, default: disabled)")
+	rootCmd.Flags().StringVar(&flags.metricsPushURL, "metrics-pushgateway", "", "Pushgateway URL the metrics are pushed to at exit (env: METRICS_PUSHGATEWAY, default: disabled)")
+	rootCmd.Flags().BoolVar(&flags.emitEvents, "emit-events", true, "Record Kubernetes Events on the owning workloads for deletions, failed deletions and dry-run decisions (env: EMIT_EVENTS, default: true)")
+	rootCmd.Flags().StringVar(&flags.report, "report", "", "File the plan report is written to at exit, - for stdout (env: REPORT, default: disabled)")
+	rootCmd.Flags().StringVar(&flags.reportFormat, "report-format", "", "Plan report format: json, yaml, table or markdown (env: REPORT_FORMAT, default: json)")
+	rootCmd.Flags().StringVar(&flags.rolloutPhases, "rollout-phases", "", "Comma-separated Rollout phases to collect: Healthy,Progressing,Paused,Degraded (env: ROLLOUT_PHASES, default: Healthy)")
+
+	rootCmd.AddCommand(newControllerCommand(flags, rootCmd.Flags()))
 
 	if err := rootCmd.Execute(); err != nil {
 		os.Exit(1)
@@ -74,35 +224,48 @@ or via the NAMESPACE environment variable.`,
 
 // run is the main execution logic, separated from main() for testability.
 func run(cmd *cobra.Command, flags *cliFlags) error {
-	// 1. Load config from env/defaults, then override with CLI flags.
-	cfg, err := config.Load()
-	if err != nil {
-		cmd.PrintErrf("failed to load config: %v\n", err)
-		os.Exit(1)
-	}
-	applyFlagOverrides(cmd, flags, cfg)
-
-	// 2. Build structured logger.
-	logger, err := buildLogger(cfg.LogLevel, cfg.LogFormat)
-	if err != nil {
-		cmd.PrintErrf("failed to build logger: %v\n", err)
-		os.Exit(1)
-	}
+	// 1–2. Load config and build the logger.
+	cfg, logger := loadConfig(cmd, flags)
 	defer logger.Sync() //nolint:errcheck
 
 	// 3. Log startup configuration.
 	logger.Info("starting cm-gc",
 		zap.Strings("namespaces", cfg.Namespaces),
+		zap.Bool("all_namespaces", cfg.AllNamespaces),
+		zap.String("namespace_selector", cfg.NamespaceSelector),
+		zap.Strings("exclude_namespaces", cfg.ExcludeNamespaces),
 		zap.String("app_label", cfg.AppLabel),
 		zap.Int("keep_last", cfg.KeepLast),
 		zap.Int("keep_days", cfg.KeepDays),
 		zap.Bool("dry_run", cfg.DryRun),
 		zap.String("log_level", cfg.LogLevel),
 		zap.String("log_format", cfg.LogFormat),
+		zap.Strings("workload_kinds", cfg.WorkloadKinds),
+		zap.Strings("resource_kinds", cfg.ResourceKinds),
+		zap.String("retention_mode", cfg.RetentionMode),
+		zap.String("configmap_name_template", cfg.ConfigMapNameTemplate),
+		zap.String("secret_name_template", cfg.SecretNameTemplate),
+		zap.Int("hash_length", cfg.HashLength),
+		zap.String("hash_alphabet", cfg.HashAlphabet),
+		zap.Bool("stem_references", cfg.StemReferences),
+		zap.Strings("rollout_phases", cfg.RolloutPhases),
+		zap.Strings("include_rollouts", cfg.IncludeRollouts),
+		zap.Strings("exclude_rollouts", cfg.ExcludeRollouts),
+		zap.String("rollout_selector", cfg.RolloutSelector),
+		zap.Int("page_size", cfg.PageSize),
+		zap.Int("concurrency", cfg.Concurrency),
+		zap.Duration("namespace_timeout", cfg.NamespaceTimeout),
+		zap.Duration("run_timeout", cfg.RunTimeout),
+		zap.String("metrics_addr", cfg.MetricsAddr),
+		zap.String("metrics_file", cfg.MetricsFile),
+		zap.String("metrics_pushgateway", cfg.MetricsPushURL),
+		zap.Bool("emit_events", cfg.EmitEvents),
+		zap.String("report", cfg.Report),
+		zap.String("report_format", cfg.ReportFormat),
 	)
 
 	if cfg.DryRun {
-		logger.Info("[DRY-RUN] mode enabled — no ConfigMaps will be deleted")
+		logger.Info("[DRY-RUN] mode enabled — no ConfigMaps or Secrets will be deleted")
 	}
 
 	// 4. Initialise Kubernetes clients.
@@ -111,148 +274,807 @@ func run(cmd *cobra.Command, flags *cliFlags) error {
 		logger.Error("failed to initialise kubernetes clients", zap.Error(err))
 		os.Exit(1)
 	}
+	clients.LabelSelector = cfg.AppLabel
+	clients.PageSize = int64(cfg.PageSize)
 
-	ctx := context.Background()
-	cmClient := k8s.NewKubeConfigMapClient(clients.Kube)
-	rsClient := k8s.NewKubeReplicaSetClient(clients.Kube)
-	rolloutClient := k8s.NewKubeRolloutClient(clients.Rollout)
+	candidateClients := make([]k8s.CandidateClient, 0, len(cfg.ResourceKinds))
+	for _, kind := range cfg.ResourceKinds {
+		client, err := k8s.NewCandidateClient(kind, clients)
+		if err != nil {
+			logger.Error("failed to initialise candidate client", zap.Error(err))
+			os.Exit(1)
+		}
+		candidateClients = append(candidateClients, client)
+	}
+	templates := nameTemplates(cfg, logger)
+	sources, err := k8s.NewWorkloadSources(cfg.WorkloadKinds, clients)
+	if err != nil {
+		logger.Error("failed to initialise workload sources", zap.Error(err))
+		os.Exit(1)
+	}
 
-	// 5. Process each namespace concurrently.
-	// anyFailed is set to 1 if any namespace encounters a partial deletion error.
-	var anyFailed atomic.Int32
-	var wg sync.WaitGroup
+	events := newEventRecorder(cfg, clients.Kube, logger)
+	rep := newReport(cfg, time.Now())
+
+	// 5. Process the namespaces on a pool of cfg.Concurrency workers, within
+	// the run timeout.
+	// SIGTERM/SIGINT cancel ctx: no new namespace, family or deletion starts.
+	ctx, cancel := withTimeout(withSignals(context.Background(), logger), cfg.RunTimeout)
+	defer cancel()
+	serveMetrics(ctx, cfg, logger)
+	start := time.Now()
+	namespaces, err := targetNamespaces(ctx, cfg, clients, logger)
+	if err != nil {
+		logger.Error("failed to list namespaces", zap.Error(err))
+		os.Exit(1)
+	}
+	summary := runNamespaces(ctx, namespaces, cfg, logger, func(ctx context.Context, ns string, nsLogger *zap.Logger) ([]string, bool) {
+		return runForNamespace(ctx, ns, cfg, candidateClients, templates, sources, events, rep, nsLogger)
+	})
+
+	logger.Info("run summary",
+		zap.Int("namespaces", len(namespaces)),
+		zap.Int("failed", len(summary.failed)),
+		zap.Strings("failed_namespaces", summary.failed),
+		zap.Int("timed_out", len(summary.timedOut)),
+		zap.Strings("timed_out_namespaces", summary.timedOut),
+		zap.Int("interrupted", len(summary.interrupted)),
+		zap.Strings("interrupted_namespaces", summary.interrupted),
+		zap.Int("excluded_rollouts", len(summary.excludedRollouts)),
+		zap.Strings("excluded_rollout_names", summary.excludedRollouts),
+	)
+	metrics.RunDuration.Observe(time.Since(start).Seconds())
+	flushEvents(events, logger)
+	exportMetrics(cfg, logger)
+	reportFailed := !writeReport(cfg, rep, logger)
+	if code := exitCode(ctx, summary, reportFailed); code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// exitCode returns the exit code of a run over ctx: exitInterrupted after a
+// signal, exitFailed when a namespace failed or timed out or the report could
+// not be written, 0 otherwise.
+func exitCode(ctx context.Context, summary runSummary, reportFailed bool) int {
+	switch {
+	case errors.Is(context.Cause(ctx), errInterrupted):
+		return exitInterrupted
+	case len(summary.failed) > 0 || len(summary.timedOut) > 0 || reportFailed:
+		return exitFailed
+	}
+	return 0
+}
+
+// loadConfig loads the configuration from env/defaults, overrides it with the
+// CLI flags set on cmd, and builds the logger. Exits with 1 on invalid input.
+func loadConfig(cmd *cobra.Command, flags *cliFlags) (*config.Config, *zap.Logger) {
+	// 1. Load config from env/defaults, then override with CLI flags.
+	cfg, err := config.Load()
+	if err != nil {
+		cmd.PrintErrf("failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := applyFlagOverrides(cmd, flags, cfg); err != nil {
+		cmd.PrintErrf("invalid flags: %v\n", err)
+		os.Exit(1)
+	}
 
-	for _, ns := range cfg.Namespaces {
+	// 2. Build structured logger.
+	logger, err := buildLogger(cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		cmd.PrintErrf("failed to build logger: %v\n", err)
+		os.Exit(1)
+	}
+	if cfg.RetentionMode == config.RetentionRevisionHistory {
+		logger.Info("revision-history retention — keep_last and keep_days are ignored",
+			zap.Int("keep_last", cfg.KeepLast),
+			zap.Int("keep_days", cfg.KeepDays),
+		)
+	}
+	return cfg, logger
+}
+
+// nameTemplates parses the naming template of every enabled resource kind.
+// Exits with 1 on an invalid template.
+func nameTemplates(cfg *config.Config, logger *zap.Logger) map[string]*naming.Template {
+	templates := make(map[string]*naming.Template, len(cfg.ResourceKinds))
+	for _, kind := range cfg.ResourceKinds {
+		tpl, err := naming.New(cfg.NameTemplate(kind), cfg.HashLength, cfg.HashAlphabet)
+		if err != nil {
+			logger.Error("failed to parse naming template", zap.String("resource", kind), zap.Error(err))
+			os.Exit(1)
+		}
+		templates[kind] = tpl
+	}
+	return templates
+}
+
+// serveMetrics serves /metrics on cfg.MetricsAddr, if set, until ctx is done.
+func serveMetrics(ctx context.Context, cfg *config.Config, logger *zap.Logger) {
+	if cfg.MetricsAddr == "" {
+		return
+	}
+	logger.Info("serving metrics", zap.String("addr", cfg.MetricsAddr))
+	go func() {
+		if err := metrics.Serve(ctx, cfg.MetricsAddr); err != nil {
+			logger.Error("metrics endpoint failed", zap.Error(err))
+		}
+	}()
+}
+
+// exportMetrics dumps the metrics of a finished run to cfg.MetricsFile and
+// cfg.MetricsPushURL, if set. Failures are logged but do not fail the run.
+func exportMetrics(cfg *config.Config, logger *zap.Logger) {
+	if cfg.MetricsFile != "" {
+		if err := metrics.WriteTextfile(cfg.MetricsFile); err != nil {
+			logger.Error("failed to write metrics file", zap.String("path", cfg.MetricsFile), zap.Error(err))
+		}
+	}
+	if cfg.MetricsPushURL != "" {
+		if err := metrics.Push(cfg.MetricsPushURL); err != nil {
+			logger.Error("failed to push metrics", zap.String("url", cfg.MetricsPushURL), zap.Error(err))
+		}
+	}
+}
+
+// Exit codes besides 0 and 1 (invalid configuration or failed setup).
+const (
+	// exitFailed: a namespace failed (e.g. a deletion error) or timed out.
+	exitFailed = 2
+	// exitInterrupted: the run was stopped by SIGTERM or SIGINT.
+	exitInterrupted = 3
+)
+
+// errInterrupted is the cancellation cause of a run stopped by a signal.
+var errInterrupted = errors.New("interrupted by signal")
+
+// newEventRecorder returns the recorder of GC Events, or nil when
+// cfg.EmitEvents is off.
+func newEventRecorder(cfg *config.Config, kube kubernetes.Interface, logger *zap.Logger) *k8s.EventRecorder {
+	if !cfg.EmitEvents {
+		return nil
+	}
+	return k8s.NewEventRecorder(kube, func(event *corev1.Event, err error) {
+		logger.Warn("failed to record event",
+			zap.String("namespace", event.InvolvedObject.Namespace),
+			zap.String("object", event.InvolvedObject.Kind+"/"+event.InvolvedObject.Name),
+			zap.String("reason", event.Reason),
+			zap.Error(err),
+		)
+	})
+}
+
+// flushEvents waits for the recorded Events to be sent, within
+// eventFlushTimeout, so that none is lost at exit.
+func flushEvents(events *k8s.EventRecorder, logger *zap.Logger) {
+	if events == nil {
+		return
+	}
+	if !events.Flush(eventFlushTimeout) {
+		logger.Warn("timed out sending events — some may be lost", zap.Duration("timeout", eventFlushTimeout))
+	}
+}
+
+// newReport returns the plan report of a run started at now, or nil when
+// cfg.Report is empty.
+func newReport(cfg *config.Config, now time.Time) *report.Report {
+	if cfg.Report == "" {
+		return nil
+	}
+	return report.New(now, cfg.DryRun)
+}
+
+// writeReport writes rep to cfg.Report in cfg.ReportFormat, when a report was
+// requested, and reports whether it succeeded.
+func writeReport(cfg *config.Config, rep *report.Report, logger *zap.Logger) bool {
+	if rep == nil {
+		return true
+	}
+	if cfg.Report == "-" {
+		if err := rep.Write(os.Stdout, cfg.ReportFormat); err != nil {
+			logger.Error("failed to write report", zap.Error(err))
+			return false
+		}
+		return true
+	}
+	f, err := os.Create(cfg.Report)
+	if err == nil {
+		err = rep.Write(f, cfg.ReportFormat)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		logger.Error("failed to write report", zap.String("path", cfg.Report), zap.Error(err))
+		return false
+	}
+	logger.Info("wrote report", zap.String("path", cfg.Report), zap.String("format", cfg.ReportFormat))
+	return true
+}
+
+// eventFlushTimeout bounds the wait for pending Events at exit.
+const eventFlushTimeout = 10 * time.Second
+
+// withSignals returns a context cancelled with errInterrupted on the first
+// SIGTERM or SIGINT. The signal handler is then removed, so a second signal
+// terminates the process immediately.
+func withSignals(parent context.Context, logger *zap.Logger) context.Context {
+	ctx, cancel := context.WithCancelCause(parent)
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		sig := <-signals
+		signal.Stop(signals)
+		logger.Warn("received signal — finishing in-flight deletions, starting no new ones",
+			zap.String("signal", sig.String()),
+		)
+		cancel(errInterrupted)
+	}()
+	return ctx
+}
+
+// targetNamespaces returns the namespaces to process: those discovered in the
+// cluster when cfg.Discover(), cfg.Namespaces otherwise, minus exclusions.
+func targetNamespaces(ctx context.Context, cfg *config.Config, clients *k8s.Clients, logger *zap.Logger) ([]string, error) {
+	excluded := func(ns string) bool {
+		if cfg.Excluded(ns) {
+			logger.Info("excluding namespace", zap.String("namespace", ns))
+			return true
+		}
+		return false
+	}
+	if !cfg.Discover() {
+		var namespaces []string
+		for _, ns := range cfg.Namespaces {
+			if !excluded(ns) {
+				namespaces = append(namespaces, ns)
+			}
+		}
+		return namespaces, nil
+	}
+	namespaces, err := k8s.ListNamespaces(ctx, clients, cfg.NamespaceSelector, excluded)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("discovered namespaces",
+		zap.String("namespace_selector", cfg.NamespaceSelector),
+		zap.Strings("namespaces", namespaces),
+	)
+	return namespaces, nil
+}
+
+// runSummary lists the namespaces of a run that did not complete cleanly, in
+// the order they were given.
+type runSummary struct {
+	failed      []string
+	timedOut    []string
+	interrupted []string
+	// excludedRollouts are the "namespace/name" of the Rollouts the Rollout
+	// filters excluded.
+	excludedRollouts []string
+}
+
+// runNamespaces runs gc for every namespace in namespaces on at most
+// cfg.Concurrency goroutines, each namespace within cfg.NamespaceTimeout of
+// its start and all within ctx. Namespaces cut short or never started because
+// ctx was done are reported as timed out, or as interrupted when ctx was
+// cancelled with errInterrupted.
+func runNamespaces(
+	ctx context.Context,
+	namespaces []string,
+	cfg *config.Config,
+	logger *zap.Logger,
+	gc func(ctx context.Context, ns string, logger *zap.Logger) (excluded []string, failed bool),
+) runSummary {
+	const (
+		statusOK = iota
+		statusFailed
+		statusTimedOut
+		statusInterrupted
+	)
+	// stopped returns the status of a namespace whose context is done.
+	stopped := func(ctx context.Context) int {
+		if errors.Is(context.Cause(ctx), errInterrupted) {
+			return statusInterrupted
+		}
+		return statusTimedOut
+	}
+	status := make([]int, len(namespaces))
+	excluded := make([][]string, len(namespaces))
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(cfg.Concurrency, len(namespaces)); w++ {
 		wg.Add(1)
-		go func(ns string) {
+		go func() {
 			defer wg.Done()
-			nsLogger := logger.With(zap.String("namespace", ns))
-			if failed := runForNamespace(ctx, ns, cfg, cmClient, rsClient, rolloutClient, nsLogger); failed {
-				anyFailed.Store(1)
+			for i := range queue {
+				ns := namespaces[i]
+				nsLogger := logger.With(zap.String("namespace", ns))
+				if ctx.Err() != nil {
+					nsLogger.Warn("run stopped — namespace not processed", zap.Error(context.Cause(ctx)))
+					status[i] = stopped(ctx)
+					continue
+				}
+				nsCtx, cancel := withTimeout(ctx, cfg.NamespaceTimeout)
+				var nsFailed bool
+				excluded[i], nsFailed = gc(nsCtx, ns, nsLogger)
+				if nsCtx.Err() != nil {
+					nsLogger.Warn("namespace stopped before completion",
+						zap.Error(context.Cause(nsCtx)),
+						zap.Duration("namespace_timeout", cfg.NamespaceTimeout),
+						zap.Duration("run_timeout", cfg.RunTimeout),
+					)
+					status[i] = stopped(nsCtx)
+				} else if nsFailed {
+					status[i] = statusFailed
+				}
+				cancel()
 			}
-		}(ns)
+		}()
 	}
-
+	for i := range namespaces {
+		queue <- i
+	}
+	close(queue)
 	wg.Wait()
 
-	if anyFailed.Load() != 0 {
-		os.Exit(2)
+	var summary runSummary
+	for i, ns := range namespaces {
+		switch status[i] {
+		case statusFailed:
+			summary.failed = append(summary.failed, ns)
+		case statusTimedOut:
+			summary.timedOut = append(summary.timedOut, ns)
+		case statusInterrupted:
+			summary.interrupted = append(summary.interrupted, ns)
+		}
+		for _, name := range excluded[i] {
+			summary.excludedRollouts = append(summary.excludedRollouts, ns+"/"+name)
+		}
 	}
-	return nil
+	return summary
+}
+
+// withTimeout returns a context bounded by timeout, or merely cancellable when
+// timeout is 0.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
 }
 
 // runForNamespace executes the full GC cycle for a single namespace:
-//  1. List all Rollouts → auto-derive ConfigMap prefix per Rollout
-//  2. For each Rollout: list prefix-matched CMs → resolve in-use checksums →
-//     plan → delete (or dry-run log).
+//  1. Take one snapshot of the namespace: the workloads of each enabled kind
+//     with their retained pod-template revisions (ReplicaSets /
+//     ControllerRevisions), and the metadata of every object of each enabled
+//     resource kind (ConfigMap, Secret). Each API resource is listed once,
+//     however many workloads and families the namespace holds.
+//  2. Derive each workload's candidate families from its name and from its
+//     desired pod template, and resolve referenced objects and checksums from
+//     the snapshot's revisions.
+//  3. For each resource kind and family: look up prefix-matched objects in the
+//     snapshot → keep exact family matches → mark in-use → plan → delete (or
+//     dry-run log).
 //
 // Returns true if any deletion failed (caller should exit with code 2).
 func runForNamespace(
 	ctx context.Context,
 	ns string,
 	cfg *config.Config,
-	cmClient k8s.ConfigMapClient,
-	rsClient *k8s.KubeReplicaSetClient,
-	rolloutClient *k8s.KubeRolloutClient,
+	candidateClients []k8s.CandidateClient,
+	templates map[string]*naming.Template,
+	sources []k8s.WorkloadSource,
+	events *k8s.EventRecorder,
+	rep *report.Report,
 	logger *zap.Logger,
-) (anyFailed bool) {
-	// 5. Discover all Rollout names in the namespace.
-	// Each Rollout "foo" manages ConfigMaps with prefix "foo-config-".
-	rolloutNames, err := rolloutClient.ListRolloutNames(ctx, ns)
+) (excluded []string, anyFailed bool) {
+	// 5. Snapshot the namespace: all workloads of the enabled kinds, their
+	// revisions, and every candidate. Each workload "foo" manages the objects
+	// its naming templates resolve to, e.g. "foo-config-{hash}".
+	snap, err := k8s.TakeSnapshot(ctx, ns, sources, candidateClients)
 	if err != nil {
-		logger.Error("failed to list rollouts", zap.Error(err))
-		return true
+		logger.Error("failed to snapshot namespace", zap.Error(err))
+		return nil, true
+	}
+	workloads := snap.Workloads
+	for _, source := range sources {
+		list := snap.WorkloadsOfKind(source.Kind())
+		names := make([]string, 0, len(list))
+		for _, w := range list {
+			names = append(names, w.Name)
+		}
+		logger.Info("discovered workloads",
+			zap.String("kind", source.Kind()),
+			zap.Strings("workloads", names),
+		)
 	}
-	logger.Info("discovered rollouts",
-		zap.Strings("rollouts", rolloutNames),
-	)
 
-	if len(rolloutNames) == 0 {
-		logger.Info("no rollouts found in namespace — nothing to do")
-		return false
+	if len(workloads) == 0 {
+		logger.Info("no workloads found in namespace — nothing to do")
+		return nil, false
 	}
+	return collectSnapshot(ctx, snap, cfg, candidateClients, templates, "", events, rep, logger)
+}
 
-	// 6. Resolve in-use checksums once for all Rollout-owned ReplicaSets
-	// in the namespace (a single API call covers all Rollouts).
-	resolver := k8s.NewInUseResolver(rsClient)
-	checksums, err := resolver.Resolve(ctx, ns)
-	if err != nil {
-		logger.Error("failed to resolve in-use checksums", zap.Error(err))
-		return true
+// collectSnapshot runs steps 6–7 of runForNamespace over a snapshot: it holds
+// back workloads with an undecodable revision and excluded, unresolved and
+// non-allowed-phase Rollouts, resolves in-use objects, and collects every
+// candidate family. When only is set, just the
+// families owned by that workload are collected, and only its exclusion,
+// hold-back or deferral is logged; the other workloads still protect shared
+// families.
+func collectSnapshot(
+	ctx context.Context,
+	snap *k8s.Snapshot,
+	cfg *config.Config,
+	candidateClients []k8s.CandidateClient,
+	templates map[string]*naming.Template,
+	only types.UID,
+	events *k8s.EventRecorder,
+	rep *report.Report,
+	logger *zap.Logger,
+) (excluded []string, anyFailed bool) {
+	ns, workloads := snap.Namespace, snap.Workloads
+	logged := func(w k8s.Workload) bool { return only == "" || w.UID == only }
+	nsReport := newNamespaceReport(rep, ns, logger)
+
+	// Workloads with a revision that could not be decoded reference unknown
+	// objects; Rollouts excluded by the Rollout filters are never collected;
+	// Rollouts whose spec.workloadRef cannot be resolved have an unknown
+	// desired template; Rollouts outside the allowed phases (by default:
+	// anything but Healthy) are mid-update or need attention. Their families
+	// are skipped for this run, with the reason held; their revisions still
+	// count as in use for shared families.
+	held := make(map[types.UID]string)
+	deferred, unresolved, undecoded := 0, 0, 0
+	for _, w := range workloads {
+		if w.RevisionError != nil {
+			held[w.UID] = skipReasonRevision
+			if !logged(w) {
+				continue
+			}
+			undecoded++
+			logger.Warn("holding back workload — revision not decoded",
+				zap.String("workload", w.Kind+"/"+w.Name),
+				zap.Error(w.RevisionError),
+			)
+			continue
+		}
+		if w.Kind != k8s.KindRollout {
+			continue
+		}
+		if reason := cfg.RolloutExclusion(w.Name, w.Labels, w.Annotations); reason != "" {
+			held[w.UID] = skipReasonExcluded
+			if !logged(w) {
+				continue
+			}
+			excluded = append(excluded, w.Name)
+			logger.Info("excluding rollout",
+				zap.String("rollout", w.Name),
+				zap.String("filter", reason),
+			)
+			continue
+		}
+		if w.TemplateError != nil {
+			held[w.UID] = skipReasonWorkloadRef
+			if !logged(w) {
+				continue
+			}
+			unresolved++
+			logger.Warn("holding back rollout — workloadRef not resolved",
+				zap.String("rollout", w.Name),
+				zap.Error(w.TemplateError),
+			)
+			continue
+		}
+		if !slices.Contains(cfg.RolloutPhases, w.Phase) {
+			held[w.UID] = skipReasonRolloutPhase
+			if !logged(w) {
+				continue
+			}
+			deferred++
+			logger.Info("deferring gc for rollout",
+				zap.String("rollout", w.Name),
+				zap.String("phase", w.Phase),
+				zap.String("message", w.Message),
+				zap.Strings("allowed_phases", cfg.RolloutPhases),
+			)
+		}
 	}
-	logger.Info("resolved in-use checksums from rollout replicasets",
-		zap.Int("count", len(checksums)),
-		zap.Strings("checksums", mapKeys(checksums)),
+
+	// 6. Resolve in-use objects and checksums once for all workload revisions
+	// in the snapshot, grouped by owning workload so each workload is only
+	// protected by its own revisions. In revision-history mode only the
+	// revisions within each workload's revisionHistoryLimit count.
+	resources := make([]k8s.VersionedResource, 0, len(candidateClients))
+	for _, client := range candidateClients {
+		resources = append(resources, client.Resource())
+	}
+	revisions := snap.Revisions
+	if cfg.RetentionMode == config.RetentionRevisionHistory {
+		revisions = k8s.RetainedRevisions(workloads, revisions)
+	}
+	inUseSets := k8s.ScopeRevisions(revisions, resources...)
+	logger.Info("resolved objects referenced by workload revisions",
+		zap.Int("owners", inUseSets.Owners()),
 	)
 
-	// 7. Process each Rollout independently using its auto-derived prefix.
-	for _, rolloutName := range rolloutNames {
-		prefix := rolloutName + "-config-"
-		rolloutLogger := logger.With(zap.String("rollout", rolloutName), zap.String("prefix", prefix))
-		if failed := runForRollout(ctx, ns, prefix, cfg, cmClient, checksums, rolloutLogger); failed {
-			anyFailed = true
+	// 7. Process each candidate family independently. Families come from the
+	// naming template of each workload and from the versioned objects its
+	// desired pod template references; a family shared by several workloads
+	// is processed once on behalf of all of them, and skipped entirely when
+	// any of them is held.
+	skipped := 0
+	for _, client := range candidateClients {
+		res := client.Resource()
+		tpl := templates[res.Kind]
+		for _, group := range k8s.GroupFamilies(workloads, res, tpl, cfg.StemReferences) {
+			if only != "" && !slices.ContainsFunc(group.Workloads, logged) {
+				continue
+			}
+			if ctx.Err() != nil {
+				logger.Warn("stopping namespace before remaining families", zap.Error(ctx.Err()))
+				return excluded, true
+			}
+			if blocking, reasons := heldOwners(group.Workloads, held); len(blocking) > 0 {
+				logger.Info("skipping family — owning workload held back",
+					zap.String("resource", res.Kind),
+					zap.String("family", group.Family.Key()),
+					zap.Strings("workloads", blocking),
+					zap.Strings("reasons", reasons),
+				)
+				if nsReport != nil {
+					reason := strings.Join(slices.Compact(slices.Sorted(slices.Values(reasons))), "; ")
+					reportSkippedFamily(ctx, nsReport, snap, group, client, inUseSets, reason)
+				}
+				skipped++
+				continue
+			}
+			keepLast, keepDays := retentionFor(cfg)
+			familyLogger := logger.With(
+				zap.String("resource", res.Kind),
+				zap.String("family", group.Family.Key()),
+				zap.Strings("workloads", workloadNames(group.Workloads)),
+				zap.Int("keep_last", keepLast),
+				zap.Int("keep_days", keepDays),
+			)
+			listed := snap.Candidates(res.Kind, group.Family.Prefix())
+			if failed := runForFamily(ctx, ns, group, listed, keepLast, keepDays, cfg, client, inUseSets, events, nsReport, familyLogger); failed {
+				anyFailed = true
+			}
 		}
 	}
-	return anyFailed
+	if deferred > 0 || unresolved > 0 || undecoded > 0 || len(excluded) > 0 {
+		logger.Info("namespace summary",
+			zap.Int("deferred_rollouts", deferred),
+			zap.Int("unresolved_rollouts", unresolved),
+			zap.Int("undecoded_workloads", undecoded),
+			zap.Strings("excluded_rollouts", excluded),
+			zap.Int("skipped_families", skipped),
+		)
+	}
+	return excluded, anyFailed
+}
+
+// Reasons a held workload's families were not collected.
+const (
+	skipReasonRolloutPhase = "rollout not in an allowed phase"
+	skipReasonExcluded     = "rollout excluded by filter"
+	skipReasonWorkloadRef  = "rollout workloadRef not resolved"
+	skipReasonRevision     = "workload revision not decoded"
+)
+
+// heldOwners returns "Kind/name" of each workload in the held set, and the
+// reason each is held.
+func heldOwners(workloads []k8s.Workload, held map[types.UID]string) (names, reasons []string) {
+	for _, w := range workloads {
+		if reason, ok := held[w.UID]; ok {
+			names = append(names, w.Kind+"/"+w.Name)
+			reasons = append(reasons, reason)
+		}
+	}
+	return names, reasons
+}
+
+// retentionFor returns the keep-last / keep-days pair for a family. In
+// revision-history mode nothing is kept by count or age: the in-use set is
+// built from each workload's retained revisions only (see
+// k8s.RetainedRevisions), so exactly what they reference is kept.
+func retentionFor(cfg *config.Config) (keepLast, keepDays int) {
+	if cfg.RetentionMode == config.RetentionRevisionHistory {
+		return 0, 0
+	}
+	return cfg.KeepLast, cfg.KeepDays
+}
+
+// workloadNames returns "Kind/name" for each workload, for logging.
+func workloadNames(workloads []k8s.Workload) []string {
+	names := make([]string, 0, len(workloads))
+	for _, w := range workloads {
+		names = append(names, w.Kind+"/"+w.Name)
+	}
+	return names
 }
 
-// runForRollout runs the GC cycle for a single Rollout within a namespace,
-// using the pre-resolved checksum set shared across all Rollouts.
-func runForRollout(
+// familyOwners returns the comma-separated names of a family's owning
+// workloads, the "rollout" label of its metrics.
+func familyOwners(workloads []k8s.Workload) string {
+	names := make([]string, 0, len(workloads))
+	for _, w := range workloads {
+		names = append(names, w.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// setPlanMetrics sets the protected and would-delete gauges of a family from
+// the planner decisions, zeroing the reasons no candidate was kept for.
+func setPlanMetrics(ns, rollouts, resource string, decisions map[string]planner.Decision) {
+	protected := make(map[planner.Reason]int, len(planner.KeepReasons))
+	wouldDelete := 0
+	for _, d := range decisions {
+		if d.Delete {
+			wouldDelete++
+		} else {
+			protected[d.Reason]++
+		}
+	}
+	for _, reason := range planner.KeepReasons {
+		metrics.Protected.WithLabelValues(ns, rollouts, resource, string(reason)).Set(float64(protected[reason]))
+	}
+	metrics.WouldDelete.WithLabelValues(ns, rollouts, resource).Set(float64(wouldDelete))
+}
+
+// runForFamily runs the GC cycle for one candidate family within a namespace,
+// given the snapshot's objects sharing the family prefix. Candidates are
+// matched only against the in-use set resolved from the revisions of the
+// workloads owning the family.
+func runForFamily(
 	ctx context.Context,
 	ns string,
-	prefix string,
+	group k8s.FamilyGroup,
+	listed []metav1.ObjectMeta,
+	keepLast, keepDays int,
 	cfg *config.Config,
-	cmClient k8s.ConfigMapClient,
-	checksums map[string]bool,
+	client k8s.CandidateClient,
+	inUseSets k8s.ScopedInUseSets,
+	events *k8s.EventRecorder,
+	rep *namespaceReport,
 	logger *zap.Logger,
 ) (anyFailed bool) {
-	// List only ConfigMaps matching this Rollout's auto-derived prefix.
-	candidateCMs, err := cmClient.ListConfigMaps(ctx, ns, prefix)
-	if err != nil {
-		logger.Error("failed to list configmaps")
-		return true
+	// listed holds only objects sharing the family prefix; keep the exact
+	// family matches: "foo-{hash10}" must not pick up "foo-bar-{hash10}".
+	// Only metadata is listed — object data never reaches this function.
+	objects := make([]metav1.ObjectMeta, 0, len(listed))
+	hashes := make(map[string]string, len(listed))
+	for _, obj := range listed {
+		hash, ok := group.Family.Match(obj.Name)
+		if !ok {
+			logger.Debug("skipping object outside the candidate family",
+				zap.String("name", obj.Name),
+			)
+			continue
+		}
+		objects = append(objects, obj)
+		hashes[obj.Name] = hash
 	}
-	logger.Info("discovered configmaps matching prefix",
-		zap.Int("count", len(candidateCMs)),
+	logger.Info("discovered candidates in family",
+		zap.Int("count", len(objects)),
 	)
+	resource := client.Resource().Kind
+	rollouts := familyOwners(group.Workloads)
+	metrics.Candidates.WithLabelValues(ns, rollouts, resource).Set(float64(len(objects)))
 
-	if len(candidateCMs) == 0 {
-		logger.Info("no configmaps found matching prefix — nothing to do")
+	if len(objects) == 0 {
+		setPlanMetrics(ns, rollouts, resource, nil)
+		logger.Info("no candidates found in family — nothing to do")
 		return false
 	}
 
-	// Build the inUse set (keyed by full CM name) for this Rollout's candidates.
+	// Build the inUse set (keyed by full object name) for this family's
+	// candidates. An object is in use when any retained pod template in the
+	// namespace references it by name, or when the hash parsed from its name
+	// equals one of the checksum annotations of the family's owners exactly.
+	owners := make([]types.UID, 0, len(group.Workloads))
+	for _, w := range group.Workloads {
+		owners = append(owners, w.UID)
+	}
+	inUseSet := inUseSets.For(client.Resource().Kind, owners...)
+	logger.Debug("objects referenced by workload revisions",
+		zap.Strings("names", mapKeys(inUseSet.Names)),
+		zap.Strings("checksums", mapKeys(inUseSet.Checksums)),
+	)
 	inUse := make(map[string]bool)
-	for _, cm := range candidateCMs {
-		for checksum := range checksums {
-			if strings.Contains(cm.Name, checksum) {
-				inUse[cm.Name] = true
-				break
-			}
+	for _, obj := range objects {
+		if inUseSet.Matches(obj.Name, hashes[obj.Name]) {
+			inUse[obj.Name] = true
+			continue
+		}
+		if others := inUseSets.OtherOwners(client.Resource().Kind, obj.Name, hashes[obj.Name], owners...); len(others) > 0 {
+			logger.Debug("ignored checksum match from another workload's revisions",
+				zap.String("name", obj.Name),
+				zap.Strings("matched_by", others),
+			)
 		}
 	}
-	logger.Debug("in-use configmaps (referenced by replicasets)",
-		zap.Strings("configmaps", mapKeys(inUse)),
+	logger.Debug("in-use candidates (referenced by workload revisions)",
+		zap.Strings("names", mapKeys(inUse)),
 	)
 
+	// Candidates referenced by a ReplicaSet the Rollout strategy relies on
+	// (stable, canary, blue-green active/preview, scaleDownDelay) or by a
+	// running Experiment or AnalysisRun are protected with their own reason
+	// so they are reported as such.
+	strategySet := inUseSets.StrategyFor(client.Resource().Kind, owners...)
+	strategy := make(map[string]bool)
+	for _, obj := range objects {
+		if strategySet.Matches(obj.Name, hashes[obj.Name]) {
+			strategy[obj.Name] = true
+		}
+	}
+
 	// Build planner candidates.
-	candidates := make([]planner.ConfigMapCandidate, 0, len(candidateCMs))
-	for _, cm := range candidateCMs {
-		candidates = append(candidates, planner.ConfigMapCandidate{
-			Name:              cm.Name,
-			CreationTimestamp: cm.CreationTimestamp.Time,
-			Annotations:       cm.Annotations,
+	candidates := make([]planner.Candidate, 0, len(objects))
+	for _, obj := range objects {
+		candidates = append(candidates, planner.Candidate{
+			Name:              obj.Name,
+			CreationTimestamp: obj.CreationTimestamp.Time,
+			Annotations:       obj.Annotations,
 		})
 	}
 
-	toDelete := planner.Plan(candidates, inUse, cfg.KeepLast, cfg.KeepDays, time.Now())
+	var toDelete []string
+	decisions := make(map[string]planner.Decision)
+	for _, d := range planner.Explain(candidates, inUse, strategy, keepLast, keepDays, time.Now()) {
+		decisions[d.Name] = d
+		if d.Delete {
+			toDelete = append(toDelete, d.Name)
+		}
+		if d.Reason == planner.ReasonStrategy {
+			logger.Info("candidate protected by rollout strategy",
+				zap.String("name", d.Name),
+				zap.String("reason", string(d.Reason)),
+			)
+		}
+	}
+	setPlanMetrics(ns, rollouts, resource, decisions)
+	// The report records each candidate once the family is done, planned
+	// deletions with their outcome: attempted holds the error of each
+	// deletion sent, nil when it succeeded.
+	attempted := make(map[string]error)
+	if rep != nil {
+		// Size the namespace's objects while the candidates still exist.
+		rep.sizesOf(ctx, client)
+		defer func() {
+			now := time.Now()
+			for _, obj := range objects {
+				d := decisions[obj.Name]
+				decision, reason := report.DecisionKeep, string(d.Reason)
+				if d.Delete {
+					err, ok := attempted[obj.Name]
+					switch {
+					case cfg.DryRun:
+						decision = report.DecisionDelete
+					case !ok:
+						decision = report.DecisionNotAttempted
+					case err != nil:
+						decision, reason = report.DecisionFailed, err.Error()
+					default:
+						decision = report.DecisionDeleted
+					}
+				}
+				rep.add(ctx, group, client, obj, now, inUse[obj.Name] || strategy[obj.Name], decision, reason)
+			}
+		}()
+	}
 	logger.Info("planner result",
 		zap.Int("candidates_for_deletion", len(toDelete)),
+		zap.Int("protected_by_strategy", len(strategy)),
 	)
 
 	if len(toDelete) == 0 {
-		logger.Info("no configmaps eligible for deletion — done")
+		logger.Info("no candidates eligible for deletion — done")
 		return false
 	}
 
@@ -260,22 +1082,22 @@ func runForRollout(
 	now := time.Now()
 	for _, name := range toDelete {
 		var age time.Duration
-		for _, cm := range candidateCMs {
-			if cm.Name == name {
-				age = now.Sub(cm.CreationTimestamp.Time)
+		for _, obj := range objects {
+			if obj.Name == name {
+				age = now.Sub(obj.CreationTimestamp.Time)
 				break
 			}
 		}
 		ageDays := int(age.Hours() / 24)
 		if cfg.DryRun {
-			logger.Info("[DRY-RUN] would delete configmap",
-				zap.String("configmap", name),
+			logger.Info("[DRY-RUN] would delete candidate",
+				zap.String("name", name),
 				zap.Int("age_days", ageDays),
-				zap.String("reason", "not in-use, not in keep-last, older than keep-days"),
+				zap.String("reason", string(decisions[name].Reason)),
 			)
 		} else {
-			logger.Info("deleting configmap",
-				zap.String("configmap", name),
+			logger.Info("deleting candidate",
+				zap.String("name", name),
 				zap.Int("age_days", ageDays),
 			)
 		}
@@ -286,39 +1108,217 @@ func runForRollout(
 		logger.Info("[DRY-RUN] completed — no deletions performed",
 			zap.Int("would_delete", len(toDelete)),
 		)
+		recordFamilyEvent(events, ns, group, corev1.EventTypeNormal, k8s.EventReasonWouldDelete,
+			fmt.Sprintf("[dry-run] would delete %d %s(s) of family %s: %s", len(toDelete), resource, group.Family.Key(), k8s.EventNames(toDelete)))
 		return false
 	}
 
-	deleted := 0
-	for _, name := range toDelete {
-		if err := cmClient.DeleteConfigMap(ctx, ns, name); err != nil {
-			logger.Error("failed to delete configmap",
-				zap.String("configmap", name),
+	// A deletion already sent finishes even when ctx is cancelled meanwhile,
+	// within deleteTimeout; once ctx is done no further deletion starts.
+	timeout := deleteTimeout(cfg)
+	deleted, skipped := 0, 0
+	var deletedNames, failedNames []string
+	var firstErr error
+	for i, name := range toDelete {
+		if ctx.Err() != nil {
+			skipped = len(toDelete) - i
+			logger.Warn("run stopped — remaining deletions skipped",
+				zap.Strings("skipped", toDelete[i:]),
+				zap.Error(context.Cause(ctx)),
+			)
+			anyFailed = true
+			break
+		}
+		deleteCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+		err := client.DeleteCandidate(deleteCtx, ns, name)
+		cancel()
+		attempted[name] = err
+		if err != nil {
+			logger.Error("failed to delete candidate",
+				zap.String("name", name),
 				zap.Error(err),
 			)
+			metrics.DeletionFailures.WithLabelValues(ns, rollouts, resource).Inc()
+			failedNames = append(failedNames, name)
+			if firstErr == nil {
+				firstErr = err
+			}
 			anyFailed = true
 			continue
 		}
-		logger.Info("deleted configmap", zap.String("configmap", name))
+		logger.Info("deleted candidate", zap.String("name", name))
+		metrics.Deletions.WithLabelValues(ns, rollouts, resource).Inc()
+		deletedNames = append(deletedNames, name)
 		deleted++
 	}
+	if len(deletedNames) > 0 {
+		recordFamilyEvent(events, ns, group, corev1.EventTypeNormal, k8s.EventReasonDeleted,
+			fmt.Sprintf("Deleted %d %s(s) of family %s: %s", len(deletedNames), resource, group.Family.Key(), k8s.EventNames(deletedNames)))
+	}
+	if len(failedNames) > 0 {
+		recordFamilyEvent(events, ns, group, corev1.EventTypeWarning, k8s.EventReasonDeleteFailed,
+			fmt.Sprintf("Failed to delete %d %s(s) of family %s: %s: %v", len(failedNames), resource, group.Family.Key(), k8s.EventNames(failedNames), firstErr))
+	}
 
 	logger.Info("gc completed",
 		zap.Int("deleted", deleted),
-		zap.Int("failed", len(toDelete)-deleted),
+		zap.Int("failed", len(toDelete)-deleted-skipped),
+		zap.Int("skipped", skipped),
 	)
 	return anyFailed
 }
 
+// reportSkippedFamily records in rep the candidates of a family held back for
+// reason, as skipped.
+func reportSkippedFamily(
+	ctx context.Context,
+	rep *namespaceReport,
+	snap *k8s.Snapshot,
+	group k8s.FamilyGroup,
+	client k8s.CandidateClient,
+	inUseSets k8s.ScopedInUseSets,
+	reason string,
+) {
+	owners := make([]types.UID, 0, len(group.Workloads))
+	for _, w := range group.Workloads {
+		owners = append(owners, w.UID)
+	}
+	kind := client.Resource().Kind
+	inUseSet := inUseSets.For(kind, owners...)
+	strategySet := inUseSets.StrategyFor(kind, owners...)
+	now := time.Now()
+	for _, obj := range snap.Candidates(kind, group.Family.Prefix()) {
+		hash, ok := group.Family.Match(obj.Name)
+		if !ok {
+			continue
+		}
+		inUse := inUseSet.Matches(obj.Name, hash) || strategySet.Matches(obj.Name, hash)
+		rep.add(ctx, group, client, obj, now, inUse, report.DecisionSkip, reason)
+	}
+}
+
+// namespaceReport records the candidates of one namespace in a plan report.
+// Candidates are sized when client is a k8s.CandidateSizer, from one List per
+// resource kind made the first time a candidate of that kind is recorded.
+type namespaceReport struct {
+	rep    *report.Report
+	ns     string
+	sizes  map[string]map[string]int64
+	logger *zap.Logger
+}
+
+// newNamespaceReport returns the recorder of namespace ns's candidates in
+// rep, or nil when rep is nil.
+func newNamespaceReport(rep *report.Report, ns string, logger *zap.Logger) *namespaceReport {
+	if rep == nil {
+		return nil
+	}
+	return &namespaceReport{rep: rep, ns: ns, sizes: make(map[string]map[string]int64), logger: logger}
+}
+
+// add records obj, a candidate of group listed by client.
+func (r *namespaceReport) add(
+	ctx context.Context,
+	group k8s.FamilyGroup,
+	client k8s.CandidateClient,
+	obj metav1.ObjectMeta,
+	now time.Time,
+	inUse bool,
+	decision, reason string,
+) {
+	c := report.Candidate{
+		Resource:  client.Resource().Kind,
+		Family:    group.Family.Key(),
+		Name:      obj.Name,
+		CreatedAt: obj.CreationTimestamp.UTC(),
+		AgeDays:   int(now.Sub(obj.CreationTimestamp.Time).Hours() / 24),
+		InUse:     inUse,
+		Decision:  decision,
+		Reason:    reason,
+	}
+	if size, ok := r.sizesOf(ctx, client)[obj.Name]; ok {
+		c.SizeBytes = &size
+	}
+	r.rep.Add(r.ns, familyOwners(group.Workloads), c)
+}
+
+// sizesOf returns the sizes of the namespace's objects listed by client,
+// listing them on first use. A failed listing is logged once and leaves
+// the candidates of that kind unsized.
+func (r *namespaceReport) sizesOf(ctx context.Context, client k8s.CandidateClient) map[string]int64 {
+	kind := client.Resource().Kind
+	if sizes, ok := r.sizes[kind]; ok {
+		return sizes
+	}
+	var sizes map[string]int64
+	if sizer, ok := client.(k8s.CandidateSizer); ok {
+		var err error
+		sizes, err = sizer.CandidateSizes(ctx, r.ns)
+		if err != nil {
+			r.logger.Warn("failed to size candidates for the report", zap.String("resource", kind), zap.Error(err))
+		}
+	}
+	r.sizes[kind] = sizes
+	return sizes
+}
+
+// recordFamilyEvent records an Event on each workload owning group, unless
+// events is nil.
+func recordFamilyEvent(events *k8s.EventRecorder, ns string, group k8s.FamilyGroup, eventType, reason, message string) {
+	if events == nil {
+		return
+	}
+	for _, w := range group.Workloads {
+		events.Record(ns, w, eventType, reason, message)
+	}
+}
+
+// deleteGracePeriod bounds a single deletion, which is not cancelled with the
+// run so that it never stops halfway.
+const deleteGracePeriod = 30 * time.Second
+
+// deleteTimeout returns how long a single deletion may take. With leader
+// election it is at most LeaseDuration−RenewDeadline, the time a leader that
+// failed to renew still holds the Lease, so that a deletion started while
+// leading ends before a standby can take over.
+func deleteTimeout(cfg *config.Config) time.Duration {
+	if !cfg.LeaderElect {
+		return deleteGracePeriod
+	}
+	return min(deleteGracePeriod, cfg.LeaseDuration-cfg.RenewDeadline)
+}
+
 // applyFlagOverrides replaces cfg values with any CLI flags that were explicitly
 // set (non-zero / non-empty), so that flags always win over env vars / defaults.
-func applyFlagOverrides(cmd *cobra.Command, flags *cliFlags, cfg *config.Config) {
+// Returns an error when a flag value fails validation.
+func applyFlagOverrides(cmd *cobra.Command, flags *cliFlags, cfg *config.Config) error {
 	if cmd.Flags().Changed("namespace") {
 		// Re-parse the comma-separated string through the same logic as config.Load().
 		cfg.Namespaces = config.ParseNamespaces(flags.namespace, cfg.Namespaces[0])
 	}
+	if cmd.Flags().Changed("all-namespaces") {
+		cfg.AllNamespaces = flags.allNamespaces
+	}
+	if cmd.Flags().Changed("namespace-selector") {
+		selector, err := config.ParseNamespaceSelector(flags.namespaceSelector)
+		if err != nil {
+			return err
+		}
+		cfg.NamespaceSelector = selector
+	}
+	if cmd.Flags().Changed("exclude-namespaces") {
+		patterns, err := config.ParseExcludeNamespaces(flags.excludeNamespaces)
+		if err != nil {
+			return err
+		}
+		cfg.ExcludeNamespaces = patterns
+	}
 	if cmd.Flags().Changed("app-label") {
-		cfg.AppLabel = flags.appLabel
+		selector, err := config.ParseAppLabel(flags.appLabel)
+		if err != nil {
+			return err
+		}
+		cfg.AppLabel = selector
 	}
 	if cmd.Flags().Changed("keep-last") {
 		cfg.KeepLast = flags.keepLast
@@ -335,6 +1335,142 @@ func applyFlagOverrides(cmd *cobra.Command, flags *cliFlags, cfg *config.Config)
 	if cmd.Flags().Changed("log-format") {
 		cfg.LogFormat = flags.logFormat
 	}
+	if cmd.Flags().Changed("workload-kinds") {
+		kinds, err := config.ParseWorkloadKinds(flags.workloadKinds)
+		if err != nil {
+			return err
+		}
+		cfg.WorkloadKinds = kinds
+	}
+	if cmd.Flags().Changed("resource-kinds") {
+		kinds, err := config.ParseResourceKinds(flags.resourceKinds)
+		if err != nil {
+			return err
+		}
+		cfg.ResourceKinds = kinds
+	}
+	if cmd.Flags().Changed("retention-mode") {
+		mode, err := config.ParseRetentionMode(flags.retentionMode)
+		if err != nil {
+			return err
+		}
+		cfg.RetentionMode = mode
+	}
+	if cmd.Flags().Changed("configmap-name-template") {
+		cfg.ConfigMapNameTemplate = flags.configMapNameTemplate
+	}
+	if cmd.Flags().Changed("secret-name-template") {
+		cfg.SecretNameTemplate = flags.secretNameTemplate
+	}
+	if cmd.Flags().Changed("hash-length") {
+		cfg.HashLength = flags.hashLength
+	}
+	if cmd.Flags().Changed("hash-alphabet") {
+		cfg.HashAlphabet = flags.hashAlphabet
+	}
+	if cmd.Flags().Changed("page-size") {
+		if err := config.ValidatePageSize(flags.pageSize); err != nil {
+			return err
+		}
+		cfg.PageSize = flags.pageSize
+	}
+	if cmd.Flags().Changed("include-rollouts") {
+		patterns, err := config.ParseRolloutPatterns(flags.includeRollouts)
+		if err != nil {
+			return err
+		}
+		cfg.IncludeRollouts = patterns
+	}
+	if cmd.Flags().Changed("exclude-rollouts") {
+		patterns, err := config.ParseRolloutPatterns(flags.excludeRollouts)
+		if err != nil {
+			return err
+		}
+		cfg.ExcludeRollouts = patterns
+	}
+	if cmd.Flags().Changed("rollout-selector") {
+		selector, err := config.ParseRolloutSelector(flags.rolloutSelector)
+		if err != nil {
+			return err
+		}
+		cfg.RolloutSelector = selector
+	}
+	if cmd.Flags().Changed("concurrency") {
+		if err := config.ValidateConcurrency(flags.concurrency); err != nil {
+			return err
+		}
+		cfg.Concurrency = flags.concurrency
+	}
+	if cmd.Flags().Changed("namespace-timeout") {
+		if err := config.ValidateTimeout("namespace timeout", flags.namespaceTimeout); err != nil {
+			return err
+		}
+		cfg.NamespaceTimeout = flags.namespaceTimeout
+	}
+	if cmd.Flags().Changed("run-timeout") {
+		if err := config.ValidateTimeout("run timeout", flags.runTimeout); err != nil {
+			return err
+		}
+		cfg.RunTimeout = flags.runTimeout
+	}
+	if cmd.Flags().Changed("resync-period") {
+		if err := config.ValidateTimeout("resync period", flags.resyncPeriod); err != nil {
+			return err
+		}
+		cfg.ResyncPeriod = flags.resyncPeriod
+	}
+	if cmd.Flags().Changed("leader-elect") {
+		cfg.LeaderElect = flags.leaderElect
+	}
+	if cmd.Flags().Changed("lease-name") {
+		cfg.LeaseName = strings.TrimSpace(flags.leaseName)
+	}
+	if cmd.Flags().Changed("lease-namespace") {
+		cfg.LeaseNamespace = strings.TrimSpace(flags.leaseNamespace)
+	}
+	if cmd.Flags().Changed("lease-duration") {
+		cfg.LeaseDuration = flags.leaseDuration
+	}
+	if cmd.Flags().Changed("renew-deadline") {
+		cfg.RenewDeadline = flags.renewDeadline
+	}
+	if cmd.Flags().Changed("retry-period") {
+		cfg.RetryPeriod = flags.retryPeriod
+	}
+	if cmd.Flags().Changed("rollout-phases") {
+		phases, err := config.ParseRolloutPhases(flags.rolloutPhases)
+		if err != nil {
+			return err
+		}
+		cfg.RolloutPhases = phases
+	}
+	if cmd.Flags().Changed("metrics-addr") {
+		cfg.MetricsAddr = strings.TrimSpace(flags.metricsAddr)
+	}
+	if cmd.Flags().Changed("metrics-file") {
+		cfg.MetricsFile = strings.TrimSpace(flags.metricsFile)
+	}
+	if cmd.Flags().Changed("metrics-pushgateway") {
+		cfg.MetricsPushURL = strings.TrimSpace(flags.metricsPushURL)
+	}
+	if cmd.Flags().Changed("emit-events") {
+		cfg.EmitEvents = flags.emitEvents
+	}
+	if cmd.Flags().Changed("stem-references") {
+		cfg.StemReferences = flags.stemReferences
+	}
+	if cmd.Flags().Changed("report") {
+		cfg.Report = strings.TrimSpace(flags.report)
+	}
+	if cmd.Flags().Changed("report-format") {
+		format, err := config.ParseReportFormat(flags.reportFormat)
+		if err != nil {
+			return err
+		}
+		cfg.ReportFormat = format
+	}
+	// The lease durations are only valid relative to each other.
+	return cfg.ValidateLeaderElection()
 }
 
 // buildLogger creates a zap.Logger configured for the given level and format.
@@ -0,0 +1,597 @@
+package main
+
+// Tests for the one-shot run: a namespace is collected end to end against fake
+// clientsets for the core API and Argo Rollouts.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+
+	"github.com/yujen77300/configmap-collector/internal/config"
+	"github.com/yujen77300/configmap-collector/internal/k8s"
+	"github.com/yujen77300/configmap-collector/internal/metrics"
+	"github.com/yujen77300/configmap-collector/internal/naming"
+	"github.com/yujen77300/configmap-collector/internal/planner"
+	"github.com/yujen77300/configmap-collector/internal/report"
+)
+
+const testNamespace = "mwpcloud"
+
+// testConfig returns the default configuration, deleting for real every
+// candidate but the newest of each family.
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	cfg.Namespaces = []string{testNamespace}
+	cfg.DryRun = false
+	cfg.KeepLast = 1
+	cfg.KeepDays = 0
+	return cfg
+}
+
+// makeRollout builds a Rollout whose desired template mounts cmName.
+func makeRollout(name string, uid types.UID, cmName, checksum string) *rolloutsv1alpha1.Rollout {
+	return &rolloutsv1alpha1.Rollout{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: name, UID: uid},
+		Spec: rolloutsv1alpha1.RolloutSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{k8s.AnnotationChecksumConfig: checksum},
+				},
+				Spec: corev1.PodSpec{
+					Volumes: []corev1.Volume{{
+						Name: "config",
+						VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+							LocalObjectReference: corev1.LocalObjectReference{Name: cmName},
+						}},
+					}},
+				},
+			},
+		},
+		Status: rolloutsv1alpha1.RolloutStatus{Phase: rolloutsv1alpha1.RolloutPhaseHealthy},
+	}
+}
+
+// makeConfigMap builds a ConfigMap created age ago.
+func makeConfigMap(name string, age time.Duration) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:         testNamespace,
+		Name:              name,
+		CreationTimestamp: metav1.NewTime(time.Now().Add(-age)),
+	}}
+}
+
+// gcFixture collects testNamespace through fake clientsets.
+type gcFixture struct {
+	cfg              *config.Config
+	kube             *fake.Clientset
+	clients          *k8s.Clients
+	candidateClients []k8s.CandidateClient
+	templates        map[string]*naming.Template
+	sources          []k8s.WorkloadSource
+}
+
+func newGCFixture(t *testing.T, cfg *config.Config, kubeObjects []runtime.Object, rollouts ...runtime.Object) *gcFixture {
+	t.Helper()
+	f := &gcFixture{cfg: cfg, kube: fake.NewSimpleClientset(kubeObjects...), templates: make(map[string]*naming.Template)}
+	f.clients = &k8s.Clients{Kube: f.kube, Rollout: rolloutfake.NewSimpleClientset(rollouts...)}
+	for _, kind := range cfg.ResourceKinds {
+		client, err := k8s.NewCandidateClient(kind, f.clients)
+		require.NoError(t, err)
+		f.candidateClients = append(f.candidateClients, client)
+		tpl, err := naming.New(cfg.NameTemplate(kind), cfg.HashLength, cfg.HashAlphabet)
+		require.NoError(t, err)
+		f.templates[kind] = tpl
+	}
+	sources, err := k8s.NewWorkloadSources(cfg.WorkloadKinds, f.clients)
+	require.NoError(t, err)
+	f.sources = sources
+	return f
+}
+
+// run collects testNamespace once.
+func (f *gcFixture) run(ctx context.Context) (excluded []string, failed bool) {
+	return runForNamespace(ctx, testNamespace, f.cfg, f.candidateClients, f.templates, f.sources, nil, nil, zap.NewNop())
+}
+
+// deleted returns the names of the ConfigMaps deleted, sorted.
+func (f *gcFixture) deleted() []string {
+	var names []string
+	for _, action := range f.kube.Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "configmaps" {
+			names = append(names, action.(k8stesting.DeleteAction).GetName())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// TestRunForNamespace_HoldsBackUnresolvedRollout verifies that a Rollout whose
+// workloadRef cannot be resolved holds back only its own families.
+func TestRunForNamespace_HoldsBackUnresolvedRollout(t *testing.T) {
+	broken := makeRollout("cart", "uid-cart", "", "")
+	broken.Spec.Template = corev1.PodTemplateSpec{}
+	broken.Spec.WorkloadRef = &rolloutsv1alpha1.ObjectRef{APIVersion: "apps/v1", Kind: "Deployment", Name: "missing"}
+	f := newGCFixture(t, testConfig(t), []runtime.Object{
+		makeConfigMap("seat-config-00000001", 72*time.Hour),
+		makeConfigMap("seat-config-00000002", 48*time.Hour),
+		makeConfigMap("seat-config-00000003", 24*time.Hour),
+		makeConfigMap("cart-config-00000001", 72*time.Hour),
+		makeConfigMap("cart-config-00000002", 48*time.Hour),
+	}, makeRollout("seat", "uid-seat", "seat-config-00000003", "00000003"), broken)
+
+	_, failed := f.run(context.Background())
+
+	assert.False(t, failed)
+	assert.Equal(t, []string{"seat-config-00000001", "seat-config-00000002"}, f.deleted(),
+		"cart's ConfigMaps are kept while its workloadRef is unresolved")
+}
+
+// TestRunForNamespace_HoldsBackUndecodableRevision verifies that a workload
+// with a ControllerRevision that fails to decode holds back only its own
+// families, instead of failing the namespace.
+func TestRunForNamespace_HoldsBackUndecodableRevision(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.WorkloadKinds = []string{k8s.KindRollout, k8s.KindStatefulSet}
+	db := &appsv1.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "db", UID: "uid-db"},
+		Spec:       appsv1.StatefulSetSpec{Template: makeRollout("db", "uid-db", "db-config-00000003", "00000003").Spec.Template},
+	}
+	broken := &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            "db-0bad",
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: k8s.KindStatefulSet, Name: "db", UID: "uid-db"}},
+		},
+		Data: runtime.RawExtension{Raw: []byte("not-json")},
+	}
+	f := newGCFixture(t, cfg, []runtime.Object{
+		db, broken,
+		makeConfigMap("seat-config-00000001", 72*time.Hour),
+		makeConfigMap("seat-config-00000002", 48*time.Hour),
+		makeConfigMap("seat-config-00000003", 24*time.Hour),
+		makeConfigMap("db-config-00000001", 72*time.Hour),
+		makeConfigMap("db-config-00000002", 48*time.Hour),
+		makeConfigMap("db-config-00000003", 24*time.Hour),
+	}, makeRollout("seat", "uid-seat", "seat-config-00000003", "00000003"))
+
+	_, failed := f.run(context.Background())
+
+	assert.False(t, failed)
+	assert.Equal(t, []string{"seat-config-00000001", "seat-config-00000002"}, f.deleted(),
+		"db's ConfigMaps are kept while one of its revisions cannot be decoded")
+}
+
+// makeReplicaSet builds revision revision of a Rollout: a ReplicaSet owned by
+// it whose pod template is the Rollout's template mounting cmName.
+func makeReplicaSet(owner *rolloutsv1alpha1.Rollout, revision int, cmName string) *appsv1.ReplicaSet {
+	tpl := makeRollout(owner.Name, owner.UID, cmName, "").Spec.Template
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            fmt.Sprintf("%s-%d", owner.Name, revision),
+			Annotations:     map[string]string{k8s.AnnotationRolloutRevision: strconv.Itoa(revision)},
+			OwnerReferences: []metav1.OwnerReference{{APIVersion: "argoproj.io/v1alpha1", Kind: k8s.KindRollout, Name: owner.Name, UID: owner.UID}},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: tpl},
+	}
+}
+
+func TestRetentionFor(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.KeepLast, cfg.KeepDays = 3, 7
+
+	keepLast, keepDays := retentionFor(cfg)
+	assert.Equal(t, []int{3, 7}, []int{keepLast, keepDays})
+
+	cfg.RetentionMode = config.RetentionRevisionHistory
+	keepLast, keepDays = retentionFor(cfg)
+	assert.Equal(t, []int{0, 0}, []int{keepLast, keepDays}, "retained revisions alone decide")
+}
+
+// TestRunForNamespace_RevisionHistoryRetention verifies that in
+// revision-history mode each Rollout keeps exactly the ConfigMaps of its
+// current and revisionHistoryLimit previous revisions, by revision number
+// rather than by creation time.
+func TestRunForNamespace_RevisionHistoryRetention(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.RetentionMode = config.RetentionRevisionHistory
+	cfg.KeepLast, cfg.KeepDays = 10, 30
+
+	seat := makeRollout("seat", "uid-seat", "seat-config-00000004", "")
+	seat.Spec.RevisionHistoryLimit = ptr.To[int32](1)
+	cart := makeRollout("cart", "uid-cart", "cart-config-00000004", "")
+	cart.Spec.RevisionHistoryLimit = ptr.To[int32](3)
+	objects := []runtime.Object{makeConfigMap("cart-config-00000000", 120*time.Hour)}
+	for i := 1; i <= 4; i++ {
+		for _, r := range []*rolloutsv1alpha1.Rollout{seat, cart} {
+			cm := fmt.Sprintf("%s-config-%08d", r.Name, i)
+			// Creation times run against revision order: the ConfigMap of
+			// the oldest revision is the newest object.
+			objects = append(objects, makeConfigMap(cm, time.Duration(i)*24*time.Hour), makeReplicaSet(r, i, cm))
+		}
+	}
+	f := newGCFixture(t, cfg, objects, seat, cart)
+
+	_, failed := f.run(context.Background())
+
+	assert.False(t, failed)
+	assert.Equal(t, []string{"cart-config-00000000", "seat-config-00000001", "seat-config-00000002"}, f.deleted())
+}
+
+// recordingGC is a runNamespaces gc func recording the namespaces it started
+// and running block for each.
+type recordingGC struct {
+	mu      sync.Mutex
+	started []string
+	block   func(ctx context.Context, ns string) (failed bool)
+}
+
+func (g *recordingGC) gc(ctx context.Context, ns string, _ *zap.Logger) ([]string, bool) {
+	g.mu.Lock()
+	g.started = append(g.started, ns)
+	g.mu.Unlock()
+	return nil, g.block(ctx, ns)
+}
+
+// TestRunNamespaces_Concurrency verifies that no more than cfg.Concurrency
+// namespaces are processed at once, and that every namespace is processed.
+func TestRunNamespaces_Concurrency(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Concurrency = 2
+	var running, peak atomic.Int32
+	g := &recordingGC{block: func(context.Context, string) bool {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return false
+	}}
+	namespaces := []string{"a", "b", "c", "d", "e", "f"}
+
+	summary := runNamespaces(context.Background(), namespaces, cfg, zap.NewNop(), g.gc)
+
+	assert.Equal(t, runSummary{}, summary)
+	assert.ElementsMatch(t, namespaces, g.started)
+	assert.LessOrEqual(t, peak.Load(), int32(cfg.Concurrency))
+}
+
+// TestRunNamespaces_NamespaceTimeout verifies that a namespace exceeding
+// cfg.NamespaceTimeout is reported as timed out while the others complete.
+func TestRunNamespaces_NamespaceTimeout(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Concurrency = 2
+	cfg.NamespaceTimeout = 50 * time.Millisecond
+	g := &recordingGC{block: func(ctx context.Context, ns string) bool {
+		switch ns {
+		case "slow":
+			<-ctx.Done()
+		case "broken":
+			return true
+		}
+		return false
+	}}
+
+	summary := runNamespaces(context.Background(), []string{"a", "slow", "b", "broken", "c"}, cfg, zap.NewNop(), g.gc)
+
+	assert.Equal(t, []string{"slow"}, summary.timedOut)
+	assert.Equal(t, []string{"broken"}, summary.failed)
+	assert.Empty(t, summary.interrupted)
+	assert.ElementsMatch(t, []string{"a", "slow", "b", "broken", "c"}, g.started)
+}
+
+// TestRunNamespaces_RunDeadline verifies that once the run's context is done
+// no further namespace starts: the namespace cut short and those never started
+// are timed out, or interrupted after a signal.
+func TestRunNamespaces_RunDeadline(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Concurrency = 1
+	cfg.NamespaceTimeout = 0
+	block := func(ctx context.Context, _ string) bool {
+		<-ctx.Done()
+		return false
+	}
+
+	t.Run("timeout", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		g := &recordingGC{block: block}
+
+		summary := runNamespaces(ctx, []string{"a", "b", "c"}, cfg, zap.NewNop(), g.gc)
+
+		assert.Equal(t, []string{"a"}, g.started)
+		assert.Equal(t, []string{"a", "b", "c"}, summary.timedOut)
+		assert.Empty(t, summary.interrupted)
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		g := &recordingGC{block: func(ctx context.Context, ns string) bool {
+			cancel(errInterrupted)
+			return block(ctx, ns)
+		}}
+
+		summary := runNamespaces(ctx, []string{"a", "b", "c"}, cfg, zap.NewNop(), g.gc)
+
+		assert.Equal(t, []string{"a"}, g.started)
+		assert.Equal(t, []string{"a", "b", "c"}, summary.interrupted)
+		assert.Empty(t, summary.timedOut)
+	})
+}
+
+// TestRun_SignalStopsDeletions verifies that a signal received while a
+// deletion is in flight lets it finish, starts no further deletion, and ends
+// the run with exitInterrupted.
+func TestRun_SignalStopsDeletions(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	f := newGCFixture(t, testConfig(t), []runtime.Object{
+		makeConfigMap("seat-config-00000001", 96*time.Hour),
+		makeConfigMap("seat-config-00000002", 72*time.Hour),
+		makeConfigMap("seat-config-00000003", 48*time.Hour),
+		makeConfigMap("seat-config-00000004", 24*time.Hour),
+	}, makeRollout("seat", "uid-seat", "seat-config-00000004", "00000004"))
+	var deletes atomic.Int32
+	f.kube.PrependReactor("delete", "configmaps", func(k8stesting.Action) (bool, runtime.Object, error) {
+		deletes.Add(1)
+		cancel(errInterrupted)
+		return false, nil, nil
+	})
+
+	summary := runNamespaces(ctx, []string{testNamespace}, f.cfg, zap.NewNop(), func(ctx context.Context, _ string, _ *zap.Logger) ([]string, bool) {
+		return f.run(ctx)
+	})
+
+	assert.Equal(t, int32(1), deletes.Load(), "no deletion starts after the signal")
+	list, err := f.kube.CoreV1().ConfigMaps(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, list.Items, 3, "the in-flight deletion completed")
+	assert.Equal(t, []string{testNamespace}, summary.interrupted)
+	assert.Equal(t, exitInterrupted, exitCode(ctx, summary, false))
+}
+
+func TestExitCode(t *testing.T) {
+	interrupted, cancel := context.WithCancelCause(context.Background())
+	cancel(errInterrupted)
+	timedOut, cancelTimeout := context.WithTimeout(context.Background(), 0)
+	defer cancelTimeout()
+
+	assert.Equal(t, 0, exitCode(context.Background(), runSummary{}, false))
+	assert.Equal(t, 0, exitCode(context.Background(), runSummary{excludedRollouts: []string{"ns/seat"}}, false))
+	assert.Equal(t, exitFailed, exitCode(context.Background(), runSummary{failed: []string{"ns"}}, false))
+	assert.Equal(t, exitFailed, exitCode(timedOut, runSummary{timedOut: []string{"ns"}}, false))
+	assert.Equal(t, exitFailed, exitCode(context.Background(), runSummary{}, true))
+	assert.Equal(t, exitInterrupted, exitCode(interrupted, runSummary{failed: []string{"ns"}}, false))
+}
+
+func TestDeleteTimeout(t *testing.T) {
+	cfg := testConfig(t)
+	assert.Equal(t, deleteGracePeriod, deleteTimeout(cfg))
+
+	cfg.LeaderElect = true
+	assert.Equal(t, cfg.LeaseDuration-cfg.RenewDeadline, deleteTimeout(cfg), "a deletion ends before a standby can take over")
+	cfg.LeaseDuration, cfg.RenewDeadline = 2*time.Minute, 30*time.Second
+	assert.Equal(t, deleteGracePeriod, deleteTimeout(cfg))
+}
+
+// metricValue returns the value of a gauge or counter.
+func metricValue(t *testing.T, m prometheus.Metric) float64 {
+	t.Helper()
+	var out dto.Metric
+	require.NoError(t, m.Write(&out))
+	if out.Gauge != nil {
+		return out.GetGauge().GetValue()
+	}
+	return out.GetCounter().GetValue()
+}
+
+// TestRunForFamily_PlanMetrics verifies that re-planning a family, as every
+// controller sync does, overwrites the plan gauges instead of adding to them,
+// and that only actual deletions are counted.
+func TestRunForFamily_PlanMetrics(t *testing.T) {
+	names := []string{"meter-config-00000001", "meter-config-00000002", "meter-config-00000003"}
+	var objects []runtime.Object
+	var listed []metav1.ObjectMeta
+	for i, name := range names {
+		cm := makeConfigMap(name, time.Duration(len(names)-i)*24*time.Hour)
+		objects = append(objects, cm)
+		listed = append(listed, cm.ObjectMeta)
+	}
+	cfg := testConfig(t)
+	cfg.DryRun = true
+	f := newGCFixture(t, cfg, objects)
+	kind := cfg.ResourceKinds[0]
+	group := k8s.FamilyGroup{
+		Family:    f.templates[kind].Family("meter"),
+		Workloads: []k8s.Workload{{Kind: k8s.KindRollout, Name: "meter", UID: "uid-meter"}},
+	}
+	run := func(listed []metav1.ObjectMeta) bool {
+		return runForFamily(context.Background(), testNamespace, group, listed, 1, 0, cfg,
+			f.candidateClients[0], k8s.ScopedInUseSets{}, nil, nil, zap.NewNop())
+	}
+	resource := f.candidateClients[0].Resource().Kind
+	candidates := metrics.Candidates.WithLabelValues(testNamespace, "meter", resource)
+	keepLast := metrics.Protected.WithLabelValues(testNamespace, "meter", resource, string(planner.ReasonKeepLast))
+	inUse := metrics.Protected.WithLabelValues(testNamespace, "meter", resource, string(planner.ReasonInUse))
+	wouldDelete := metrics.WouldDelete.WithLabelValues(testNamespace, "meter", resource)
+	deletions := metrics.Deletions.WithLabelValues(testNamespace, "meter", resource)
+	// Counters live in the process-wide registry; compare against their
+	// value before this test.
+	deleted := metricValue(t, deletions)
+
+	for range 2 {
+		assert.False(t, run(listed))
+		assert.Equal(t, 3.0, metricValue(t, candidates))
+		assert.Equal(t, 1.0, metricValue(t, keepLast))
+		assert.Equal(t, 0.0, metricValue(t, inUse))
+		assert.Equal(t, 2.0, metricValue(t, wouldDelete))
+		assert.Equal(t, deleted, metricValue(t, deletions), "a dry run deletes nothing")
+	}
+
+	cfg.DryRun = false
+	assert.False(t, run(listed))
+	assert.Equal(t, deleted+2, metricValue(t, deletions))
+	assert.False(t, run(listed[2:]))
+	assert.Equal(t, 1.0, metricValue(t, candidates))
+	assert.Equal(t, 1.0, metricValue(t, keepLast))
+	assert.Equal(t, 0.0, metricValue(t, wouldDelete))
+	assert.Equal(t, deleted+2, metricValue(t, deletions), "deletions are counted once")
+}
+
+// TestRunForFamily_RecordsEvents verifies that dry-run decisions, deletions
+// and failed deletions are recorded as Events on the owning Rollout.
+func TestRunForFamily_RecordsEvents(t *testing.T) {
+	names := []string{"pulse-config-00000001", "pulse-config-00000002", "pulse-config-00000003"}
+	var objects []runtime.Object
+	var listed []metav1.ObjectMeta
+	for i, name := range names {
+		cm := makeConfigMap(name, time.Duration(len(names)-i)*24*time.Hour)
+		objects = append(objects, cm)
+		listed = append(listed, cm.ObjectMeta)
+	}
+	cfg := testConfig(t)
+	cfg.DryRun = true
+	f := newGCFixture(t, cfg, objects)
+	f.kube.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() == names[0] {
+			return true, nil, errors.New("etcdserver: request timed out")
+		}
+		return false, nil, nil
+	})
+	events := k8s.NewEventRecorder(f.kube, func(event *corev1.Event, err error) {
+		t.Errorf("event %s not recorded: %v", event.Reason, err)
+	})
+	group := k8s.FamilyGroup{
+		Family:    f.templates[cfg.ResourceKinds[0]].Family("pulse"),
+		Workloads: []k8s.Workload{{Kind: k8s.KindRollout, Name: "pulse", UID: "uid-pulse"}},
+	}
+	run := func() bool {
+		return runForFamily(context.Background(), testNamespace, group, listed, 1, 0, cfg,
+			f.candidateClients[0], k8s.ScopedInUseSets{}, events, nil, zap.NewNop())
+	}
+
+	assert.False(t, run())
+	cfg.DryRun = false
+	assert.True(t, run())
+	require.True(t, events.Flush(5*time.Second))
+
+	list, err := f.kube.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	messages := make(map[string]string)
+	for _, event := range list.Items {
+		assert.Equal(t, k8s.KindRollout, event.InvolvedObject.Kind)
+		assert.Equal(t, "pulse", event.InvolvedObject.Name)
+		assert.Equal(t, types.UID("uid-pulse"), event.InvolvedObject.UID)
+		messages[event.Reason] = event.Message
+	}
+	require.Len(t, messages, 3)
+	assert.Contains(t, messages[k8s.EventReasonWouldDelete], names[0])
+	assert.Contains(t, messages[k8s.EventReasonWouldDelete], names[1])
+	assert.Equal(t, corev1.EventTypeNormal, eventType(list.Items, k8s.EventReasonDeleted))
+	assert.Contains(t, messages[k8s.EventReasonDeleted], names[1])
+	assert.NotContains(t, messages[k8s.EventReasonDeleted], names[0])
+	assert.Equal(t, corev1.EventTypeWarning, eventType(list.Items, k8s.EventReasonDeleteFailed))
+	assert.Contains(t, messages[k8s.EventReasonDeleteFailed], names[0])
+}
+
+// eventType returns the type of the Event with reason.
+func eventType(events []corev1.Event, reason string) string {
+	for _, event := range events {
+		if event.Reason == reason {
+			return event.Type
+		}
+	}
+	return ""
+}
+
+// TestRun_Report verifies the --report wiring: a run records every candidate
+// with its size and deletion outcome, sized from Lists rather than one GET
+// per candidate, and the report is written to cfg.Report.
+func TestRun_Report(t *testing.T) {
+	cfg := testConfig(t)
+	cfg.Report = filepath.Join(t.TempDir(), "plan.json")
+	cfg.ReportFormat = config.ReportFormatJSON
+	var objects []runtime.Object
+	for i, name := range seatConfigMaps {
+		cm := makeConfigMap(name, time.Duration(len(seatConfigMaps)-i)*24*time.Hour)
+		cm.Data = map[string]string{"app.yaml": strings.Repeat("x", i)}
+		objects = append(objects, cm)
+	}
+	f := newGCFixture(t, cfg, objects, makeRollout("seat", "uid-seat", seatConfigMaps[2], "00000003"))
+	f.kube.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.(k8stesting.DeleteAction).GetName() == seatConfigMaps[0] {
+			return true, nil, errors.New("etcdserver: request timed out")
+		}
+		return false, nil, nil
+	})
+
+	rep := newReport(cfg, time.Now())
+	require.NotNil(t, rep)
+	_, failed := runForNamespace(context.Background(), testNamespace, cfg, f.candidateClients, f.templates, f.sources, nil, rep, zap.NewNop())
+	assert.True(t, failed)
+	require.True(t, writeReport(cfg, rep, zap.NewNop()))
+
+	out, err := os.ReadFile(cfg.Report)
+	require.NoError(t, err)
+	var written report.Report
+	require.NoError(t, json.Unmarshal(out, &written))
+	require.Len(t, written.Namespaces, 1)
+	require.Len(t, written.Namespaces[0].Rollouts, 1)
+	decisions := make(map[string]string)
+	for _, c := range written.Namespaces[0].Rollouts[0].Candidates {
+		decisions[c.Name] = c.Decision
+		require.NotNil(t, c.SizeBytes, c.Name)
+		assert.Equal(t, int64(len("app.yaml")+slices.Index(seatConfigMaps, c.Name)), *c.SizeBytes, c.Name)
+	}
+	assert.Equal(t, map[string]string{
+		seatConfigMaps[0]: report.DecisionFailed,
+		seatConfigMaps[1]: report.DecisionDeleted,
+		seatConfigMaps[2]: report.DecisionKeep,
+	}, decisions)
+	for _, action := range f.kube.Actions() {
+		assert.NotEqual(t, "get", action.GetVerb(), "candidates are sized from Lists")
+	}
+
+	cfg.Report = ""
+	assert.Nil(t, newReport(cfg, time.Now()), "no --report, no report")
+}
+
+// TestReportFormats verifies that config accepts exactly the formats the
+// report package writes.
+func TestReportFormats(t *testing.T) {
+	assert.Equal(t, report.Formats, config.ReportFormats)
+	assert.Equal(t, report.FormatJSON, config.ReportFormatJSON)
+}
@@ -1,23 +1,454 @@
 package config
 
 import (
+	"fmt"
+	"path"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+
+	"github.com/yujen77300/configmap-collector/internal/naming"
 )
 
 // Config holds all configuration parameters for the ConfigMap GC.
 type Config struct {
 	// Namespaces is the list of target namespaces to scan.
 	// Accepts a comma-separated string via the NAMESPACE env var or --namespace flag,
-	// e.g. "mwpcloud,staging-ns,prod-ns".
+	// e.g. "mwpcloud,staging-ns,prod-ns". Ignored when namespaces are
+	// discovered (see Discover).
 	Namespaces []string
-	AppLabel   string
-	KeepLast   int
-	KeepDays   int
-	DryRun     bool
-	LogLevel   string
-	LogFormat  string
+	// AllNamespaces makes the run cover every namespace in the cluster. Set via
+	// the ALL_NAMESPACES env var or --all-namespaces flag.
+	AllNamespaces bool
+	// NamespaceSelector is a label selector on Namespace objects, e.g.
+	// "team,env!=sandbox"; when set, the run covers the matching namespaces.
+	// Set via the NAMESPACE_SELECTOR env var or --namespace-selector flag.
+	NamespaceSelector string
+	// ExcludeNamespaces lists namespace names and glob patterns never
+	// processed, e.g. ["monitoring", "*-sandbox"], on top of
+	// DeniedNamespaces. Accepts a comma-separated string via the
+	// EXCLUDE_NAMESPACES env var or --exclude-namespaces flag.
+	ExcludeNamespaces []string
+	// AppLabel is a Kubernetes label selector scoping the run, e.g.
+	// "app in (seat,order),tier!=batch". Workloads and candidates are listed
+	// with it server-side, so it must match the labels of each; ReplicaSets and
+	// ControllerRevisions are always listed in full, so a revision missing the
+	// label still protects what it references. A bare value such as "xzk0-seat" is
+	// shorthand for "app=xzk0-seat"; empty selects everything. Set via the
+	// APP_LABEL env var or --app-label flag.
+	AppLabel  string
+	KeepLast  int
+	KeepDays  int
+	DryRun    bool
+	LogLevel  string
+	LogFormat string
+	// WorkloadKinds selects which controllers are garbage-collected, e.g.
+	// ["Rollout", "Deployment"]. Accepts a comma-separated string via the
+	// WORKLOAD_KINDS env var or --workload-kinds flag.
+	WorkloadKinds []string
+	// ResourceKinds selects which versioned objects are collected:
+	// "ConfigMap", "Secret" or both. Accepts a comma-separated string via the
+	// RESOURCE_KINDS env var or --resource-kinds flag.
+	ResourceKinds []string
+	// RetentionMode selects how many candidates are kept per workload:
+	// RetentionKeepLast applies the global KeepLast/KeepDays;
+	// RetentionRevisionHistory keeps what the revisionHistoryLimit+1 newest
+	// revisions of each workload reference.
+	RetentionMode string
+	// ConfigMapNameTemplate and SecretNameTemplate describe how each workload's
+	// versioned objects are named, e.g. "{workload}-config-{hash}" or the
+	// Kustomize generator form "{workload}-{hash10}". See internal/naming.
+	ConfigMapNameTemplate string
+	SecretNameTemplate    string
+	// HashLength is the hash length for templates using a bare {hash}.
+	HashLength int
+	// HashAlphabet lists the characters a hash may consist of.
+	HashAlphabet string
+	// StemReferences makes every name a desired pod template references that
+	// ends in a hash a candidate family, even when it does not match the
+	// naming template. Off by default: unversioned names such as
+	// "db-cafebabe" would otherwise be collected. Set via the STEM_REFERENCES
+	// env var or --stem-references flag.
+	StemReferences bool
+	// RolloutPhases lists the Rollout status phases in which a Rollout's
+	// candidates are collected, e.g. ["Healthy", "Paused"]. Families of a
+	// Rollout in any other phase are left untouched for the run. Accepts a
+	// comma-separated string via the ROLLOUT_PHASES env var or --rollout-phases flag.
+	RolloutPhases []string
+	// IncludeRollouts and ExcludeRollouts are Rollout name glob patterns, and
+	// RolloutSelector a label selector on Rollouts, e.g. ["seat-*"], ["legacy-*"]
+	// and "gc!=off". A Rollout's families are collected only when it matches
+	// an include pattern (if any) and the selector (if set), matches no
+	// exclude pattern, and does not carry ExcludeAnnotation. Set via the
+	// ROLLOUT_INCLUDE / ROLLOUT_EXCLUDE / ROLLOUT_SELECTOR env vars or the
+	// --include-rollouts / --exclude-rollouts / --rollout-selector flags.
+	IncludeRollouts []string
+	ExcludeRollouts []string
+	RolloutSelector string
+	// PageSize is the number of objects requested per List call; 0 disables
+	// pagination. Set via the PAGE_SIZE env var or --page-size flag.
+	PageSize int
+	// Concurrency is the number of namespaces processed at the same time.
+	// Set via the CONCURRENCY env var or --concurrency flag.
+	Concurrency int
+	// NamespaceTimeout bounds the GC cycle of a single namespace and
+	// RunTimeout the whole run, e.g. 5m and 30m; 0 disables the limit. Set
+	// via the NAMESPACE_TIMEOUT / RUN_TIMEOUT env vars or the
+	// --namespace-timeout / --run-timeout flags.
+	NamespaceTimeout time.Duration
+	RunTimeout       time.Duration
+	// ResyncPeriod is how often the controller re-plans every Rollout even
+	// when nothing changed, so age-based retention catches up; 0 disables
+	// resyncs. Set via the RESYNC_PERIOD env var or --resync-period flag.
+	ResyncPeriod time.Duration
+	// LeaderElect makes controller replicas elect a leader through a Lease;
+	// only the leader plans and deletes, the others stand by. Set via the
+	// LEADER_ELECT env var or --leader-elect flag.
+	LeaderElect bool
+	// LeaseName and LeaseNamespace identify the Lease; an empty namespace
+	// means the namespace cm-gc runs in. Set via the LEASE_NAME /
+	// LEASE_NAMESPACE env vars or the --lease-name / --lease-namespace flags.
+	LeaseName      string
+	LeaseNamespace string
+	// LeaseDuration is how long standbys wait before taking over an unrenewed
+	// Lease, RenewDeadline how long the leader keeps retrying a renewal before
+	// giving up leadership, and RetryPeriod the wait between attempts. Set via
+	// the LEASE_DURATION / RENEW_DEADLINE / RETRY_PERIOD env vars or the
+	// --lease-duration / --renew-deadline / --retry-period flags.
+	LeaseDuration time.Duration
+	RenewDeadline time.Duration
+	RetryPeriod   time.Duration
+	// MetricsAddr is the address /metrics is served on, e.g. ":9090"; empty
+	// disables the endpoint. Set via the METRICS_ADDR env var or
+	// --metrics-addr flag.
+	MetricsAddr string
+	// MetricsFile and MetricsPushURL make a one-shot run dump its metrics at
+	// exit: to a file for the node exporter's textfile collector, and to a
+	// Pushgateway. Empty disables each. Set via the METRICS_FILE /
+	// METRICS_PUSHGATEWAY env vars or the --metrics-file / --metrics-pushgateway
+	// flags.
+	MetricsFile    string
+	MetricsPushURL string
+	// EmitEvents records a Kubernetes Event on the workloads owning each
+	// family for its deletions, failed deletions and dry-run "would delete"
+	// decisions. Set via the EMIT_EVENTS env var or --emit-events flag.
+	EmitEvents bool
+	// Report is the file a one-shot run writes its plan report to, "-" for
+	// stdout; empty disables the report. ReportFormat is one of
+	// ReportFormats. Set via the REPORT / REPORT_FORMAT env vars or the
+	// --report / --report-format flags.
+	Report       string
+	ReportFormat string
+}
+
+// Default naming templates: the Helm checksum pattern.
+const (
+	DefaultConfigMapNameTemplate = "{workload}-config-{hash}"
+	DefaultSecretNameTemplate    = "{workload}-secret-{hash}"
+)
+
+// NameTemplate returns the naming template configured for the given resource
+// kind ("ConfigMap" or "Secret").
+func (c *Config) NameTemplate(kind string) string {
+	if kind == "Secret" {
+		return c.SecretNameTemplate
+	}
+	return c.ConfigMapNameTemplate
+}
+
+// SupportedWorkloadKinds lists every workload kind accepted by WORKLOAD_KINDS
+// and --workload-kinds, in canonical spelling.
+var SupportedWorkloadKinds = []string{"Rollout", "Deployment", "StatefulSet", "DaemonSet"}
+
+// DefaultWorkloadKind is used when no workload kinds are configured.
+const DefaultWorkloadKind = "Rollout"
+
+// Retention modes accepted by RETENTION_MODE and --retention-mode.
+const (
+	// RetentionKeepLast keeps the KeepLast newest candidates and anything
+	// younger than KeepDays, for every workload alike.
+	RetentionKeepLast = "keep-last"
+	// RetentionRevisionHistory keeps exactly the candidates referenced by
+	// the current revision and the spec.revisionHistoryLimit previous
+	// revisions of each workload, so `kubectl argo rollouts undo` always
+	// finds its ConfigMap. KeepLast and KeepDays are ignored in this mode.
+	RetentionRevisionHistory = "revision-history"
+)
+
+// ParseRetentionMode validates a retention mode string (case-insensitive,
+// surrounding whitespace ignored). Falls back to RetentionKeepLast when blank.
+func ParseRetentionMode(raw string) (string, error) {
+	mode := strings.ToLower(strings.TrimSpace(raw))
+	switch mode {
+	case "":
+		return RetentionKeepLast, nil
+	case RetentionKeepLast, RetentionRevisionHistory:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported retention mode %q (supported: %s, %s)", raw, RetentionKeepLast, RetentionRevisionHistory)
+	}
+}
+
+// Plan report formats accepted by REPORT_FORMAT and --report-format; the
+// formats the report package writes.
+const (
+	ReportFormatJSON     = "json"
+	ReportFormatYAML     = "yaml"
+	ReportFormatTable    = "table"
+	ReportFormatMarkdown = "markdown"
+)
+
+// ReportFormats lists the accepted plan report formats.
+var ReportFormats = []string{ReportFormatJSON, ReportFormatYAML, ReportFormatTable, ReportFormatMarkdown}
+
+// ParseReportFormat validates a report format string (case-insensitive,
+// surrounding whitespace ignored). Falls back to ReportFormatJSON when blank.
+func ParseReportFormat(raw string) (string, error) {
+	format := strings.ToLower(strings.TrimSpace(raw))
+	switch {
+	case format == "":
+		return ReportFormatJSON, nil
+	case slices.Contains(ReportFormats, format):
+		return format, nil
+	default:
+		return "", fmt.Errorf("unsupported report format %q (supported: %s)", raw, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// SupportedRolloutPhases lists every Argo Rollouts status phase accepted by
+// ROLLOUT_PHASES and --rollout-phases, in canonical spelling.
+var SupportedRolloutPhases = []string{"Healthy", "Progressing", "Paused", "Degraded"}
+
+// DefaultRolloutPhase is used when no rollout phases are configured: only
+// Healthy Rollouts are collected, so GC never runs mid-canary or while a
+// Rollout waits for manual promotion.
+const DefaultRolloutPhase = "Healthy"
+
+// SupportedResourceKinds lists every resource kind accepted by RESOURCE_KINDS
+// and --resource-kinds, in canonical spelling.
+var SupportedResourceKinds = []string{"ConfigMap", "Secret"}
+
+// DefaultResourceKind is used when no resource kinds are configured.
+const DefaultResourceKind = "ConfigMap"
+
+// AppLabelKey is the label key a bare APP_LABEL value selects on.
+const AppLabelKey = "app"
+
+// ParseAppLabel validates an APP_LABEL selector and returns it in canonical
+// form. A bare label value (no operator, comma or parenthesis) is expanded to
+// AppLabelKey=value; anything else must be valid label selector syntax.
+// Returns "" (select everything) when the input is blank.
+func ParseAppLabel(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if !strings.ContainsAny(raw, "=!(), ") && len(validation.IsValidLabelValue(raw)) == 0 {
+		raw = AppLabelKey + "=" + raw
+	}
+	selector, err := labels.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid app label selector %q: %w", raw, err)
+	}
+	return selector.String(), nil
+}
+
+// ValidatePageSize rejects negative PAGE_SIZE values; 0 disables pagination.
+func ValidatePageSize(size int) error {
+	if size < 0 {
+		return fmt.Errorf("invalid page size %d: must not be negative", size)
+	}
+	return nil
+}
+
+// Defaults for CONCURRENCY, NAMESPACE_TIMEOUT, RUN_TIMEOUT and RESYNC_PERIOD.
+const (
+	DefaultConcurrency      = 10
+	DefaultNamespaceTimeout = 5 * time.Minute
+	DefaultRunTimeout       = 30 * time.Minute
+	DefaultResyncPeriod     = time.Hour
+)
+
+// ValidateConcurrency rejects CONCURRENCY values below 1.
+func ValidateConcurrency(n int) error {
+	if n < 1 {
+		return fmt.Errorf("invalid concurrency %d: must be at least 1", n)
+	}
+	return nil
+}
+
+// ParseTimeout parses a timeout such as "90s" or "5m"; name identifies the
+// option in error messages. Negative values are rejected; 0 disables the
+// timeout.
+func ParseTimeout(name, raw string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", name, raw, err)
+	}
+	if err := ValidateTimeout(name, d); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+// ValidateTimeout rejects negative timeouts; name identifies the option in
+// error messages.
+func ValidateTimeout(name string, d time.Duration) error {
+	if d < 0 {
+		return fmt.Errorf("invalid %s %s: must not be negative", name, d)
+	}
+	return nil
+}
+
+// Defaults for LEASE_NAME, LEASE_DURATION, RENEW_DEADLINE and RETRY_PERIOD,
+// matching those of Kubernetes controllers.
+const (
+	DefaultLeaseName     = "cm-gc"
+	DefaultLeaseDuration = 15 * time.Second
+	DefaultRenewDeadline = 10 * time.Second
+	DefaultRetryPeriod   = 2 * time.Second
+)
+
+// ValidateLeaderElection rejects a lease configuration the leader elector
+// would refuse: every duration must be positive, and LeaseDuration must exceed
+// RenewDeadline, which must exceed RetryPeriod.
+func (c *Config) ValidateLeaderElection() error {
+	switch {
+	case c.LeaseName == "":
+		return fmt.Errorf("invalid lease name: must not be empty")
+	case c.RetryPeriod <= 0:
+		return fmt.Errorf("invalid retry period %s: must be positive", c.RetryPeriod)
+	case c.RenewDeadline <= c.RetryPeriod:
+		return fmt.Errorf("invalid renew deadline %s: must exceed the retry period %s", c.RenewDeadline, c.RetryPeriod)
+	case c.LeaseDuration <= c.RenewDeadline:
+		return fmt.Errorf("invalid lease duration %s: must exceed the renew deadline %s", c.LeaseDuration, c.RenewDeadline)
+	}
+	return nil
+}
+
+// DeniedNamespaces are system namespaces that are never processed, whatever
+// the namespace options.
+var DeniedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// Discover reports whether the namespaces are listed from the cluster
+// (AllNamespaces or NamespaceSelector) rather than taken from Namespaces.
+func (c *Config) Discover() bool {
+	return c.AllNamespaces || c.NamespaceSelector != ""
+}
+
+// Excluded reports whether namespace is in DeniedNamespaces or matches one of
+// ExcludeNamespaces.
+func (c *Config) Excluded(namespace string) bool {
+	for _, denied := range DeniedNamespaces {
+		if namespace == denied {
+			return true
+		}
+	}
+	return matchAny(c.ExcludeNamespaces, namespace)
+}
+
+// ExcludeAnnotation opts a Rollout out of GC when set to "true" on the Rollout
+// itself, whatever the Rollout filters.
+const ExcludeAnnotation = "cm-gc/exclude"
+
+// Reasons returned by RolloutExclusion.
+const (
+	ExclusionAnnotation  = "opt-out annotation"
+	ExclusionNotIncluded = "no include pattern matches"
+	ExclusionExcluded    = "exclude pattern matches"
+	ExclusionSelector    = "rollout selector does not match"
+)
+
+// RolloutExclusion returns why a Rollout with the given name, labels and
+// annotations must be left untouched, or "" when its families may be
+// collected. The selector has been validated by Load or ParseRolloutSelector.
+func (c *Config) RolloutExclusion(name string, rolloutLabels, annotations map[string]string) string {
+	if annotations[ExcludeAnnotation] == "true" {
+		return ExclusionAnnotation
+	}
+	if len(c.IncludeRollouts) > 0 && !matchAny(c.IncludeRollouts, name) {
+		return ExclusionNotIncluded
+	}
+	if matchAny(c.ExcludeRollouts, name) {
+		return ExclusionExcluded
+	}
+	if c.RolloutSelector != "" {
+		selector, err := labels.Parse(c.RolloutSelector)
+		if err != nil || !selector.Matches(labels.Set(rolloutLabels)) {
+			return ExclusionSelector
+		}
+	}
+	return ""
+}
+
+// matchAny reports whether name matches one of the glob patterns.
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseNamespaceSelector validates a NAMESPACE_SELECTOR label selector and
+// returns it in canonical form; "" when the input is blank.
+func ParseNamespaceSelector(raw string) (string, error) {
+	return parseSelector(raw, "namespace selector")
+}
+
+// ParseRolloutSelector is the ParseNamespaceSelector equivalent for
+// ROLLOUT_SELECTOR.
+func ParseRolloutSelector(raw string) (string, error) {
+	return parseSelector(raw, "rollout selector")
+}
+
+// parseSelector implements ParseNamespaceSelector and ParseRolloutSelector;
+// label names the option in error messages.
+func parseSelector(raw, label string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	selector, err := labels.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("invalid %s %q: %w", label, raw, err)
+	}
+	return selector.String(), nil
+}
+
+// ParseExcludeNamespaces splits a comma-separated list of namespace names and
+// glob patterns ("*", "?" and "[...]" as in path.Match) into a trimmed,
+// non-empty slice, and returns an error for a malformed pattern.
+func ParseExcludeNamespaces(raw string) ([]string, error) {
+	return parsePatterns(raw, "namespace exclusion")
+}
+
+// ParseRolloutPatterns is the ParseExcludeNamespaces equivalent for
+// ROLLOUT_INCLUDE and ROLLOUT_EXCLUDE.
+func ParseRolloutPatterns(raw string) ([]string, error) {
+	return parsePatterns(raw, "rollout pattern")
+}
+
+// parsePatterns implements ParseExcludeNamespaces and ParseRolloutPatterns;
+// label names the option in error messages.
+func parsePatterns(raw, label string) ([]string, error) {
+	var patterns []string
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid %s %q: %w", label, p, err)
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
 }
 
 // ParseNamespaces splits a comma-separated namespace string into a trimmed,
@@ -42,6 +473,59 @@ func ParseNamespaces(raw, defaultNS string) []string {
 	return result
 }
 
+// ParseWorkloadKinds splits a comma-separated workload kind string into a
+// deduplicated slice of canonical kind names (matching is case-insensitive,
+// so "deployment" becomes "Deployment"). Falls back to DefaultWorkloadKind when
+// the input is blank, and returns an error for any unsupported kind.
+func ParseWorkloadKinds(raw string) ([]string, error) {
+	return parseKinds(raw, "workload kind", SupportedWorkloadKinds, DefaultWorkloadKind)
+}
+
+// ParseResourceKinds is the ParseWorkloadKinds equivalent for RESOURCE_KINDS:
+// "configmap,secret" becomes ["ConfigMap", "Secret"]. Falls back to
+// DefaultResourceKind when the input is blank.
+func ParseResourceKinds(raw string) ([]string, error) {
+	return parseKinds(raw, "resource kind", SupportedResourceKinds, DefaultResourceKind)
+}
+
+// ParseRolloutPhases is the ParseWorkloadKinds equivalent for ROLLOUT_PHASES:
+// "healthy,paused" becomes ["Healthy", "Paused"]. Falls back to
+// DefaultRolloutPhase when the input is blank.
+func ParseRolloutPhases(raw string) ([]string, error) {
+	return parseKinds(raw, "rollout phase", SupportedRolloutPhases, DefaultRolloutPhase)
+}
+
+// parseKinds implements ParseWorkloadKinds, ParseResourceKinds and
+// ParseRolloutPhases; label names the option in error messages.
+func parseKinds(raw, label string, supported []string, defaultKind string) ([]string, error) {
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, p := range strings.Split(raw, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		kind := ""
+		for _, s := range supported {
+			if strings.EqualFold(p, s) {
+				kind = s
+				break
+			}
+		}
+		if kind == "" {
+			return nil, fmt.Errorf("unsupported %s %q (supported: %s)", label, p, strings.Join(supported, ", "))
+		}
+		if !seen[kind] {
+			seen[kind] = true
+			kinds = append(kinds, kind)
+		}
+	}
+	if len(kinds) == 0 {
+		return []string{defaultKind}, nil
+	}
+	return kinds, nil
+}
+
 // Load reads configuration from environment variables with fallback to defaults.
 // Priority: environment variables > default values.
 // A fresh viper.Viper instance is created on every call to avoid global state
@@ -51,22 +535,168 @@ func Load() (*Config, error) {
 
 	// Set defaults
 	v.SetDefault("NAMESPACE", "mwpcloud")
-	v.SetDefault("APP_LABEL", "xzk0-seat")
+	v.SetDefault("ALL_NAMESPACES", false)
+	v.SetDefault("NAMESPACE_SELECTOR", "")
+	v.SetDefault("EXCLUDE_NAMESPACES", "")
+	v.SetDefault("ROLLOUT_INCLUDE", "")
+	v.SetDefault("ROLLOUT_EXCLUDE", "")
+	v.SetDefault("ROLLOUT_SELECTOR", "")
+	v.SetDefault("APP_LABEL", "")
 	v.SetDefault("KEEP_LAST", 5)
 	v.SetDefault("KEEP_DAYS", 7)
 	v.SetDefault("DRY_RUN", true)
 	v.SetDefault("LOG_LEVEL", "info")
 	v.SetDefault("LOG_FORMAT", "text")
+	v.SetDefault("WORKLOAD_KINDS", DefaultWorkloadKind)
+	v.SetDefault("RESOURCE_KINDS", DefaultResourceKind)
+	v.SetDefault("RETENTION_MODE", RetentionKeepLast)
+	v.SetDefault("CONFIGMAP_NAME_TEMPLATE", DefaultConfigMapNameTemplate)
+	v.SetDefault("SECRET_NAME_TEMPLATE", DefaultSecretNameTemplate)
+	v.SetDefault("HASH_LENGTH", naming.DefaultHashLength)
+	v.SetDefault("HASH_ALPHABET", naming.DefaultHashAlphabet)
+	v.SetDefault("STEM_REFERENCES", false)
+	v.SetDefault("ROLLOUT_PHASES", DefaultRolloutPhase)
+	v.SetDefault("PAGE_SIZE", 500)
+	v.SetDefault("CONCURRENCY", DefaultConcurrency)
+	v.SetDefault("NAMESPACE_TIMEOUT", DefaultNamespaceTimeout.String())
+	v.SetDefault("RUN_TIMEOUT", DefaultRunTimeout.String())
+	v.SetDefault("RESYNC_PERIOD", DefaultResyncPeriod.String())
+	v.SetDefault("LEADER_ELECT", false)
+	v.SetDefault("LEASE_NAME", DefaultLeaseName)
+	v.SetDefault("LEASE_NAMESPACE", "")
+	v.SetDefault("LEASE_DURATION", DefaultLeaseDuration.String())
+	v.SetDefault("RENEW_DEADLINE", DefaultRenewDeadline.String())
+	v.SetDefault("RETRY_PERIOD", DefaultRetryPeriod.String())
+	v.SetDefault("METRICS_ADDR", "")
+	v.SetDefault("METRICS_FILE", "")
+	v.SetDefault("METRICS_PUSHGATEWAY", "")
+	v.SetDefault("EMIT_EVENTS", true)
+	v.SetDefault("REPORT", "")
+	v.SetDefault("REPORT_FORMAT", ReportFormatJSON)
 
 	v.AutomaticEnv()
 
-	return &Config{
-		Namespaces: ParseNamespaces(v.GetString("NAMESPACE"), "mwpcloud"),
-		AppLabel:   v.GetString("APP_LABEL"),
-		KeepLast:   v.GetInt("KEEP_LAST"),
-		KeepDays:   v.GetInt("KEEP_DAYS"),
-		DryRun:     v.GetBool("DRY_RUN"),
-		LogLevel:   v.GetString("LOG_LEVEL"),
-		LogFormat:  v.GetString("LOG_FORMAT"),
-	}, nil
+	workloadKinds, err := ParseWorkloadKinds(v.GetString("WORKLOAD_KINDS"))
+	if err != nil {
+		return nil, err
+	}
+	resourceKinds, err := ParseResourceKinds(v.GetString("RESOURCE_KINDS"))
+	if err != nil {
+		return nil, err
+	}
+	retentionMode, err := ParseRetentionMode(v.GetString("RETENTION_MODE"))
+	if err != nil {
+		return nil, err
+	}
+	rolloutPhases, err := ParseRolloutPhases(v.GetString("ROLLOUT_PHASES"))
+	if err != nil {
+		return nil, err
+	}
+	appLabel, err := ParseAppLabel(v.GetString("APP_LABEL"))
+	if err != nil {
+		return nil, err
+	}
+	namespaceSelector, err := ParseNamespaceSelector(v.GetString("NAMESPACE_SELECTOR"))
+	if err != nil {
+		return nil, err
+	}
+	excludeNamespaces, err := ParseExcludeNamespaces(v.GetString("EXCLUDE_NAMESPACES"))
+	if err != nil {
+		return nil, err
+	}
+	includeRollouts, err := ParseRolloutPatterns(v.GetString("ROLLOUT_INCLUDE"))
+	if err != nil {
+		return nil, err
+	}
+	excludeRollouts, err := ParseRolloutPatterns(v.GetString("ROLLOUT_EXCLUDE"))
+	if err != nil {
+		return nil, err
+	}
+	rolloutSelector, err := ParseRolloutSelector(v.GetString("ROLLOUT_SELECTOR"))
+	if err != nil {
+		return nil, err
+	}
+	reportFormat, err := ParseReportFormat(v.GetString("REPORT_FORMAT"))
+	if err != nil {
+		return nil, err
+	}
+	pageSize := v.GetInt("PAGE_SIZE")
+	if err := ValidatePageSize(pageSize); err != nil {
+		return nil, err
+	}
+	concurrency := v.GetInt("CONCURRENCY")
+	if err := ValidateConcurrency(concurrency); err != nil {
+		return nil, err
+	}
+	namespaceTimeout, err := ParseTimeout("namespace timeout", v.GetString("NAMESPACE_TIMEOUT"))
+	if err != nil {
+		return nil, err
+	}
+	runTimeout, err := ParseTimeout("run timeout", v.GetString("RUN_TIMEOUT"))
+	if err != nil {
+		return nil, err
+	}
+	resyncPeriod, err := ParseTimeout("resync period", v.GetString("RESYNC_PERIOD"))
+	if err != nil {
+		return nil, err
+	}
+	leaseDuration, err := ParseTimeout("lease duration", v.GetString("LEASE_DURATION"))
+	if err != nil {
+		return nil, err
+	}
+	renewDeadline, err := ParseTimeout("renew deadline", v.GetString("RENEW_DEADLINE"))
+	if err != nil {
+		return nil, err
+	}
+	retryPeriod, err := ParseTimeout("retry period", v.GetString("RETRY_PERIOD"))
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &Config{
+		Namespaces:        ParseNamespaces(v.GetString("NAMESPACE"), "mwpcloud"),
+		AllNamespaces:     v.GetBool("ALL_NAMESPACES"),
+		NamespaceSelector: namespaceSelector,
+		ExcludeNamespaces: excludeNamespaces,
+		AppLabel:          appLabel,
+		KeepLast:          v.GetInt("KEEP_LAST"),
+		KeepDays:          v.GetInt("KEEP_DAYS"),
+		DryRun:            v.GetBool("DRY_RUN"),
+		LogLevel:          v.GetString("LOG_LEVEL"),
+		LogFormat:         v.GetString("LOG_FORMAT"),
+		WorkloadKinds:     workloadKinds,
+		ResourceKinds:     resourceKinds,
+		RetentionMode:     retentionMode,
+
+		ConfigMapNameTemplate: v.GetString("CONFIGMAP_NAME_TEMPLATE"),
+		SecretNameTemplate:    v.GetString("SECRET_NAME_TEMPLATE"),
+		HashLength:            v.GetInt("HASH_LENGTH"),
+		HashAlphabet:          v.GetString("HASH_ALPHABET"),
+		StemReferences:        v.GetBool("STEM_REFERENCES"),
+		RolloutPhases:         rolloutPhases,
+		IncludeRollouts:       includeRollouts,
+		ExcludeRollouts:       excludeRollouts,
+		RolloutSelector:       rolloutSelector,
+		PageSize:              pageSize,
+		Concurrency:           concurrency,
+		NamespaceTimeout:      namespaceTimeout,
+		RunTimeout:            runTimeout,
+		ResyncPeriod:          resyncPeriod,
+		LeaderElect:           v.GetBool("LEADER_ELECT"),
+		LeaseName:             strings.TrimSpace(v.GetString("LEASE_NAME")),
+		LeaseNamespace:        strings.TrimSpace(v.GetString("LEASE_NAMESPACE")),
+		LeaseDuration:         leaseDuration,
+		RenewDeadline:         renewDeadline,
+		RetryPeriod:           retryPeriod,
+		MetricsAddr:           strings.TrimSpace(v.GetString("METRICS_ADDR")),
+		MetricsFile:           strings.TrimSpace(v.GetString("METRICS_FILE")),
+		MetricsPushURL:        strings.TrimSpace(v.GetString("METRICS_PUSHGATEWAY")),
+		EmitEvents:            v.GetBool("EMIT_EVENTS"),
+		Report:                strings.TrimSpace(v.GetString("REPORT")),
+		ReportFormat:          reportFormat,
+	}
+	if err := cfg.ValidateLeaderElection(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
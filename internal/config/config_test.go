@@ -2,6 +2,7 @@ package config
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -12,26 +13,58 @@ var allEnvKeys = []string{
 	"NAMESPACE", "APP_LABEL",
 	"KEEP_LAST", "KEEP_DAYS", "DRY_RUN",
 	"LOG_LEVEL", "LOG_FORMAT",
+	"WORKLOAD_KINDS", "RESOURCE_KINDS", "RETENTION_MODE",
+	"CONFIGMAP_NAME_TEMPLATE", "SECRET_NAME_TEMPLATE", "HASH_LENGTH", "HASH_ALPHABET",
+	"ROLLOUT_PHASES", "PAGE_SIZE",
+	"CONCURRENCY", "NAMESPACE_TIMEOUT", "RUN_TIMEOUT", "RESYNC_PERIOD",
+	"LEADER_ELECT", "LEASE_NAME", "LEASE_NAMESPACE", "LEASE_DURATION", "RENEW_DEADLINE", "RETRY_PERIOD",
+	"METRICS_ADDR", "METRICS_FILE", "METRICS_PUSHGATEWAY", "EMIT_EVENTS", "REPORT", "REPORT_FORMAT",
+	"STEM_REFERENCES",
+	"ALL_NAMESPACES", "NAMESPACE_SELECTOR", "EXCLUDE_NAMESPACES",
+	"ROLLOUT_INCLUDE", "ROLLOUT_EXCLUDE", "ROLLOUT_SELECTOR",
+}
+
+// defaultConfig returns the Config Load returns when no env key is set.
+func defaultConfig() Config {
+	return Config{
+		Namespaces:            []string{"mwpcloud"},
+		KeepLast:              5,
+		KeepDays:              7,
+		DryRun:                true,
+		LogLevel:              "info",
+		LogFormat:             "text",
+		WorkloadKinds:         []string{"Rollout"},
+		ResourceKinds:         []string{"ConfigMap"},
+		RetentionMode:         "keep-last",
+		ConfigMapNameTemplate: "{workload}-config-{hash}",
+		SecretNameTemplate:    "{workload}-secret-{hash}",
+		HashLength:            8,
+		HashAlphabet:          "0123456789abcdef",
+		RolloutPhases:         []string{"Healthy"},
+		PageSize:              500,
+		Concurrency:           10,
+		NamespaceTimeout:      5 * time.Minute,
+		RunTimeout:            30 * time.Minute,
+		ResyncPeriod:          time.Hour,
+		LeaseName:             "cm-gc",
+		LeaseDuration:         15 * time.Second,
+		RenewDeadline:         10 * time.Second,
+		RetryPeriod:           2 * time.Second,
+		EmitEvents:            true,
+		ReportFormat:          "json",
+	}
 }
 
 func TestLoad(t *testing.T) {
 	tests := []struct {
-		name     string
-		envVars  map[string]string
-		expected Config
+		name    string
+		envVars map[string]string
+		// override turns defaultConfig() into the expected Config.
+		override func(c *Config)
 	}{
 		{
 			name:    "all defaults when no env vars set",
 			envVars: map[string]string{},
-			expected: Config{
-				Namespaces: []string{"mwpcloud"},
-				AppLabel:   "xzk0-seat",
-				KeepLast:   5,
-				KeepDays:   7,
-				DryRun:     true,
-				LogLevel:   "info",
-				LogFormat:  "text",
-			},
 		},
 		{
 			name: "all fields overridden by env vars",
@@ -44,14 +77,14 @@ func TestLoad(t *testing.T) {
 				"LOG_LEVEL":  "debug",
 				"LOG_FORMAT": "json",
 			},
-			expected: Config{
-				Namespaces: []string{"production"},
-				AppLabel:   "my-app",
-				KeepLast:   3,
-				KeepDays:   14,
-				DryRun:     false,
-				LogLevel:   "debug",
-				LogFormat:  "json",
+			override: func(c *Config) {
+				c.Namespaces = []string{"production"}
+				c.AppLabel = "app=my-app"
+				c.KeepLast = 3
+				c.KeepDays = 14
+				c.DryRun = false
+				c.LogLevel = "debug"
+				c.LogFormat = "json"
 			},
 		},
 		{
@@ -59,14 +92,8 @@ func TestLoad(t *testing.T) {
 			envVars: map[string]string{
 				"NAMESPACE": "staging",
 			},
-			expected: Config{
-				Namespaces: []string{"staging"},
-				AppLabel:   "xzk0-seat",
-				KeepLast:   5,
-				KeepDays:   7,
-				DryRun:     true,
-				LogLevel:   "info",
-				LogFormat:  "text",
+			override: func(c *Config) {
+				c.Namespaces = []string{"staging"}
 			},
 		},
 		{
@@ -74,29 +101,169 @@ func TestLoad(t *testing.T) {
 			envVars: map[string]string{
 				"DRY_RUN": "true",
 			},
-			expected: Config{
-				Namespaces: []string{"mwpcloud"},
-				AppLabel:   "xzk0-seat",
-				KeepLast:   5,
-				KeepDays:   7,
-				DryRun:     true,
-				LogLevel:   "info",
-				LogFormat:  "text",
-			},
 		},
 		{
 			name: "multiple namespaces comma-separated",
 			envVars: map[string]string{
 				"NAMESPACE": "mwpcloud,staging-ns,prod-ns",
 			},
-			expected: Config{
-				Namespaces: []string{"mwpcloud", "staging-ns", "prod-ns"},
-				AppLabel:   "xzk0-seat",
-				KeepLast:   5,
-				KeepDays:   7,
-				DryRun:     true,
-				LogLevel:   "info",
-				LogFormat:  "text",
+			override: func(c *Config) {
+				c.Namespaces = []string{"mwpcloud", "staging-ns", "prod-ns"}
+			},
+		},
+		{
+			name: "WORKLOAD_KINDS selects several kinds",
+			envVars: map[string]string{
+				"WORKLOAD_KINDS": "rollout,Deployment, statefulset",
+			},
+			override: func(c *Config) {
+				c.WorkloadKinds = []string{"Rollout", "Deployment", "StatefulSet"}
+			},
+		},
+		{
+			name: "RESOURCE_KINDS enables Secret collection",
+			envVars: map[string]string{
+				"RESOURCE_KINDS": "configmap,secret",
+			},
+			override: func(c *Config) {
+				c.ResourceKinds = []string{"ConfigMap", "Secret"}
+			},
+		},
+		{
+			name: "RETENTION_MODE selects revision-history retention",
+			envVars: map[string]string{
+				"RETENTION_MODE": "Revision-History",
+			},
+			override: func(c *Config) {
+				c.RetentionMode = "revision-history"
+			},
+		},
+		{
+			name: "naming template env vars select a Kustomize-style suffix",
+			envVars: map[string]string{
+				"CONFIGMAP_NAME_TEMPLATE": "{workload}-{hash}",
+				"SECRET_NAME_TEMPLATE":    "{workload}-creds-{hash}",
+				"HASH_LENGTH":             "10",
+				"HASH_ALPHABET":           "bcdfghkmt2456789",
+			},
+			override: func(c *Config) {
+				c.ConfigMapNameTemplate = "{workload}-{hash}"
+				c.SecretNameTemplate = "{workload}-creds-{hash}"
+				c.HashLength = 10
+				c.HashAlphabet = "bcdfghkmt2456789"
+			},
+		},
+		{
+			name: "rollout phases and page size from env",
+			envVars: map[string]string{
+				"ROLLOUT_PHASES": "healthy,Paused",
+				"PAGE_SIZE":      "0",
+			},
+			override: func(c *Config) {
+				c.RolloutPhases = []string{"Healthy", "Paused"}
+				c.PageSize = 0
+			},
+		},
+		{
+			name: "concurrency and timeouts from env",
+			envVars: map[string]string{
+				"CONCURRENCY":       "3",
+				"NAMESPACE_TIMEOUT": "90s",
+				"RUN_TIMEOUT":       "0",
+				"RESYNC_PERIOD":     "10m",
+			},
+			override: func(c *Config) {
+				c.Concurrency = 3
+				c.NamespaceTimeout = 90 * time.Second
+				c.RunTimeout = 0
+				c.ResyncPeriod = 10 * time.Minute
+			},
+		},
+		{
+			name: "namespace discovery from env",
+			envVars: map[string]string{
+				"ALL_NAMESPACES":     "true",
+				"NAMESPACE_SELECTOR": "team, env != sandbox",
+				"EXCLUDE_NAMESPACES": "monitoring, *-sandbox",
+			},
+			override: func(c *Config) {
+				c.AllNamespaces = true
+				c.NamespaceSelector = "env!=sandbox,team"
+				c.ExcludeNamespaces = []string{"monitoring", "*-sandbox"}
+			},
+		},
+		{
+			name: "rollout filters from env",
+			envVars: map[string]string{
+				"ROLLOUT_INCLUDE":  "seat-*,order",
+				"ROLLOUT_EXCLUDE":  "*-legacy",
+				"ROLLOUT_SELECTOR": "gc!=off",
+			},
+			override: func(c *Config) {
+				c.IncludeRollouts = []string{"seat-*", "order"}
+				c.ExcludeRollouts = []string{"*-legacy"}
+				c.RolloutSelector = "gc!=off"
+			},
+		},
+		{
+			name: "leader election from env",
+			envVars: map[string]string{
+				"LEADER_ELECT":    "true",
+				"LEASE_NAME":      "cm-gc-prod",
+				"LEASE_NAMESPACE": "platform",
+				"LEASE_DURATION":  "30s",
+				"RENEW_DEADLINE":  "20s",
+				"RETRY_PERIOD":    "5s",
+			},
+			override: func(c *Config) {
+				c.LeaderElect = true
+				c.LeaseName = "cm-gc-prod"
+				c.LeaseNamespace = "platform"
+				c.LeaseDuration = 30 * time.Second
+				c.RenewDeadline = 20 * time.Second
+				c.RetryPeriod = 5 * time.Second
+			},
+		},
+		{
+			name: "metrics outputs from env",
+			envVars: map[string]string{
+				"METRICS_ADDR":        ":9090",
+				"METRICS_FILE":        " /var/lib/node-exporter/cm-gc.prom ",
+				"METRICS_PUSHGATEWAY": "http://pushgateway:9091",
+			},
+			override: func(c *Config) {
+				c.MetricsAddr = ":9090"
+				c.MetricsFile = "/var/lib/node-exporter/cm-gc.prom"
+				c.MetricsPushURL = "http://pushgateway:9091"
+			},
+		},
+		{
+			name: "events disabled from env",
+			envVars: map[string]string{
+				"EMIT_EVENTS": "false",
+			},
+			override: func(c *Config) {
+				c.EmitEvents = false
+			},
+		},
+		{
+			name: "reference stemming enabled from env",
+			envVars: map[string]string{
+				"STEM_REFERENCES": "true",
+			},
+			override: func(c *Config) {
+				c.StemReferences = true
+			},
+		},
+		{
+			name: "report from env",
+			envVars: map[string]string{
+				"REPORT":        " /tmp/plan.md ",
+				"REPORT_FORMAT": "Markdown",
+			},
+			override: func(c *Config) {
+				c.Report = "/tmp/plan.md"
+				c.ReportFormat = "markdown"
 			},
 		},
 		{
@@ -104,14 +271,8 @@ func TestLoad(t *testing.T) {
 			envVars: map[string]string{
 				"NAMESPACE": " mwpcloud , staging-ns ",
 			},
-			expected: Config{
-				Namespaces: []string{"mwpcloud", "staging-ns"},
-				AppLabel:   "xzk0-seat",
-				KeepLast:   5,
-				KeepDays:   7,
-				DryRun:     true,
-				LogLevel:   "info",
-				LogFormat:  "text",
+			override: func(c *Config) {
+				c.Namespaces = []string{"mwpcloud", "staging-ns"}
 			},
 		},
 	}
@@ -126,11 +287,15 @@ func TestLoad(t *testing.T) {
 			for k, v := range tt.envVars {
 				t.Setenv(k, v)
 			}
+			expected := defaultConfig()
+			if tt.override != nil {
+				tt.override(&expected)
+			}
 
 			cfg, err := Load()
 
 			assert.NoError(t, err)
-			assert.Equal(t, tt.expected, *cfg)
+			assert.Equal(t, expected, *cfg)
 		})
 	}
 }
@@ -181,3 +346,307 @@ func TestParseNamespaces(t *testing.T) {
 		})
 	}
 }
+
+func TestLoad_InvalidWorkloadKind(t *testing.T) {
+	for _, key := range allEnvKeys {
+		t.Setenv(key, "")
+	}
+	t.Setenv("WORKLOAD_KINDS", "Rollout,CronJob")
+
+	cfg, err := Load()
+
+	assert.Error(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_NegativePageSize(t *testing.T) {
+	for _, key := range allEnvKeys {
+		t.Setenv(key, "")
+	}
+	t.Setenv("PAGE_SIZE", "-1")
+
+	cfg, err := Load()
+
+	assert.Error(t, err)
+	assert.Nil(t, cfg)
+}
+
+func TestLoad_InvalidValues(t *testing.T) {
+	for _, env := range []map[string]string{
+		{"CONCURRENCY": "0"},
+		{"NAMESPACE_TIMEOUT": "-1m"},
+		{"RUN_TIMEOUT": "soon"},
+		{"RESYNC_PERIOD": "-1h"},
+		{"LEASE_NAME": " "},
+		{"LEASE_DURATION": "10s"},
+		{"RENEW_DEADLINE": "1s"},
+		{"RETRY_PERIOD": "0s"},
+		{"NAMESPACE_SELECTOR": "team in (a"},
+		{"EXCLUDE_NAMESPACES": "team-["},
+		{"ROLLOUT_INCLUDE": "seat-["},
+		{"ROLLOUT_SELECTOR": "gc in (off"},
+		{"REPORT_FORMAT": "pdf"},
+	} {
+		for _, key := range allEnvKeys {
+			t.Setenv(key, "")
+		}
+		for k, v := range env {
+			t.Setenv(k, v)
+		}
+
+		cfg, err := Load()
+
+		assert.Error(t, err, env)
+		assert.Nil(t, cfg)
+	}
+}
+
+func TestParseWorkloadKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "empty string returns default",
+			raw:      "",
+			expected: []string{"Rollout"},
+		},
+		{
+			name:     "all supported kinds, case-insensitive",
+			raw:      "ROLLOUT,deployment,StatefulSet,daemonset",
+			expected: []string{"Rollout", "Deployment", "StatefulSet", "DaemonSet"},
+		},
+		{
+			name:     "duplicates and blanks are dropped",
+			raw:      " Deployment, ,deployment ",
+			expected: []string{"Deployment"},
+		},
+		{
+			name:    "unsupported kind returns error",
+			raw:     "Rollout,Job",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseWorkloadKinds(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseResourceKinds(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "empty string returns default", raw: "", expected: []string{"ConfigMap"}},
+		{name: "secrets only", raw: "SECRET", expected: []string{"Secret"}},
+		{name: "both kinds", raw: "secret, configmap", expected: []string{"Secret", "ConfigMap"}},
+		{name: "unsupported kind returns error", raw: "ConfigMap,PersistentVolumeClaim", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseResourceKinds(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseAppLabel(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "empty string selects everything", raw: " ", expected: ""},
+		{name: "bare value selects on the app label", raw: "xzk0-seat", expected: "app=xzk0-seat"},
+		{name: "equality selector", raw: "app.kubernetes.io/name=seat", expected: "app.kubernetes.io/name=seat"},
+		{name: "set-based selector", raw: "app in (seat,order), tier!=batch", expected: "app in (order,seat),tier!=batch"},
+		{name: "existence selector", raw: "app,!canary", expected: "app,!canary"},
+		{name: "invalid selector returns error", raw: "app in (seat", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAppLabel(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseRolloutPhases(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected []string
+		wantErr  bool
+	}{
+		{name: "empty string returns default", raw: "", expected: []string{"Healthy"}},
+		{name: "case-insensitive and deduplicated", raw: "paused, Healthy,PAUSED", expected: []string{"Paused", "Healthy"}},
+		{name: "every phase", raw: "Healthy,Progressing,Paused,Degraded", expected: []string{"Healthy", "Progressing", "Paused", "Degraded"}},
+		{name: "unknown phase returns error", raw: "Healthy,Aborted", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRolloutPhases(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseRetentionMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "empty string returns keep-last", raw: "", expected: RetentionKeepLast},
+		{name: "keep-last", raw: "keep-last", expected: RetentionKeepLast},
+		{name: "revision-history with spaces and case", raw: " Revision-History ", expected: RetentionRevisionHistory},
+		{name: "unknown mode returns error", raw: "forever", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRetentionMode(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		expected string
+		wantErr  bool
+	}{
+		{name: "empty string returns json", raw: "", expected: "json"},
+		{name: "yaml", raw: "yaml", expected: "yaml"},
+		{name: "markdown with spaces and case", raw: " Markdown ", expected: "markdown"},
+		{name: "unknown format returns error", raw: "pdf", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseReportFormat(tt.raw)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestConfig_NameTemplate(t *testing.T) {
+	cfg := &Config{
+		ConfigMapNameTemplate: DefaultConfigMapNameTemplate,
+		SecretNameTemplate:    DefaultSecretNameTemplate,
+	}
+	assert.Equal(t, "{workload}-config-{hash}", cfg.NameTemplate("ConfigMap"))
+	assert.Equal(t, "{workload}-secret-{hash}", cfg.NameTemplate("Secret"))
+}
+
+func TestParseExcludeNamespaces(t *testing.T) {
+	got, err := ParseExcludeNamespaces(" monitoring, ,*-sandbox,team-[ab]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"monitoring", "*-sandbox", "team-[ab]"}, got)
+
+	got, err = ParseExcludeNamespaces("")
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = ParseExcludeNamespaces("team-[")
+	assert.Error(t, err)
+}
+
+func TestConfig_Excluded(t *testing.T) {
+	cfg := &Config{ExcludeNamespaces: []string{"monitoring", "*-sandbox", "team-[ab]"}}
+
+	for ns, want := range map[string]bool{
+		"kube-system":     true,
+		"kube-public":     true,
+		"kube-node-lease": true,
+		"monitoring":      true,
+		"seat-sandbox":    true,
+		"team-a":          true,
+		"team-c":          false,
+		"mwpcloud":        false,
+		"monitoring-2":    false,
+	} {
+		assert.Equal(t, want, cfg.Excluded(ns), ns)
+	}
+}
+
+func TestConfig_Discover(t *testing.T) {
+	assert.False(t, (&Config{Namespaces: []string{"mwpcloud"}}).Discover())
+	assert.True(t, (&Config{AllNamespaces: true}).Discover())
+	assert.True(t, (&Config{NamespaceSelector: "team"}).Discover())
+}
+
+func TestConfig_RolloutExclusion(t *testing.T) {
+	cfg := &Config{
+		IncludeRollouts: []string{"seat-*", "order"},
+		ExcludeRollouts: []string{"*-legacy"},
+		RolloutSelector: "gc!=off",
+	}
+	tests := []struct {
+		name        string
+		rollout     string
+		labels      map[string]string
+		annotations map[string]string
+		expected    string
+	}{
+		{name: "included", rollout: "seat-api", expected: ""},
+		{name: "included by exact name", rollout: "order", expected: ""},
+		{name: "opt-out annotation wins", rollout: "seat-api", annotations: map[string]string{ExcludeAnnotation: "true"}, expected: ExclusionAnnotation},
+		{name: "annotation must be true", rollout: "seat-api", annotations: map[string]string{ExcludeAnnotation: "no"}, expected: ""},
+		{name: "no include pattern matches", rollout: "billing", expected: ExclusionNotIncluded},
+		{name: "exclude pattern matches", rollout: "seat-legacy", expected: ExclusionExcluded},
+		{name: "selector does not match", rollout: "seat-api", labels: map[string]string{"gc": "off"}, expected: ExclusionSelector},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, cfg.RolloutExclusion(tt.rollout, tt.labels, tt.annotations))
+		})
+	}
+
+	assert.Empty(t, (&Config{}).RolloutExclusion("anything", nil, nil), "no filters include every rollout")
+}
@@ -0,0 +1,184 @@
+package k8s
+
+// Experiment and AnalysisRun pod templates.
+// Argo Rollouts Experiments run their own ReplicaSets from spec.templates, and
+// AnalysisRuns with a job metric provider run Jobs from the metric's job spec.
+// Neither is owned by kind=Rollout, so without this their pod templates — and
+// the ConfigMaps they reference — would be invisible to the in-use resolver.
+//
+// analysisRevisions reports the templates of every running Experiment and
+// AnalysisRun, and of those completed within RecentAnalysisWindow, as
+// revisions of the Rollout that started them: an Experiment through its
+// ownerReference, an AnalysisRun through its Rollout or its Experiment's
+// Rollout. Running ones are flagged Strategy, since the rollout step waits on
+// them. Objects not started by a Rollout are attributed to themselves; while
+// they run they are flagged Shared and protect every family in the namespace,
+// since any Rollout's ConfigMap may be the one they test.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Owner kinds of Argo Rollouts analysis objects.
+const (
+	KindExperiment  = "Experiment"
+	KindAnalysisRun = "AnalysisRun"
+)
+
+// RecentAnalysisWindow is how long after completion an Experiment's or
+// AnalysisRun's templates still count as in use, so a ConfigMap is not
+// collected while its results are being inspected or the analysis is retried.
+const RecentAnalysisWindow = time.Hour
+
+// AnalysisLister lists the Experiments and AnalysisRuns of a namespace.
+type AnalysisLister interface {
+	ListExperiments(ctx context.Context, namespace string) ([]rolloutsv1alpha1.Experiment, error)
+	ListAnalysisRuns(ctx context.Context, namespace string) ([]rolloutsv1alpha1.AnalysisRun, error)
+}
+
+// KubeAnalysisClient is the AnalysisLister backed by a real (or fake) Argo
+// Rollouts clientset.
+type KubeAnalysisClient struct {
+	client rolloutclientset.Interface
+	// scope pages the listings; analysis objects are never label-filtered, so
+	// they protect a Rollout's ConfigMaps whatever labels they carry.
+	scope listScope
+}
+
+// NewKubeAnalysisClient creates a KubeAnalysisClient wrapping the provided
+// clientset. Pass rolloutfake.NewSimpleClientset() in tests.
+func NewKubeAnalysisClient(client rolloutclientset.Interface) *KubeAnalysisClient {
+	return &KubeAnalysisClient{client: client}
+}
+
+// ListExperiments returns every Experiment in the namespace.
+func (k *KubeAnalysisClient) ListExperiments(ctx context.Context, namespace string) ([]rolloutsv1alpha1.Experiment, error) {
+	experiments, err := listAll(ctx, "experiments", k.scope, k.client.ArgoprojV1alpha1().Experiments(namespace).List,
+		func(l *rolloutsv1alpha1.ExperimentList) []rolloutsv1alpha1.Experiment { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list experiments in namespace %q: %w", namespace, err)
+	}
+	return experiments, nil
+}
+
+// ListAnalysisRuns returns every AnalysisRun in the namespace.
+func (k *KubeAnalysisClient) ListAnalysisRuns(ctx context.Context, namespace string) ([]rolloutsv1alpha1.AnalysisRun, error) {
+	runs, err := listAll(ctx, "analysisruns", k.scope, k.client.ArgoprojV1alpha1().AnalysisRuns(namespace).List,
+		func(l *rolloutsv1alpha1.AnalysisRunList) []rolloutsv1alpha1.AnalysisRun { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list analysisruns in namespace %q: %w", namespace, err)
+	}
+	return runs, nil
+}
+
+// analysisRevisions lists Experiment and AnalysisRun pod templates as
+// revisions of their Rollouts.
+type analysisRevisions struct {
+	client AnalysisLister
+	// now returns the current time; replaced in tests.
+	now func() time.Time
+}
+
+// analysisOwner is the workload a revision of an analysis object is
+// attributed to.
+type analysisOwner struct {
+	name string
+	uid  types.UID
+	// rollout is set when the owner is a Rollout, not the object itself.
+	rollout bool
+}
+
+func (r analysisRevisions) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	experiments, err := r.client.ListExperiments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	runs, err := r.client.ListAnalysisRuns(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+
+	// Every Experiment's Rollout, by Experiment UID, so AnalysisRuns started by
+	// an Experiment are attributed to the same Rollout.
+	experimentOwners := make(map[types.UID]analysisOwner, len(experiments))
+	var revisions []TemplateRevision
+	for _, ex := range experiments {
+		owner := analysisOwnerOf(ex.ObjectMeta, KindExperiment, nil)
+		experimentOwners[ex.UID] = owner
+		if !analysisActive(ex.Status.Phase, lastActivity(ex.CreationTimestamp, ex.Status.AvailableAt), now) {
+			continue
+		}
+		for _, tpl := range ex.Spec.Templates {
+			revisions = append(revisions, TemplateRevision{
+				Name:        KindExperiment + "/" + ex.Name + "/" + tpl.Name,
+				OwnerName:   owner.name,
+				OwnerUID:    owner.uid,
+				Labels:      ex.Labels,
+				Annotations: ex.Annotations,
+				Strategy:    !ex.Status.Phase.Completed(),
+				Shared:      !owner.rollout && !ex.Status.Phase.Completed(),
+				Template:    tpl.Template,
+			})
+		}
+	}
+	for _, run := range runs {
+		if !analysisActive(run.Status.Phase, lastActivity(run.CreationTimestamp, run.Status.CompletedAt), now) {
+			continue
+		}
+		owner := analysisOwnerOf(run.ObjectMeta, KindAnalysisRun, experimentOwners)
+		for _, metric := range run.Spec.Metrics {
+			if metric.Provider.Job == nil {
+				continue
+			}
+			revisions = append(revisions, TemplateRevision{
+				Name:        KindAnalysisRun + "/" + run.Name + "/" + metric.Name,
+				OwnerName:   owner.name,
+				OwnerUID:    owner.uid,
+				Labels:      run.Labels,
+				Annotations: run.Annotations,
+				Strategy:    !run.Status.Phase.Completed(),
+				Shared:      !owner.rollout && !run.Status.Phase.Completed(),
+				Template:    metric.Provider.Job.Spec.Template,
+			})
+		}
+	}
+	return revisions, nil
+}
+
+// analysisOwnerOf returns the Rollout an analysis object belongs to: its
+// Rollout ownerReference, else the Rollout of its owning Experiment (looked up
+// in experimentOwners), else the object itself as "<kind>/<name>".
+func analysisOwnerOf(meta metav1.ObjectMeta, kind string, experimentOwners map[types.UID]analysisOwner) analysisOwner {
+	if ref, ok := ownerOfKind(meta.OwnerReferences, KindRollout); ok {
+		return analysisOwner{name: ref.Name, uid: ref.UID, rollout: true}
+	}
+	if ref, ok := ownerOfKind(meta.OwnerReferences, KindExperiment); ok {
+		if owner, ok := experimentOwners[ref.UID]; ok {
+			return owner
+		}
+	}
+	return analysisOwner{name: kind + "/" + meta.Name, uid: meta.UID}
+}
+
+// analysisActive reports whether an analysis object is still running, or
+// completed no longer than RecentAnalysisWindow before now.
+func analysisActive(phase rolloutsv1alpha1.AnalysisPhase, last time.Time, now time.Time) bool {
+	return !phase.Completed() || now.Sub(last) <= RecentAnalysisWindow
+}
+
+// lastActivity returns the latest of the creation time and an optional status
+// timestamp.
+func lastActivity(created metav1.Time, updated *metav1.Time) time.Time {
+	if updated != nil && updated.After(created.Time) {
+		return updated.Time
+	}
+	return created.Time
+}
@@ -0,0 +1,170 @@
+package k8s
+
+// Unit tests for analysis.go: ConfigMaps referenced by the pod templates of
+// Argo Rollouts Experiments and AnalysisRuns (job metrics). Mirrors
+// rolloutstatus_test.go — fake clientsets for the core API and Argo Rollouts.
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testExperimentUID = "6d1f0c2a-93b4-4e8e-a0f5-3c7b9e1d2a60"
+
+// makeExperiment builds an Experiment with one template mounting cmName.
+func makeExperiment(name, uid string, phase rolloutsv1alpha1.AnalysisPhase, created time.Time, cmName, checksum string) *rolloutsv1alpha1.Experiment {
+	return &rolloutsv1alpha1.Experiment{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         testNamespace,
+			Name:              name,
+			UID:               k8stypes.UID(uid),
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: rolloutsv1alpha1.ExperimentSpec{
+			Templates: []rolloutsv1alpha1.TemplateSpec{{Name: "canary", Template: makeTemplate(cmName, checksum)}},
+		},
+		Status: rolloutsv1alpha1.ExperimentStatus{Phase: phase},
+	}
+}
+
+// makeAnalysisRun builds an AnalysisRun with one job metric mounting cmName.
+func makeAnalysisRun(name string, phase rolloutsv1alpha1.AnalysisPhase, created time.Time, cmName, checksum string) *rolloutsv1alpha1.AnalysisRun {
+	return &rolloutsv1alpha1.AnalysisRun{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         testNamespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(created),
+		},
+		Spec: rolloutsv1alpha1.AnalysisRunSpec{
+			Metrics: []rolloutsv1alpha1.Metric{
+				{Name: "smoke", Provider: rolloutsv1alpha1.MetricProvider{
+					Job: &rolloutsv1alpha1.JobMetric{Spec: batchv1.JobSpec{Template: makeTemplate(cmName, checksum)}},
+				}},
+				{Name: "prometheus"},
+			},
+		},
+		Status: rolloutsv1alpha1.AnalysisRunStatus{Phase: phase},
+	}
+}
+
+func TestAnalysisRevisions(t *testing.T) {
+	now := time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC)
+
+	experiment := makeExperiment("xzk0-seat-65df947c4c-2-0", testExperimentUID,
+		rolloutsv1alpha1.AnalysisPhaseRunning, now.Add(-3*time.Hour), "xzk0-seat-config-b870a608", "b870a608")
+	experiment.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef(testRolloutName, testRolloutUID)}
+
+	// Started by the Experiment, not the Rollout directly.
+	viaExperiment := makeAnalysisRun("xzk0-seat-65df947c4c-2-0-smoke", rolloutsv1alpha1.AnalysisPhaseRunning,
+		now.Add(-time.Minute), "xzk0-seat-config-b870a608", "b870a608")
+	viaExperiment.OwnerReferences = []metav1.OwnerReference{makeOwnerRef(KindExperiment, experiment.Name, testExperimentUID)}
+
+	recent := makeAnalysisRun("xzk0-seat-847848bbcf-1", rolloutsv1alpha1.AnalysisPhaseSuccessful,
+		now.Add(-3*time.Hour), "xzk0-seat-config-e6120fae", "e6120fae")
+	recent.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef(testRolloutName, testRolloutUID)}
+	completedAt := metav1.NewTime(now.Add(-10 * time.Minute))
+	recent.Status.CompletedAt = &completedAt
+
+	stale := makeAnalysisRun("xzk0-seat-6977fddb67-1", rolloutsv1alpha1.AnalysisPhaseFailed,
+		now.Add(-3*time.Hour), "xzk0-seat-config-f3bca2cb", "f3bca2cb")
+	stale.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef(testRolloutName, testRolloutUID)}
+
+	standalone := makeAnalysisRun("load-test", rolloutsv1alpha1.AnalysisPhasePending,
+		now, "xzk0-seat-config-d5eb6ebf", "d5eb6ebf")
+
+	lister := analysisRevisions{
+		client: NewKubeAnalysisClient(rolloutfake.NewSimpleClientset(experiment, viaExperiment, recent, stale, standalone)),
+		now:    func() time.Time { return now },
+	}
+	revisions, err := lister.ListRevisions(context.Background(), testNamespace)
+	require.NoError(t, err)
+
+	type attribution struct {
+		owner    string
+		strategy bool
+		shared   bool
+	}
+	got := make(map[string]attribution, len(revisions))
+	for _, rev := range revisions {
+		got[rev.Name] = attribution{owner: rev.OwnerName, strategy: rev.Strategy, shared: rev.Shared}
+	}
+	assert.Equal(t, map[string]attribution{
+		"Experiment/xzk0-seat-65df947c4c-2-0/canary":       {owner: testRolloutName, strategy: true},
+		"AnalysisRun/xzk0-seat-65df947c4c-2-0-smoke/smoke": {owner: testRolloutName, strategy: true},
+		"AnalysisRun/xzk0-seat-847848bbcf-1/smoke":         {owner: testRolloutName, strategy: false},
+		"AnalysisRun/load-test/smoke":                      {owner: "AnalysisRun/load-test", strategy: true, shared: true},
+	}, got)
+
+	// The standalone run's Rollout is unknown, so its checksum protects the
+	// candidates of every workload, the Rollout's own included.
+	scoped, err := NewWorkloadInUseResolver(lister).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	for _, owner := range []k8stypes.UID{testRolloutUID, "uid-other"} {
+		assert.True(t, scoped.For(ResourceConfigMap, owner).Matches("xzk0-seat-config-d5eb6ebf", "d5eb6ebf"))
+		assert.True(t, scoped.StrategyFor(ResourceConfigMap, owner).Checksums["d5eb6ebf"])
+	}
+	assert.False(t, scoped.For(ResourceConfigMap, "uid-other").Checksums["b870a608"],
+		"a Rollout's own analyses protect only its candidates")
+}
+
+// TestAnalysisRevisions_OwnerlessExperiment verifies that an AnalysisRun
+// started by an Experiment without a Rollout is Shared while it runs, and
+// that a completed ownerless run no longer protects other workloads.
+func TestAnalysisRevisions_OwnerlessExperiment(t *testing.T) {
+	now := time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC)
+
+	experiment := makeExperiment("canary-check", testExperimentUID,
+		rolloutsv1alpha1.AnalysisPhaseSuccessful, now.Add(-2*time.Hour), "xzk0-seat-config-b870a608", "b870a608")
+	run := makeAnalysisRun("canary-check-smoke", rolloutsv1alpha1.AnalysisPhaseRunning,
+		now.Add(-time.Minute), "xzk0-seat-config-e6120fae", "e6120fae")
+	run.OwnerReferences = []metav1.OwnerReference{makeOwnerRef(KindExperiment, experiment.Name, testExperimentUID)}
+	done := makeAnalysisRun("load-test", rolloutsv1alpha1.AnalysisPhaseSuccessful,
+		now.Add(-time.Minute), "xzk0-seat-config-d5eb6ebf", "d5eb6ebf")
+
+	lister := analysisRevisions{
+		client: NewKubeAnalysisClient(rolloutfake.NewSimpleClientset(experiment, run, done)),
+		now:    func() time.Time { return now },
+	}
+	scoped, err := NewWorkloadInUseResolver(lister).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	seat := scoped.For(ResourceConfigMap, testRolloutUID)
+	assert.True(t, seat.Checksums["e6120fae"])
+	assert.False(t, seat.Checksums["d5eb6ebf"], "completed runs are not Shared")
+}
+
+// TestAnalysis_InUse verifies end to end that the ConfigMap of a running
+// Experiment is protected for its Rollout, by the strategy, even though no
+// Rollout ReplicaSet references it.
+func TestAnalysis_InUse(t *testing.T) {
+	stable := makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae")
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.UID = k8stypes.UID(testRolloutUID)
+	r.Spec.Template = stable.Spec.Template
+
+	experiment := makeExperiment("xzk0-seat-65df947c4c-2-0", testExperimentUID,
+		rolloutsv1alpha1.AnalysisPhaseRunning, time.Now(), "xzk0-seat-config-b870a608", "b870a608")
+	experiment.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef(testRolloutName, testRolloutUID)}
+
+	clients := &Clients{
+		Kube:    fake.NewSimpleClientset(&stable),
+		Rollout: rolloutfake.NewSimpleClientset(r, experiment),
+	}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+
+	assert.True(t, scoped.For(ResourceConfigMap, testRolloutUID).Matches("xzk0-seat-config-b870a608", "b870a608"))
+	assert.True(t, scoped.StrategyFor(ResourceConfigMap, testRolloutUID).Names["xzk0-seat-config-b870a608"])
+}
@@ -0,0 +1,485 @@
+package k8s
+
+// Informer-backed listers for the long-running controller.
+// The controller keeps Rollouts, ReplicaSets, Deployments, Experiments,
+// AnalysisRuns and the metadata of ConfigMaps / Secrets in shared informer
+// caches and serves the RolloutLister, ReplicaSetLister, DeploymentGetter,
+// AnalysisLister, ConfigMapClient and CandidateClient interfaces from them,
+// so the snapshot and planner code paths are the same as for a one-shot run.
+// Only deletions reach the API server.
+//
+// Candidates are cached as PartialObjectMetadata through the metadata
+// informer: ConfigMap data and Secret payloads are never held in memory.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/metadata/metadatainformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Informers holds the shared informers of one namespace (or of every
+// namespace, for metav1.NamespaceAll). Rollouts and candidates are scoped by
+// Clients.LabelSelector; ReplicaSets, Experiments and AnalysisRuns, being
+// revisions, and Deployments, being workloadRef targets, are cached in full.
+type Informers struct {
+	// Rollouts caches *rolloutsv1alpha1.Rollout objects.
+	Rollouts cache.SharedIndexInformer
+	// ReplicaSets caches *appsv1.ReplicaSet objects.
+	ReplicaSets cache.SharedIndexInformer
+	// Deployments caches *appsv1.Deployment objects.
+	Deployments cache.SharedIndexInformer
+	// Experiments and AnalysisRuns cache *rolloutsv1alpha1.Experiment and
+	// *rolloutsv1alpha1.AnalysisRun objects.
+	Experiments  cache.SharedIndexInformer
+	AnalysisRuns cache.SharedIndexInformer
+	// Candidates caches *metav1.PartialObjectMetadata per resource kind.
+	Candidates map[string]cache.SharedIndexInformer
+
+	clients     *Clients
+	namespace   string
+	kube        informers.SharedInformerFactory
+	metadata    metadatainformer.SharedInformerFactory
+	replicaSets appslisters.ReplicaSetLister
+	deployments appslisters.DeploymentLister
+}
+
+// NewInformers creates the informers for namespace and the given resource
+// kinds, resyncing every resync (0 disables resyncs). clients.Metadata must
+// be set. The informers do nothing until Start.
+func NewInformers(clients *Clients, namespace string, resourceKinds []string, resync time.Duration) (*Informers, error) {
+	if clients.Metadata == nil {
+		return nil, fmt.Errorf("informers require a metadata client")
+	}
+	tweak := func(opts *metav1.ListOptions) { opts.LabelSelector = clients.LabelSelector }
+	kube := informers.NewSharedInformerFactoryWithOptions(clients.Kube, resync,
+		informers.WithNamespace(namespace))
+	meta := metadatainformer.NewFilteredSharedInformerFactory(clients.Metadata, resync, namespace, tweak)
+
+	rsInformer := kube.Apps().V1().ReplicaSets()
+	deployInformer := kube.Apps().V1().Deployments()
+	argo := clients.Rollout.ArgoprojV1alpha1()
+	indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+	inf := &Informers{
+		Rollouts: cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				tweak(&opts)
+				return argo.Rollouts(namespace).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				tweak(&opts)
+				return argo.Rollouts(namespace).Watch(context.Background(), opts)
+			},
+		}, &rolloutsv1alpha1.Rollout{}, resync, indexers),
+		ReplicaSets: rsInformer.Informer(),
+		Deployments: deployInformer.Informer(),
+		Experiments: cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return argo.Experiments(namespace).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return argo.Experiments(namespace).Watch(context.Background(), opts)
+			},
+		}, &rolloutsv1alpha1.Experiment{}, resync, indexers),
+		AnalysisRuns: cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return argo.AnalysisRuns(namespace).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return argo.AnalysisRuns(namespace).Watch(context.Background(), opts)
+			},
+		}, &rolloutsv1alpha1.AnalysisRun{}, resync, indexers),
+		Candidates:  make(map[string]cache.SharedIndexInformer, len(resourceKinds)),
+		clients:     clients,
+		namespace:   namespace,
+		kube:        kube,
+		metadata:    meta,
+		replicaSets: rsInformer.Lister(),
+		deployments: deployInformer.Lister(),
+	}
+	for _, kind := range resourceKinds {
+		switch kind {
+		case ResourceConfigMap:
+			inf.Candidates[kind] = meta.ForResource(configMapsResource).Informer()
+		case ResourceSecret:
+			inf.Candidates[kind] = meta.ForResource(secretsResource).Informer()
+		default:
+			return nil, fmt.Errorf("unsupported resource kind %q", kind)
+		}
+	}
+	return inf, nil
+}
+
+// Namespace returns the namespace the informers watch; metav1.NamespaceAll
+// for every namespace.
+func (i *Informers) Namespace() string { return i.namespace }
+
+// Start starts every informer; they stop when stop is closed.
+func (i *Informers) Start(stop <-chan struct{}) {
+	go i.Rollouts.Run(stop)
+	go i.Experiments.Run(stop)
+	go i.AnalysisRuns.Run(stop)
+	i.kube.Start(stop)
+	i.metadata.Start(stop)
+}
+
+// WaitForCacheSync blocks until every informer has synced, and reports
+// whether they did before stop was closed.
+func (i *Informers) WaitForCacheSync(stop <-chan struct{}) bool {
+	synced := []cache.InformerSynced{
+		i.Rollouts.HasSynced, i.ReplicaSets.HasSynced, i.Deployments.HasSynced,
+		i.Experiments.HasSynced, i.AnalysisRuns.HasSynced,
+	}
+	for _, informer := range i.Candidates {
+		synced = append(synced, informer.HasSynced)
+	}
+	return cache.WaitForCacheSync(stop, synced...)
+}
+
+// RolloutSource returns the Rollout WorkloadSource reading Rollouts,
+// ReplicaSets, workloadRef Deployments, Experiments and AnalysisRuns from the
+// caches; see NewRolloutSource.
+func (i *Informers) RolloutSource() WorkloadSource {
+	return NewRolloutSource(
+		NewCachedRolloutClient(i.Rollouts.GetIndexer()),
+		NewCachedReplicaSetClient(i.replicaSets),
+		NewCachedDeploymentClient(i.deployments),
+		NewCachedAnalysisClient(i.Experiments.GetIndexer(), i.AnalysisRuns.GetIndexer()),
+	)
+}
+
+// CandidateClients returns one cache-backed CandidateClient per resource
+// kind, in the given order; deletions go to the API server.
+func (i *Informers) CandidateClients(resourceKinds []string) ([]CandidateClient, error) {
+	clients := make([]CandidateClient, 0, len(resourceKinds))
+	for _, kind := range resourceKinds {
+		informer, ok := i.Candidates[kind]
+		if !ok {
+			return nil, fmt.Errorf("no informer for resource kind %q", kind)
+		}
+		switch kind {
+		case ResourceConfigMap:
+			clients = append(clients, NewCachedConfigMapClient(informer.GetIndexer(), i.clients.Kube))
+		case ResourceSecret:
+			clients = append(clients, NewCachedSecretClient(informer.GetIndexer(), i.clients.Kube))
+		}
+	}
+	return clients, nil
+}
+
+// CachedRolloutClient is the RolloutLister backed by a Rollout informer's
+// indexer.
+type CachedRolloutClient struct {
+	indexer cache.Indexer
+}
+
+// NewCachedRolloutClient creates a CachedRolloutClient reading from indexer,
+// which holds *rolloutsv1alpha1.Rollout objects indexed by namespace.
+func NewCachedRolloutClient(indexer cache.Indexer) *CachedRolloutClient {
+	return &CachedRolloutClient{indexer: indexer}
+}
+
+// ListRolloutNames returns the names of the cached Rollouts in the namespace,
+// sorted.
+func (c *CachedRolloutClient) ListRolloutNames(ctx context.Context, namespace string) ([]string, error) {
+	rollouts, err := c.ListRollouts(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(rollouts))
+	for _, r := range rollouts {
+		names = append(names, r.Name)
+	}
+	return names, nil
+}
+
+// ListRollouts returns deep copies of the cached Rollouts in the namespace,
+// sorted by name.
+func (c *CachedRolloutClient) ListRollouts(_ context.Context, namespace string) ([]rolloutsv1alpha1.Rollout, error) {
+	var rollouts []rolloutsv1alpha1.Rollout
+	err := cache.ListAllByNamespace(c.indexer, namespace, labels.Everything(), func(obj interface{}) {
+		if r, ok := obj.(*rolloutsv1alpha1.Rollout); ok {
+			rollouts = append(rollouts, *r.DeepCopy())
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached rollouts in namespace %q: %w", namespace, err)
+	}
+	sort.Slice(rollouts, func(i, j int) bool { return rollouts[i].Name < rollouts[j].Name })
+	return rollouts, nil
+}
+
+// CachedReplicaSetClient is the ReplicaSetLister backed by a ReplicaSet
+// informer's lister.
+type CachedReplicaSetClient struct {
+	lister appslisters.ReplicaSetLister
+}
+
+// NewCachedReplicaSetClient creates a CachedReplicaSetClient reading from
+// lister.
+func NewCachedReplicaSetClient(lister appslisters.ReplicaSetLister) *CachedReplicaSetClient {
+	return &CachedReplicaSetClient{lister: lister}
+}
+
+// ListRolloutReplicaSets returns the cached RS owned by the named Rollout.
+func (c *CachedReplicaSetClient) ListRolloutReplicaSets(ctx context.Context, namespace, rolloutName string) ([]appsv1.ReplicaSet, error) {
+	items, err := c.ListNamespaceReplicaSets(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var owned []appsv1.ReplicaSet
+	for _, rs := range items {
+		if isOwnedByRollout(rs, rolloutName) {
+			owned = append(owned, rs)
+		}
+	}
+	return owned, nil
+}
+
+// ListNamespaceRolloutReplicaSets returns the cached RS owned by any Rollout.
+func (c *CachedReplicaSetClient) ListNamespaceRolloutReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	return c.ListNamespaceOwnedReplicaSets(ctx, namespace, KindRollout)
+}
+
+// ListNamespaceOwnedReplicaSets returns the cached RS owned by any controller
+// of the given kind.
+func (c *CachedReplicaSetClient) ListNamespaceOwnedReplicaSets(ctx context.Context, namespace, ownerKind string) ([]appsv1.ReplicaSet, error) {
+	items, err := c.ListNamespaceReplicaSets(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ownedByKind(items, ownerKind), nil
+}
+
+// ListNamespaceReplicaSets returns deep copies of every cached RS in the
+// namespace, sorted by name.
+func (c *CachedReplicaSetClient) ListNamespaceReplicaSets(_ context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	cached, err := c.lister.ReplicaSets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached replicasets in namespace %q: %w", namespace, err)
+	}
+	items := make([]appsv1.ReplicaSet, 0, len(cached))
+	for _, rs := range cached {
+		items = append(items, *rs.DeepCopy())
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	return items, nil
+}
+
+// CachedDeploymentClient is the DeploymentGetter backed by a Deployment
+// informer's lister.
+type CachedDeploymentClient struct {
+	lister appslisters.DeploymentLister
+}
+
+// NewCachedDeploymentClient creates a CachedDeploymentClient reading from
+// lister.
+func NewCachedDeploymentClient(lister appslisters.DeploymentLister) *CachedDeploymentClient {
+	return &CachedDeploymentClient{lister: lister}
+}
+
+// GetDeployment returns a deep copy of the named cached Deployment; a missing
+// one is reported as NotFound, like the API server does.
+func (c *CachedDeploymentClient) GetDeployment(_ context.Context, namespace, name string) (*appsv1.Deployment, error) {
+	d, err := c.lister.Deployments(namespace).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return d.DeepCopy(), nil
+}
+
+// CachedAnalysisClient is the AnalysisLister backed by the Experiment and
+// AnalysisRun informers' indexers.
+type CachedAnalysisClient struct {
+	experiments cache.Indexer
+	runs        cache.Indexer
+}
+
+// NewCachedAnalysisClient creates a CachedAnalysisClient reading from the
+// given indexers, which hold *rolloutsv1alpha1.Experiment and
+// *rolloutsv1alpha1.AnalysisRun objects indexed by namespace.
+func NewCachedAnalysisClient(experiments, runs cache.Indexer) *CachedAnalysisClient {
+	return &CachedAnalysisClient{experiments: experiments, runs: runs}
+}
+
+// ListExperiments returns deep copies of the cached Experiments in the
+// namespace, sorted by name.
+func (c *CachedAnalysisClient) ListExperiments(_ context.Context, namespace string) ([]rolloutsv1alpha1.Experiment, error) {
+	var experiments []rolloutsv1alpha1.Experiment
+	err := cache.ListAllByNamespace(c.experiments, namespace, labels.Everything(), func(obj interface{}) {
+		if ex, ok := obj.(*rolloutsv1alpha1.Experiment); ok {
+			experiments = append(experiments, *ex.DeepCopy())
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached experiments in namespace %q: %w", namespace, err)
+	}
+	sort.Slice(experiments, func(i, j int) bool { return experiments[i].Name < experiments[j].Name })
+	return experiments, nil
+}
+
+// ListAnalysisRuns returns deep copies of the cached AnalysisRuns in the
+// namespace, sorted by name.
+func (c *CachedAnalysisClient) ListAnalysisRuns(_ context.Context, namespace string) ([]rolloutsv1alpha1.AnalysisRun, error) {
+	var runs []rolloutsv1alpha1.AnalysisRun
+	err := cache.ListAllByNamespace(c.runs, namespace, labels.Everything(), func(obj interface{}) {
+		if run, ok := obj.(*rolloutsv1alpha1.AnalysisRun); ok {
+			runs = append(runs, *run.DeepCopy())
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached analysisruns in namespace %q: %w", namespace, err)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Name < runs[j].Name })
+	return runs, nil
+}
+
+// cachedMetadata returns copies of the metadata of the cached objects in the
+// namespace whose name starts with namePrefix, sorted by name.
+func cachedMetadata(indexer cache.Indexer, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	var metas []metav1.ObjectMeta
+	err := cache.ListAllByNamespace(indexer, namespace, labels.Everything(), func(obj interface{}) {
+		item, ok := obj.(*metav1.PartialObjectMetadata)
+		if ok && strings.HasPrefix(item.Name, namePrefix) {
+			metas = append(metas, *item.ObjectMeta.DeepCopy())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(metas, func(i, j int) bool { return metas[i].Name < metas[j].Name })
+	return metas, nil
+}
+
+// CachedConfigMapClient is the ConfigMapClient and CandidateClient backed by a
+// ConfigMap metadata informer; deletions go to the API server.
+type CachedConfigMapClient struct {
+	indexer cache.Indexer
+	api     *KubeConfigMapClient
+}
+
+// NewCachedConfigMapClient creates a CachedConfigMapClient reading from
+// indexer, which holds ConfigMap *metav1.PartialObjectMetadata, and deleting
+// through client.
+func NewCachedConfigMapClient(indexer cache.Indexer, client kubernetes.Interface) *CachedConfigMapClient {
+	return &CachedConfigMapClient{indexer: indexer, api: NewKubeConfigMapClient(client)}
+}
+
+// Resource returns ConfigMapResource.
+func (c *CachedConfigMapClient) Resource() VersionedResource {
+	return ConfigMapResource
+}
+
+// ListCandidates returns the metadata of the cached ConfigMaps whose name
+// starts with namePrefix; see ListConfigMapMetadata.
+func (c *CachedConfigMapClient) ListCandidates(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	return c.ListConfigMapMetadata(ctx, namespace, namePrefix)
+}
+
+// ListConfigMapMetadata returns the metadata of the cached ConfigMaps whose
+// name starts with namePrefix.
+func (c *CachedConfigMapClient) ListConfigMapMetadata(_ context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	metas, err := cachedMetadata(c.indexer, namespace, namePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached configmaps in namespace %q: %w", namespace, err)
+	}
+	return metas, nil
+}
+
+// ListConfigMaps returns the cached ConfigMaps whose name starts with
+// namePrefix. Only metadata is cached: Data and BinaryData are always empty.
+//
+// Deprecated: prefer ListAllConfigMaps + FilterConfigMapsByChecksums for
+// multi-service namespaces where a single prefix is insufficient.
+func (c *CachedConfigMapClient) ListConfigMaps(ctx context.Context, namespace, namePrefix string) ([]corev1.ConfigMap, error) {
+	metas, err := c.ListConfigMapMetadata(ctx, namespace, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	cms := make([]corev1.ConfigMap, 0, len(metas))
+	for _, meta := range metas {
+		cms = append(cms, corev1.ConfigMap{ObjectMeta: meta})
+	}
+	return cms, nil
+}
+
+// ListAllConfigMaps returns every cached ConfigMap in the namespace; see
+// ListConfigMaps.
+func (c *CachedConfigMapClient) ListAllConfigMaps(ctx context.Context, namespace string) ([]corev1.ConfigMap, error) {
+	return c.ListConfigMaps(ctx, namespace, "")
+}
+
+// DeleteCandidate deletes the named ConfigMap through the API server; see
+// DeleteConfigMap.
+func (c *CachedConfigMapClient) DeleteCandidate(ctx context.Context, namespace, name string) error {
+	return c.DeleteConfigMap(ctx, namespace, name)
+}
+
+// DeleteConfigMap deletes the named ConfigMap through the API server. A
+// ConfigMap already gone counts as deleted; see ignoreNotFound.
+func (c *CachedConfigMapClient) DeleteConfigMap(ctx context.Context, namespace, name string) error {
+	return ignoreNotFound(c.api.DeleteConfigMap(ctx, namespace, name))
+}
+
+// CachedSecretClient is the CandidateClient for Secrets backed by a Secret
+// metadata informer; deletions go to the API server.
+type CachedSecretClient struct {
+	indexer cache.Indexer
+	api     *KubeSecretClient
+}
+
+// NewCachedSecretClient creates a CachedSecretClient reading from indexer,
+// which holds Secret *metav1.PartialObjectMetadata, and deleting through
+// client.
+func NewCachedSecretClient(indexer cache.Indexer, client kubernetes.Interface) *CachedSecretClient {
+	return &CachedSecretClient{indexer: indexer, api: NewKubeSecretClient(client)}
+}
+
+// Resource returns SecretResource.
+func (c *CachedSecretClient) Resource() VersionedResource {
+	return SecretResource
+}
+
+// ListCandidates returns the metadata of the cached Secrets whose name starts
+// with namePrefix, redacted like KubeSecretClient.ListCandidates.
+func (c *CachedSecretClient) ListCandidates(_ context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	metas, err := cachedMetadata(c.indexer, namespace, namePrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached secrets in namespace %q: %w", namespace, err)
+	}
+	for i := range metas {
+		metas[i] = redactSecretMeta(metas[i])
+	}
+	return metas, nil
+}
+
+// DeleteCandidate deletes the named Secret through the API server. A Secret
+// already gone counts as deleted; see ignoreNotFound.
+func (c *CachedSecretClient) DeleteCandidate(ctx context.Context, namespace, name string) error {
+	return ignoreNotFound(c.api.DeleteCandidate(ctx, namespace, name))
+}
+
+// ignoreNotFound returns nil when err reports that the object does not exist.
+// The cache lags behind the API server: a candidate deleted meanwhile, by
+// another sync or by hand, is not a failure to retry.
+func ignoreNotFound(err error) error {
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package k8s
+
+// Unit tests for cached.go: informers fed by the fake clientsets must yield
+// the same snapshot as the API-backed sources and clients, without listing
+// Rollouts, ReplicaSets or candidates through the API.
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// startInformers starts and syncs informers over clients for testNamespace.
+func startInformers(t *testing.T, clients *Clients, resourceKinds ...string) *Informers {
+	t.Helper()
+	inf, err := NewInformers(clients, testNamespace, resourceKinds, 0)
+	require.NoError(t, err)
+	stop := make(chan struct{})
+	t.Cleanup(func() { close(stop) })
+	inf.Start(stop)
+	require.True(t, inf.WaitForCacheSync(stop))
+	return inf
+}
+
+// TestInformers_SnapshotMatchesAPI verifies that a snapshot read from the
+// informer caches equals one read from the API, workloadRef Deployments,
+// Experiments and AnalysisRuns included.
+func TestInformers_SnapshotMatchesAPI(t *testing.T) {
+	kube, rollout := snapshotFixture(3)
+	experiment := makeExperiment("app-000-1-0", testExperimentUID,
+		rolloutsv1alpha1.AnalysisPhaseRunning, time.Now(), "app-000-config-0a0a0a0a", "0a0a0a0a")
+	experiment.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef("app-000", "uid-000")}
+	run := makeAnalysisRun("app-001-1", rolloutsv1alpha1.AnalysisPhaseRunning,
+		time.Now(), "app-001-config-0b0b0b0b", "0b0b0b0b")
+	run.OwnerReferences = []metav1.OwnerReference{makeRolloutOwnerRef("app-001", "uid-001")}
+	ref := makeWorkloadRefRollout(testWorkloadRefDeployment)
+	require.NoError(t, rollout.Tracker().Add(experiment))
+	require.NoError(t, rollout.Tracker().Add(run))
+	require.NoError(t, rollout.Tracker().Add(ref))
+	require.NoError(t, kube.Tracker().Add(makeRefDeployment("xzk0-seat-config-a1b2c3d4", "a1b2c3d4")))
+	cms, err := kube.CoreV1().ConfigMaps(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	var metas []runtime.Object
+	for _, cm := range cms.Items {
+		metas = append(metas, makeConfigMapMetadata(testNamespace, cm.Name))
+	}
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	clients := &Clients{Kube: kube, Rollout: rollout, Metadata: metadatafake.NewSimpleMetadataClient(scheme, metas...)}
+
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+	client, err := NewCandidateClient(ResourceConfigMap, clients)
+	require.NoError(t, err)
+	want, err := TakeSnapshot(context.Background(), testNamespace, []WorkloadSource{source}, []CandidateClient{client})
+	require.NoError(t, err)
+
+	inf := startInformers(t, clients, ResourceConfigMap)
+	cached, err := inf.CandidateClients([]string{ResourceConfigMap})
+	require.NoError(t, err)
+	apiCalls(kube, rollout)
+	got, err := TakeSnapshot(context.Background(), testNamespace, []WorkloadSource{inf.RolloutSource()}, cached)
+	require.NoError(t, err)
+
+	assert.ElementsMatch(t, want.Workloads, got.Workloads)
+	assert.ElementsMatch(t, want.Revisions, got.Revisions)
+	assert.Equal(t, want.Candidates(ResourceConfigMap, ""), got.Candidates(ResourceConfigMap, ""))
+	names := make([]string, 0, len(got.Revisions))
+	for _, rev := range got.Revisions {
+		names = append(names, rev.Name)
+	}
+	assert.Contains(t, names, "Experiment/app-000-1-0/canary")
+	assert.Contains(t, names, "Rollout/"+testRolloutName, "the workloadRef template is resolved from the cache")
+	for _, action := range append(kube.Actions(), rollout.Actions()...) {
+		if action.GetVerb() == "watch" {
+			continue
+		}
+		assert.NotContains(t, []string{"rollouts", "replicasets", "deployments", "experiments", "analysisruns", "configmaps"}, action.GetResource().Resource,
+			"%s %s must be served from the cache", action.GetVerb(), action.GetResource().Resource)
+	}
+}
+
+// TestNewInformers_RequiresMetadataClient verifies the metadata client is
+// mandatory: candidates are only ever cached as metadata.
+func TestNewInformers_RequiresMetadataClient(t *testing.T) {
+	_, err := NewInformers(&Clients{Kube: fake.NewSimpleClientset()}, testNamespace, []string{ResourceConfigMap}, 0)
+	assert.Error(t, err)
+}
+
+// TestCachedSecretClient verifies cached Secret metadata is redacted like
+// KubeSecretClient's and deletions go to the API, where a missing Secret
+// counts as deleted.
+func TestCachedSecretClient(t *testing.T) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, name := range []string{"app-secret-aaaaaaaa", "app-secret-bbbbbbbb", "other-secret-cccccccc"} {
+		require.NoError(t, indexer.Add(&metav1.PartialObjectMetadata{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   testNamespace,
+			Name:        name,
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: `{"data":{"password":"hunter2"}}`},
+		}}))
+	}
+	kube := fake.NewSimpleClientset(makeSecret(testNamespace, "app-secret-aaaaaaaa", nil))
+	client := NewCachedSecretClient(indexer, kube)
+
+	got, err := client.ListCandidates(context.Background(), testNamespace, "app-secret-")
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "app-secret-aaaaaaaa", got[0].Name)
+	assert.Equal(t, "app-secret-bbbbbbbb", got[1].Name)
+	for _, meta := range got {
+		assert.NotContains(t, meta.Annotations, corev1.LastAppliedConfigAnnotation)
+	}
+
+	require.NoError(t, client.DeleteCandidate(context.Background(), testNamespace, "app-secret-aaaaaaaa"))
+	require.Len(t, kube.Actions(), 1)
+	assert.Equal(t, "delete", kube.Actions()[0].GetVerb())
+
+	// Still cached but already gone from the API server: nothing to retry.
+	assert.NoError(t, client.DeleteCandidate(context.Background(), testNamespace, "app-secret-bbbbbbbb"))
+}
@@ -8,9 +8,12 @@ package k8s
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	rolloutclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
@@ -20,6 +23,28 @@ import (
 type Clients struct {
 	Kube    kubernetes.Interface
 	Rollout rolloutclientset.Interface
+	// Metadata lists PartialObjectMetadata, so candidates are listed without
+	// their data. Optional: when nil, full objects are listed instead.
+	Metadata metadata.Interface
+	// LabelSelector scopes every list of workloads and candidates made
+	// through these clients; empty selects everything. Revisions are always
+	// listed in full.
+	LabelSelector string
+	// PageSize is the number of objects fetched per List request; 0 lists
+	// everything in a single request. NewClients sets DefaultPageSize.
+	PageSize int64
+}
+
+// scope returns the listScope the workload and candidate listers built from
+// c share.
+func (c *Clients) scope() listScope {
+	return listScope{selector: c.LabelSelector, pageSize: c.PageSize}
+}
+
+// revisionScope returns the listScope of the revision listers built from c:
+// paged like scope, but never label-filtered.
+func (c *Clients) revisionScope() listScope {
+	return listScope{pageSize: c.PageSize}
 }
 
 // NewClients creates both a Kubernetes clientset and an Argo Rollouts clientset
@@ -40,9 +65,16 @@ func NewClients() (*Clients, error) {
 		return nil, fmt.Errorf("failed to create argo rollouts clientset: %w", err)
 	}
 
+	meta, err := metadata.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metadata client: %w", err)
+	}
+
 	return &Clients{
-		Kube:    kube,
-		Rollout: rollout,
+		Kube:     kube,
+		Rollout:  rollout,
+		Metadata: meta,
+		PageSize: DefaultPageSize,
 	}, nil
 }
 
@@ -71,3 +103,23 @@ func GetConfig() (*rest.Config, error) {
 
 	return cfg, nil
 }
+
+// serviceAccountNamespaceFile holds the namespace of the pod's ServiceAccount.
+const serviceAccountNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// CurrentNamespace returns the namespace cm-gc runs in, detected like
+// GetConfig: the pod's ServiceAccount namespace in-cluster, the current
+// kubeconfig context's namespace otherwise, and "default" as a last resort.
+func CurrentNamespace() string {
+	if ns, err := os.ReadFile(serviceAccountNamespaceFile); err == nil {
+		if ns := strings.TrimSpace(string(ns)); ns != "" {
+			return ns
+		}
+	}
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	ns, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).Namespace()
+	if err != nil || ns == "" {
+		return metav1.NamespaceDefault
+	}
+	return ns
+}
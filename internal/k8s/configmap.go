@@ -3,6 +3,10 @@ package k8s
 // ConfigMap list and delete operations.
 // All functions accept a kubernetes.Interface so they can be unit-tested with
 // fake.NewSimpleClientset() without a live cluster.
+//
+// Planning only needs metadata. When a metadata client is configured,
+// ListConfigMapMetadata requests PartialObjectMetadata so ConfigMap data —
+// often megabytes per object — is never transferred or held in memory.
 
 import (
 	"context"
@@ -12,8 +16,12 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
 )
 
+// configMapsResource is the ConfigMap resource for the metadata client.
+var configMapsResource = corev1.SchemeGroupVersion.WithResource("configmaps")
+
 // ConfigMapLister lists ConfigMaps in a given namespace.
 type ConfigMapLister interface {
 	// ListConfigMaps returns ConfigMaps whose name starts with namePrefix.
@@ -25,6 +33,11 @@ type ConfigMapLister interface {
 	// name-based filtering. Callers use FilterConfigMapsByChecksums to select
 	// only those referenced by Argo Rollout ReplicaSets.
 	ListAllConfigMaps(ctx context.Context, namespace string) ([]corev1.ConfigMap, error)
+
+	// ListConfigMapMetadata returns the metadata (name, timestamps, labels,
+	// annotations, owner references) of ConfigMaps whose name starts with
+	// namePrefix, without their data.
+	ListConfigMapMetadata(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error)
 }
 
 // ConfigMapDeleter deletes a single ConfigMap by name.
@@ -42,6 +55,9 @@ type ConfigMapClient interface {
 // (or fake) kubernetes.Interface.
 type KubeConfigMapClient struct {
 	client kubernetes.Interface
+	// metadata, when set, serves ListConfigMapMetadata.
+	metadata metadata.Interface
+	scope    listScope
 }
 
 // NewKubeConfigMapClient creates a KubeConfigMapClient wrapping the provided
@@ -50,6 +66,27 @@ func NewKubeConfigMapClient(client kubernetes.Interface) *KubeConfigMapClient {
 	return &KubeConfigMapClient{client: client}
 }
 
+// WithLabelSelector restricts every list made by k to ConfigMaps matching
+// selector and returns k.
+func (k *KubeConfigMapClient) WithLabelSelector(selector string) *KubeConfigMapClient {
+	k.scope.selector = selector
+	return k
+}
+
+// WithMetadataClient makes ListConfigMapMetadata list PartialObjectMetadata
+// through m instead of full ConfigMaps, and returns k.
+func (k *KubeConfigMapClient) WithMetadataClient(m metadata.Interface) *KubeConfigMapClient {
+	k.metadata = m
+	return k
+}
+
+// WithPageSize makes every list made by k fetch at most size ConfigMaps per
+// request (0 disables pagination) and returns k.
+func (k *KubeConfigMapClient) WithPageSize(size int64) *KubeConfigMapClient {
+	k.scope.pageSize = size
+	return k
+}
+
 // ListConfigMaps returns all ConfigMaps in the given namespace whose name starts
 // with namePrefix. It lists all ConfigMaps and filters client-side to avoid
 // relying on field selectors that may behave differently across cluster flavors.
@@ -57,13 +94,13 @@ func NewKubeConfigMapClient(client kubernetes.Interface) *KubeConfigMapClient {
 // Deprecated: prefer ListAllConfigMaps + FilterConfigMapsByChecksums for
 // multi-service namespaces where a single prefix is insufficient.
 func (k *KubeConfigMapClient) ListConfigMaps(ctx context.Context, namespace, namePrefix string) ([]corev1.ConfigMap, error) {
-	list, err := k.client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	items, err := k.listConfigMaps(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list configmaps in namespace %q: %w", namespace, err)
 	}
 
 	var matched []corev1.ConfigMap
-	for _, cm := range list.Items {
+	for _, cm := range items {
 		if strings.HasPrefix(cm.Name, namePrefix) {
 			matched = append(matched, cm)
 		}
@@ -75,11 +112,17 @@ func (k *KubeConfigMapClient) ListConfigMaps(ctx context.Context, namespace, nam
 // name-based filtering. Use FilterConfigMapsByChecksums to narrow the result
 // to only those referenced by Argo Rollout ReplicaSets.
 func (k *KubeConfigMapClient) ListAllConfigMaps(ctx context.Context, namespace string) ([]corev1.ConfigMap, error) {
-	list, err := k.client.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+	items, err := k.listConfigMaps(ctx, namespace)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list all configmaps in namespace %q: %w", namespace, err)
 	}
-	return list.Items, nil
+	return items, nil
+}
+
+// listConfigMaps pages through every ConfigMap in the namespace.
+func (k *KubeConfigMapClient) listConfigMaps(ctx context.Context, namespace string) ([]corev1.ConfigMap, error) {
+	return listAll(ctx, "configmaps", k.scope, k.client.CoreV1().ConfigMaps(namespace).List,
+		func(l *corev1.ConfigMapList) []corev1.ConfigMap { return l.Items })
 }
 
 // FilterConfigMapsByChecksums returns the subset of cms whose name contains at
@@ -107,6 +150,76 @@ func FilterConfigMapsByChecksums(cms []corev1.ConfigMap, checksums map[string]bo
 	return matched
 }
 
+// Resource returns ConfigMapResource; it makes KubeConfigMapClient usable as a
+// kind-agnostic CandidateClient.
+func (k *KubeConfigMapClient) Resource() VersionedResource {
+	return ConfigMapResource
+}
+
+// ListCandidates returns the metadata of every ConfigMap whose name starts with
+// namePrefix; see ListConfigMapMetadata.
+func (k *KubeConfigMapClient) ListCandidates(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	return k.ListConfigMapMetadata(ctx, namespace, namePrefix)
+}
+
+// ListConfigMapMetadata returns the metadata of every ConfigMap whose name
+// starts with namePrefix. With a metadata client only PartialObjectMetadata is
+// transferred; without one, full ConfigMaps are listed and their data dropped
+// before returning.
+func (k *KubeConfigMapClient) ListConfigMapMetadata(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	if k.metadata != nil {
+		return k.listMetadata(ctx, namespace, namePrefix)
+	}
+	cms, err := k.ListConfigMaps(ctx, namespace, namePrefix)
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]metav1.ObjectMeta, 0, len(cms))
+	for _, cm := range cms {
+		metas = append(metas, cm.ObjectMeta)
+	}
+	return metas, nil
+}
+
+// CandidateSizes returns the size of the data and binaryData of every
+// ConfigMap in the namespace, by name.
+func (k *KubeConfigMapClient) CandidateSizes(ctx context.Context, namespace string) (map[string]int64, error) {
+	sizes, err := listAll(ctx, "configmaps", k.scope, k.client.CoreV1().ConfigMaps(namespace).List,
+		func(l *corev1.ConfigMapList) []objectSize {
+			sizes := make([]objectSize, 0, len(l.Items))
+			for _, cm := range l.Items {
+				sizes = append(sizes, objectSize{name: cm.Name, size: payloadSize(cm.Data) + payloadSize(cm.BinaryData)})
+			}
+			return sizes
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to size configmaps in namespace %q: %w", namespace, err)
+	}
+	return sizesByName(sizes), nil
+}
+
+// listMetadata lists ConfigMap PartialObjectMetadata through the metadata
+// client and keeps the names starting with namePrefix.
+func (k *KubeConfigMapClient) listMetadata(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	items, err := listAll(ctx, configMapsResource.Resource, k.scope, k.metadata.Resource(configMapsResource).Namespace(namespace).List,
+		func(l *metav1.PartialObjectMetadataList) []metav1.PartialObjectMetadata { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list configmap metadata in namespace %q: %w", namespace, err)
+	}
+	var metas []metav1.ObjectMeta
+	for _, item := range items {
+		if strings.HasPrefix(item.Name, namePrefix) {
+			metas = append(metas, item.ObjectMeta)
+		}
+	}
+	return metas, nil
+}
+
+// DeleteCandidate deletes the named ConfigMap; see DeleteConfigMap.
+func (k *KubeConfigMapClient) DeleteCandidate(ctx context.Context, namespace, name string) error {
+	return k.DeleteConfigMap(ctx, namespace, name)
+}
+
 // DeleteConfigMap deletes the named ConfigMap from the given namespace.
 // The caller is responsible for enforcing dry-run logic — this function
 // always performs a real deletion when invoked.
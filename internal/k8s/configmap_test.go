@@ -13,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
 )
 
 const (
@@ -257,6 +258,73 @@ func TestListAllConfigMaps(t *testing.T) {
 	}
 }
 
+// ─── ListConfigMapMetadata ────────────────────────────────────────────────────
+
+// makeConfigMapMetadata builds the PartialObjectMetadata the metadata client
+// returns for a ConfigMap.
+func makeConfigMapMetadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+}
+
+// TestListConfigMapMetadata verifies that with a metadata client, candidates
+// are listed as PartialObjectMetadata and the typed ConfigMap API — which
+// would transfer the data — is never called.
+func TestListConfigMapMetadata(t *testing.T) {
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	meta := metadatafake.NewSimpleMetadataClient(scheme,
+		makeConfigMapMetadata(testNamespace, "xzk0-seat-config-e6120fae"),
+		makeConfigMapMetadata(testNamespace, "test-app-config-aaa1111"),
+		makeConfigMapMetadata("other-ns", "xzk0-seat-config-b870a608"),
+	)
+	kube := fake.NewSimpleClientset()
+
+	client := NewKubeConfigMapClient(kube).WithMetadataClient(meta)
+	got, err := client.ListCandidates(context.Background(), testNamespace, testPrefix)
+	require.NoError(t, err)
+
+	require.Len(t, got, 1)
+	assert.Equal(t, "xzk0-seat-config-e6120fae", got[0].Name)
+	assert.Empty(t, kube.Actions(), "the typed ConfigMap API must not be used")
+	require.Len(t, meta.Actions(), 1)
+	assert.Equal(t, "list", meta.Actions()[0].GetVerb())
+}
+
+// TestListConfigMapMetadata_WithoutMetadataClient verifies the fallback: full
+// ConfigMaps are listed and only their metadata is returned.
+func TestListConfigMapMetadata_WithoutMetadataClient(t *testing.T) {
+	cm := makeConfigMap(testNamespace, "xzk0-seat-config-e6120fae", nil)
+	cm.Data = map[string]string{"app.yaml": "large payload"}
+	client := NewKubeConfigMapClient(fake.NewSimpleClientset(&cm))
+
+	got, err := client.ListConfigMapMetadata(context.Background(), testNamespace, testPrefix)
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "xzk0-seat-config-e6120fae", got[0].Name)
+}
+
+// TestKubeConfigMapClient_CandidateSizes verifies keys and values of both data
+// and binaryData are counted, for every ConfigMap of one List.
+func TestKubeConfigMapClient_CandidateSizes(t *testing.T) {
+	cm := makeConfigMap(testNamespace, "xzk0-seat-config-e6120fae", nil)
+	cm.Data = map[string]string{"app.yaml": "large payload"}
+	cm.BinaryData = map[string][]byte{"logo.png": {0x89, 0x50}}
+	empty := makeConfigMap(testNamespace, "xzk0-seat-config-a1b2c3d4", nil)
+	kube := fake.NewSimpleClientset(&cm, &empty)
+	client := NewKubeConfigMapClient(kube)
+
+	sizes, err := client.CandidateSizes(context.Background(), testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{
+		"xzk0-seat-config-e6120fae": int64(len("app.yaml") + len("large payload") + len("logo.png") + 2),
+		"xzk0-seat-config-a1b2c3d4": 0,
+	}, sizes)
+	assert.Len(t, kube.Actions(), 1, "one List sizes the namespace")
+}
+
 // ─── FilterConfigMapsByChecksums ─────────────────────────────────────────────
 
 func TestFilterConfigMapsByChecksums(t *testing.T) {
@@ -0,0 +1,184 @@
+package k8s
+
+// Kubernetes Events on the workloads owning collected objects, so that GC
+// decisions show in `kubectl describe rollout`. Callers report each family
+// outcome once (all the objects deleted, failed or that a dry run would
+// delete). Events go through the client-go event broadcaster: repeats are
+// aggregated by its correlator, so an identical Event bumps the count of the
+// existing one and a workload flooded with similar Events gets a single
+// combined Event instead, and failed sends are retried with backoff.
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// EventComponent is the source component of every Event cm-gc records.
+const EventComponent = "cm-gc"
+
+// Event reasons.
+const (
+	EventReasonDeleted      = "VersionedObjectsDeleted"
+	EventReasonDeleteFailed = "VersionedObjectsDeleteFailed"
+	EventReasonWouldDelete  = "VersionedObjectsWouldDelete"
+)
+
+// maxEventNames bounds the object names listed in one Event message.
+const maxEventNames = 10
+
+// workloadAPIVersions maps each workload kind to the apiVersion Events refer
+// to it with.
+var workloadAPIVersions = map[string]string{
+	KindRollout:     "argoproj.io/v1alpha1",
+	KindDeployment:  "apps/v1",
+	KindStatefulSet: "apps/v1",
+	KindDaemonSet:   "apps/v1",
+}
+
+// EventRecorder records Events on workloads through a client-go event
+// broadcaster recording to the API server, with its retries and backoff.
+// Events are sent asynchronously; Flush waits for them.
+type EventRecorder struct {
+	broadcaster record.EventBroadcaster
+	recorder    record.EventRecorder
+	sink        *eventSink
+	// flushes numbers the flush markers.
+	flushes atomic.Int64
+}
+
+// NewEventRecorder returns an EventRecorder creating Events through kube.
+// failed is called with each failed attempt to send an Event. The
+// broadcaster retries transient errors; an Event the API server rejects, e.g.
+// for a missing RBAC permission, is dropped.
+func NewEventRecorder(kube kubernetes.Interface, failed func(*corev1.Event, error)) *EventRecorder {
+	return newEventRecorder(kube, failed)
+}
+
+// newEventRecorder is NewEventRecorder with extra broadcaster options.
+func newEventRecorder(kube kubernetes.Interface, failed func(*corev1.Event, error), opts ...record.BroadcasterOption) *EventRecorder {
+	opts = append([]record.BroadcasterOption{record.WithCorrelatorOptions(record.CorrelatorOptions{})}, opts...)
+	r := &EventRecorder{
+		broadcaster: record.NewBroadcaster(opts...),
+		sink: &eventSink{
+			EventSink: &typedcorev1.EventSinkImpl{Interface: kube.CoreV1().Events("")},
+			failed:    failed,
+			markers:   make(map[types.UID]chan struct{}),
+		},
+	}
+	r.broadcaster.StartRecordingToSink(r.sink)
+	r.recorder = r.broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: EventComponent})
+	return r
+}
+
+// Record records an Event on workload w of namespace ns.
+func (r *EventRecorder) Record(ns string, w Workload, eventType, reason, message string) {
+	r.recorder.Event(WorkloadReference(ns, w), eventType, reason, message)
+}
+
+// Flush waits up to timeout for the recorded Events to be sent, then shuts
+// the broadcaster down. It reports whether every Event was sent or given up
+// on in time; the broadcaster drops Events when its queue overflows, which
+// Flush can only tell by timing out.
+//
+// The broadcaster sends Events one at a time, in order, so Flush records a
+// marker Event and waits for it to reach the sink, which swallows it.
+func (r *EventRecorder) Flush(timeout time.Duration) bool {
+	defer r.broadcaster.Shutdown()
+	uid := types.UID(fmt.Sprintf("flush-%d", r.flushes.Add(1)))
+	done := r.sink.marker(uid)
+	r.recorder.Event(&corev1.ObjectReference{Kind: eventFlushKind, Name: string(uid), UID: uid},
+		corev1.EventTypeNormal, eventFlushKind, "flush")
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// eventFlushKind is the involved object kind of Flush markers.
+const eventFlushKind = "EventRecorderFlush"
+
+// eventSink reports failed attempts to send an Event and swallows Flush
+// markers, closing the channel their Flush waits on.
+type eventSink struct {
+	record.EventSink
+	failed func(*corev1.Event, error)
+
+	mu      sync.Mutex
+	markers map[types.UID]chan struct{}
+}
+
+// marker returns the channel closed once the marker with uid is sent.
+func (s *eventSink) marker(uid types.UID) <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	done := make(chan struct{})
+	s.markers[uid] = done
+	return done
+}
+
+func (s *eventSink) Create(event *corev1.Event) (*corev1.Event, error) {
+	if event.InvolvedObject.Kind == eventFlushKind {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if done, ok := s.markers[event.InvolvedObject.UID]; ok {
+			close(done)
+			delete(s.markers, event.InvolvedObject.UID)
+		}
+		return event, nil
+	}
+	sent, err := s.EventSink.Create(event)
+	s.check(event, err)
+	return sent, err
+}
+
+func (s *eventSink) Update(event *corev1.Event) (*corev1.Event, error) {
+	sent, err := s.EventSink.Update(event)
+	s.check(event, err)
+	return sent, err
+}
+
+func (s *eventSink) Patch(event *corev1.Event, data []byte) (*corev1.Event, error) {
+	sent, err := s.EventSink.Patch(event, data)
+	s.check(event, err)
+	return sent, err
+}
+
+// check reports err, if any, about event.
+func (s *eventSink) check(event *corev1.Event, err error) {
+	if err != nil {
+		s.failed(event, err)
+	}
+}
+
+// WorkloadReference returns the reference Events about workload w of
+// namespace ns are recorded on.
+func WorkloadReference(ns string, w Workload) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: workloadAPIVersions[w.Kind],
+		Kind:       w.Kind,
+		Namespace:  ns,
+		Name:       w.Name,
+		UID:        w.UID,
+	}
+}
+
+// EventNames joins names for an Event message, listing at most maxEventNames
+// of them so the message stays within the API server's limits.
+func EventNames(names []string) string {
+	if len(names) <= maxEventNames {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:maxEventNames], ", "), len(names)-maxEventNames)
+}
@@ -0,0 +1,104 @@
+package k8s
+
+// Unit tests for events.go: Events must land on the owning workload, repeats
+// must be aggregated into one Event, transient errors retried, and Flush must
+// wait for them.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestEventRecorder_AggregatesRepeats(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	recorder := NewEventRecorder(kube, func(event *corev1.Event, err error) {
+		t.Errorf("event %s not recorded: %v", event.Reason, err)
+	})
+	rollout := Workload{Kind: KindRollout, Name: "seat", UID: "uid-seat"}
+
+	recorder.Record(testNamespace, rollout, corev1.EventTypeNormal, EventReasonWouldDelete, "would delete seat-config-aaaaaaaa")
+	recorder.Record(testNamespace, rollout, corev1.EventTypeNormal, EventReasonWouldDelete, "would delete seat-config-aaaaaaaa")
+	recorder.Record(testNamespace, rollout, corev1.EventTypeWarning, EventReasonDeleteFailed, "failed to delete seat-config-bbbbbbbb")
+	require.True(t, recorder.Flush(5*time.Second))
+
+	events, err := kube.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 2, "identical events are aggregated")
+	counts := make(map[string]int32)
+	for _, event := range events.Items {
+		assert.Equal(t, corev1.ObjectReference{
+			APIVersion: "argoproj.io/v1alpha1",
+			Kind:       KindRollout,
+			Namespace:  testNamespace,
+			Name:       "seat",
+			UID:        "uid-seat",
+		}, event.InvolvedObject)
+		assert.Equal(t, EventComponent, event.Source.Component)
+		counts[event.Reason] = event.Count
+	}
+	assert.Equal(t, map[string]int32{EventReasonWouldDelete: 2, EventReasonDeleteFailed: 1}, counts)
+}
+
+func TestEventRecorder_ReportsRejectedEvents(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	kube.PrependReactor("create", "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewForbidden(corev1.Resource("events"), "", errors.New("denied"))
+	})
+	var failed []string
+	recorder := NewEventRecorder(kube, func(event *corev1.Event, err error) {
+		failed = append(failed, event.Reason)
+	})
+
+	recorder.Record(testNamespace, Workload{Kind: KindDeployment, Name: "web"}, corev1.EventTypeNormal, EventReasonDeleted, "deleted web-config-aaaaaaaa")
+	require.True(t, recorder.Flush(5*time.Second))
+	assert.Equal(t, []string{EventReasonDeleted}, failed, "a rejected Event is not retried")
+}
+
+func TestEventRecorder_RetriesTransientErrors(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	attempts := 0
+	kube.PrependReactor("create", "events", func(k8stesting.Action) (bool, runtime.Object, error) {
+		attempts++
+		if attempts == 1 {
+			return true, nil, errors.New("connection refused")
+		}
+		return false, nil, nil
+	})
+	var failed []string
+	recorder := newEventRecorder(kube, func(event *corev1.Event, err error) {
+		failed = append(failed, event.Reason)
+	}, record.WithSleepDuration(time.Millisecond))
+
+	recorder.Record(testNamespace, Workload{Kind: KindRollout, Name: "seat"}, corev1.EventTypeNormal, EventReasonDeleted, "deleted seat-config-aaaaaaaa")
+	require.True(t, recorder.Flush(5*time.Second))
+	assert.Equal(t, []string{EventReasonDeleted}, failed)
+	events, err := kube.CoreV1().Events(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, events.Items, 1, "the Event is sent on retry")
+	assert.Equal(t, EventReasonDeleted, events.Items[0].Reason)
+}
+
+func TestEventNames(t *testing.T) {
+	assert.Equal(t, "a, b", EventNames([]string{"a", "b"}))
+
+	names := make([]string, maxEventNames+3)
+	for i := range names {
+		names[i] = fmt.Sprintf("cm-%d", i)
+	}
+	got := EventNames(names)
+	assert.Contains(t, got, "cm-9 and 3 more")
+	assert.NotContains(t, got, "cm-10")
+}
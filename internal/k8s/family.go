@@ -0,0 +1,81 @@
+package k8s
+
+// Candidate families: which versioned objects belong to which workloads.
+// A workload owns the family its name resolves to through the naming template
+// ("foo" → "foo-config-{hash}"), plus the family of every object its desired
+// pod template references whose name matches the naming template
+// ("bar-config-e6120fae" → "bar-config-{hash}"), which covers ConfigMaps not
+// named after the workload. With stemReferences, any referenced name ending
+// in a hash is a family too, with the hash suffix stripped
+// ("foo-env-e6120fae" → "foo-env-{hash}"); this is opt-in because
+// unversioned names such as "db-cafebabe" or "app-20240101" look alike.
+//
+// A family referenced by several workloads is processed once, on behalf of
+// all of them.
+
+import (
+	"github.com/yujen77300/configmap-collector/internal/naming"
+)
+
+// FamilyGroup is one candidate family together with every workload that owns it.
+type FamilyGroup struct {
+	Family    naming.Family
+	Workloads []Workload
+}
+
+// WorkloadFamilies returns the families of one resource kind owned by w: the
+// template-derived family of its name first, then one per distinct family of
+// the objects its desired pod template references.
+func WorkloadFamilies(w Workload, res VersionedResource, tpl *naming.Template, stemReferences bool) []naming.Family {
+	families := []naming.Family{tpl.Family(w.Name)}
+	seen := map[string]bool{families[0].Key(): true}
+	for _, name := range res.PodSpecRefs(w.Template.Spec) {
+		family, ok := referencedFamily(name, tpl, stemReferences)
+		if !ok {
+			// Not versioned (e.g. "kube-root-ca.crt") — nothing to collect.
+			continue
+		}
+		if seen[family.Key()] {
+			continue
+		}
+		seen[family.Key()] = true
+		families = append(families, family)
+	}
+	return families
+}
+
+// referencedFamily returns the family of an object name referenced by a pod
+// template: the template family of the workload the name parses to or, with
+// stemReferences, the family of its stem when the name merely ends in a hash.
+func referencedFamily(name string, tpl *naming.Template, stemReferences bool) (naming.Family, bool) {
+	if owner, _, ok := tpl.Parse(name); ok {
+		return tpl.Family(owner), true
+	}
+	if !stemReferences {
+		return nil, false
+	}
+	stem, ok := tpl.Stem(name)
+	if !ok {
+		return nil, false
+	}
+	return tpl.StemFamily(stem), true
+}
+
+// GroupFamilies returns the families of one resource kind owned by any of the
+// workloads, each listed once with all of its owners, in discovery order.
+func GroupFamilies(workloads []Workload, res VersionedResource, tpl *naming.Template, stemReferences bool) []FamilyGroup {
+	var groups []FamilyGroup
+	index := make(map[string]int)
+	for _, w := range workloads {
+		for _, family := range WorkloadFamilies(w, res, tpl, stemReferences) {
+			i, ok := index[family.Key()]
+			if !ok {
+				i = len(groups)
+				index[family.Key()] = i
+				groups = append(groups, FamilyGroup{Family: family})
+			}
+			groups[i].Workloads = append(groups[i].Workloads, w)
+		}
+	}
+	return groups
+}
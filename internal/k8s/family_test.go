@@ -0,0 +1,95 @@
+package k8s
+
+// Unit tests for family.go: families derived from workload names and from the
+// versioned objects referenced by each workload's desired pod template.
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+
+	"github.com/yujen77300/configmap-collector/internal/naming"
+)
+
+// makeWorkload builds a Rollout workload whose desired template mounts cmNames.
+func makeWorkload(name, uid string, cmNames ...string) Workload {
+	w := Workload{Kind: KindRollout, Name: name, UID: k8stypes.UID(uid)}
+	for i, cm := range cmNames {
+		w.Template.Spec.Volumes = append(w.Template.Spec.Volumes, configMapVolume(fmt.Sprintf("config-%d", i), cm))
+	}
+	return w
+}
+
+func familyKeys(families []naming.Family) []string {
+	keys := make([]string, 0, len(families))
+	for _, f := range families {
+		keys = append(keys, f.Key())
+	}
+	return keys
+}
+
+func TestWorkloadFamilies(t *testing.T) {
+	tpl, err := naming.New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+
+	w := makeWorkload("foo", "foo-uid",
+		"foo-config-e6120fae",   // same family as the name-derived one
+		"bar-config-0a1b2c3d",   // matches the template, not named after the workload
+		"foo-env-b870a608",      // second versioned ConfigMap
+		"shared-nginx-f3bca2cb", // not named after the workload
+		"kube-root-ca.crt",      // unversioned
+	)
+	w.Template.Spec.Containers = []corev1.Container{{
+		Name:    "app",
+		EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "foo-env-d5eb6ebf"}}}},
+	}}
+
+	got := WorkloadFamilies(w, ConfigMapResource, tpl, false)
+	assert.Equal(t, []string{"foo-config-{hash}", "bar-config-{hash}"}, familyKeys(got),
+		"only names matching the template are families by default")
+
+	got = WorkloadFamilies(w, ConfigMapResource, tpl, true)
+	assert.Equal(t, []string{"foo-config-{hash}", "bar-config-{hash}", "foo-env-{hash}", "shared-nginx-{hash}"}, familyKeys(got))
+}
+
+// TestWorkloadFamilies_HashLikeNames verifies that unversioned names ending
+// in a hash-like run are not families unless stemReferences is set.
+func TestWorkloadFamilies_HashLikeNames(t *testing.T) {
+	tpl, err := naming.New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+
+	w := makeWorkload("foo", "foo-uid", "db-cafebabe", "app-20240101")
+
+	assert.Equal(t, []string{"foo-config-{hash}"}, familyKeys(WorkloadFamilies(w, ConfigMapResource, tpl, false)))
+	assert.Equal(t, []string{"foo-config-{hash}", "app-{hash}", "db-{hash}"}, familyKeys(WorkloadFamilies(w, ConfigMapResource, tpl, true)),
+		"stemReferences opts into stemming any hash-like name")
+}
+
+func TestGroupFamilies_SharedFamily(t *testing.T) {
+	tpl, err := naming.New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+
+	workloads := []Workload{
+		makeWorkload("foo", "foo-uid", "shared-nginx-f3bca2cb"),
+		makeWorkload("bar", "bar-uid", "shared-nginx-d5eb6ebf"),
+	}
+
+	groups := GroupFamilies(workloads, ConfigMapResource, tpl, true)
+	require.Len(t, groups, 3)
+
+	byKey := make(map[string][]string, len(groups))
+	for _, g := range groups {
+		for _, w := range g.Workloads {
+			byKey[g.Family.Key()] = append(byKey[g.Family.Key()], w.Name)
+		}
+	}
+	assert.Equal(t, map[string][]string{
+		"foo-config-{hash}":   {"foo"},
+		"shared-nginx-{hash}": {"foo", "bar"},
+		"bar-config-{hash}":   {"bar"},
+	}, byKey)
+}
@@ -1,60 +1,300 @@
 package k8s
 
-// InUseResolver builds the set of checksums that are actively referenced
-// by ReplicaSets owned by any Argo Rollout in the given namespace.
+// InUseResolver builds the set of ConfigMaps (and checksums) that are actively
+// referenced by the pod-template revisions of the enabled workload kinds
+// (ReplicaSets for Rollouts/Deployments, ControllerRevisions for
+// StatefulSets/DaemonSets) in the given namespace. Workload sources also report
+// each workload's desired spec.template as a revision, so a ConfigMap is
+// protected as soon as the spec references it — before the new ReplicaSet exists.
 //
-// Algorithm:
-//  1. List all ReplicaSets in the namespace whose ownerReferences point to any
-//     Rollout (active RS + history revisions up to revisionHistoryLimit).
-//  2. For each RS, extract the checksum/config annotation from the pod template.
+// ResolveConfigMaps walks each revision's pod template (volumes, projected
+// volumes, envFrom, env[].valueFrom.configMapKeyRef on regular, init and
+// ephemeral containers) and returns exact ConfigMap names. This is the
+// authoritative in-use signal: it does not depend on the chart writing a
+// checksum annotation, and it cannot collide on short hashes.
+//
+// Resolve (checksum algorithm):
+//  1. List all revisions in the namespace owned by an enabled workload kind
+//     (active revision + history up to revisionHistoryLimit).
+//  2. For each revision, extract the checksum/config annotation from the pod template.
 //  3. Collect all extracted checksums into a deduplicated set.
 //  4. Return the checksum set (e.g. {"e6120fae": true, "b870a608": true}).
 //
-// Callers use FilterConfigMapsByChecksums to find ConfigMaps whose names contain
-// one of these checksums — no fixed name prefix required (Direction B).
+// Checksums are a secondary signal: callers compare them against the hash
+// parsed from a candidate's name by its naming template — never by substring.
 //
-// If a ReplicaSet has no checksum/config annotation it is silently skipped —
+// If a revision has no checksum/config annotation it is silently skipped —
 // this can happen for apps that do not use the Helm checksum pattern.
+//
+// ResolveScoped groups the checksums by owning workload (the revision's
+// ownerReference UID), so each workload's candidates are matched only against
+// its own checksums: a checksum from Rollout A can never protect a ConfigMap
+// belonging to Rollout B. Exact name references stay namespace-wide: a
+// ConfigMap that any retained revision mounts by name is in use, whichever
+// workload's family it belongs to.
 
 import (
 	"context"
 	"fmt"
+	"slices"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/types"
 )
 
-// InUseResolver resolves the set of checksums that must not be deleted.
+// InUseResolver resolves the set of ConfigMaps and checksums that must not be deleted.
 type InUseResolver struct {
-	rsClient ReplicaSetLister
+	revisions []RevisionLister
 }
 
 // NewInUseResolver creates an InUseResolver using the provided ReplicaSetLister.
+// Only ReplicaSets owned by Argo Rollouts are considered.
 // No name prefix is needed: the resolver returns raw checksums derived from
 // ReplicaSet pod-template annotations ("checksum/config: <hash8>").
 func NewInUseResolver(rsClient ReplicaSetLister) *InUseResolver {
+	return NewWorkloadInUseResolver(replicaSetRevisions{ownerKind: KindRollout, client: rsClient})
+}
+
+// NewWorkloadInUseResolver creates an InUseResolver that merges the pod-template
+// revisions of every given lister (typically one WorkloadSource per enabled
+// workload kind) into a single in-use set.
+func NewWorkloadInUseResolver(revisions ...RevisionLister) *InUseResolver {
 	return &InUseResolver{
-		rsClient: rsClient,
+		revisions: revisions,
+	}
+}
+
+// InUseSet holds everything retained revisions reference for one resource
+// kind: exact object names from the pod spec, and checksum annotation values.
+type InUseSet struct {
+	Names     map[string]bool
+	Checksums map[string]bool
+}
+
+// Matches reports whether an object is in use: either referenced by name, or
+// carrying a hash (parsed from its name) equal to one of the checksums.
+func (s InUseSet) Matches(name, hash string) bool {
+	return s.Names[name] || (hash != "" && s.Checksums[hash])
+}
+
+// ResolveResources lists the retained revisions once and returns one InUseSet
+// per requested resource, keyed by resource kind (e.g. "ConfigMap", "Secret").
+func (r *InUseResolver) ResolveResources(ctx context.Context, namespace string, resources ...VersionedResource) (map[string]InUseSet, error) {
+	revisions, err := r.listRevisions(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make(map[string]InUseSet, len(resources))
+	for _, res := range resources {
+		sets[res.Kind] = buildInUseSet(res, revisions)
+	}
+	return sets, nil
+}
+
+// ScopedInUseSets holds one InUseSet per resource kind for every workload that
+// retains at least one revision, keyed by the workload's UID, and the names
+// referenced by every revision in the namespace.
+type ScopedInUseSets struct {
+	sets   map[types.UID]map[string]InUseSet
+	owners map[types.UID]string
+	// names holds, per resource kind, the names referenced by any revision.
+	names map[string]map[string]bool
+	// strategy holds the subset built from revisions flagged Strategy.
+	strategy map[types.UID]map[string]InUseSet
+	// shared and sharedStrategy hold, per resource kind, the sets built from
+	// revisions flagged Shared, which every workload's candidates match.
+	shared         map[string]InUseSet
+	sharedStrategy map[string]InUseSet
+}
+
+// For returns the InUseSet of the given resource kind: the names referenced by
+// any revision in the namespace, and the checksums of the revisions of the
+// workloads with the given UIDs only — typically one workload, or every owner
+// of a shared family — plus those of Shared revisions. Unknown owners
+// contribute no checksum.
+func (s ScopedInUseSets) For(kind string, owners ...types.UID) InUseSet {
+	set := mergeOwnerSets(s.sets, kind, owners)
+	set.Names = s.names[kind]
+	return unionSets(set, s.shared[kind])
+}
+
+// StrategyFor is For restricted to the revisions the Rollout strategy relies
+// on (TemplateRevision.Strategy), by owner or Shared.
+func (s ScopedInUseSets) StrategyFor(kind string, owners ...types.UID) InUseSet {
+	return unionSets(mergeOwnerSets(s.strategy, kind, owners), s.sharedStrategy[kind])
+}
+
+// unionSets returns the union of a and b, without modifying either.
+func unionSets(a, b InUseSet) InUseSet {
+	if len(b.Names) == 0 && len(b.Checksums) == 0 {
+		return a
+	}
+	union := InUseSet{Names: make(map[string]bool), Checksums: make(map[string]bool)}
+	for _, set := range []InUseSet{a, b} {
+		for name := range set.Names {
+			union.Names[name] = true
+		}
+		for checksum := range set.Checksums {
+			union.Checksums[checksum] = true
+		}
 	}
+	return union
 }
 
-// Resolve returns a set (map[string]bool) of raw checksums that are currently
-// referenced by at least one ReplicaSet owned by any Argo Rollout in the namespace.
+// mergeOwnerSets returns the union of the owners' sets of one kind.
+func mergeOwnerSets(byOwner map[types.UID]map[string]InUseSet, kind string, owners []types.UID) InUseSet {
+	if len(owners) == 1 {
+		return byOwner[owners[0]][kind]
+	}
+	merged := InUseSet{Names: make(map[string]bool), Checksums: make(map[string]bool)}
+	for _, owner := range owners {
+		set := byOwner[owner][kind]
+		for name := range set.Names {
+			merged.Names[name] = true
+		}
+		for checksum := range set.Checksums {
+			merged.Checksums[checksum] = true
+		}
+	}
+	return merged
+}
+
+// Owners returns the number of workloads with at least one retained revision.
+func (s ScopedInUseSets) Owners() int {
+	return len(s.sets)
+}
+
+// OtherOwners returns the names of workloads, other than owners, whose
+// checksums would mark the named object in use. It is used to report the
+// cross-workload checksum matches that scoping ignores.
+func (s ScopedInUseSets) OtherOwners(kind, name, hash string, owners ...types.UID) []string {
+	var others []string
+	for uid, sets := range s.sets {
+		if slices.Contains(owners, uid) {
+			continue
+		}
+		if (InUseSet{Checksums: sets[kind].Checksums}).Matches(name, hash) {
+			others = append(others, s.owners[uid])
+		}
+	}
+	sort.Strings(others)
+	return others
+}
+
+// ResolveScoped lists the retained revisions once and returns, for every
+// owning workload, one InUseSet per requested resource built only from that
+// workload's own revisions and the Shared ones.
+func (r *InUseResolver) ResolveScoped(ctx context.Context, namespace string, resources ...VersionedResource) (ScopedInUseSets, error) {
+	revisions, err := r.listRevisions(ctx, namespace)
+	if err != nil {
+		return ScopedInUseSets{}, err
+	}
+	return ScopeRevisions(revisions, resources...), nil
+}
+
+// ScopeRevisions groups already listed revisions by owning workload, as
+// ResolveScoped does.
+func ScopeRevisions(revisions []TemplateRevision, resources ...VersionedResource) ScopedInUseSets {
+	byOwner := make(map[types.UID][]TemplateRevision)
+	strategyByOwner := make(map[types.UID][]TemplateRevision)
+	var shared, sharedStrategy []TemplateRevision
+	scoped := ScopedInUseSets{
+		sets:     make(map[types.UID]map[string]InUseSet),
+		owners:   make(map[types.UID]string),
+		strategy: make(map[types.UID]map[string]InUseSet),
+		names:    make(map[string]map[string]bool, len(resources)),
+	}
+	for _, res := range resources {
+		scoped.names[res.Kind] = buildInUseSet(res, revisions).Names
+	}
+	for _, rev := range revisions {
+		byOwner[rev.OwnerUID] = append(byOwner[rev.OwnerUID], rev)
+		if rev.Strategy {
+			strategyByOwner[rev.OwnerUID] = append(strategyByOwner[rev.OwnerUID], rev)
+		}
+		scoped.owners[rev.OwnerUID] = rev.OwnerName
+		if rev.Shared {
+			shared = append(shared, rev)
+			if rev.Strategy {
+				sharedStrategy = append(sharedStrategy, rev)
+			}
+		}
+	}
+	scoped.shared = buildInUseSets(resources, shared)
+	scoped.sharedStrategy = buildInUseSets(resources, sharedStrategy)
+	for uid, owned := range byOwner {
+		scoped.sets[uid] = buildInUseSets(resources, owned)
+	}
+	for uid, pinned := range strategyByOwner {
+		scoped.strategy[uid] = buildInUseSets(resources, pinned)
+	}
+	return scoped
+}
+
+// buildInUseSets builds one InUseSet per resource kind from the revisions.
+func buildInUseSets(resources []VersionedResource, revisions []TemplateRevision) map[string]InUseSet {
+	sets := make(map[string]InUseSet, len(resources))
+	for _, res := range resources {
+		sets[res.Kind] = buildInUseSet(res, revisions)
+	}
+	return sets
+}
+
+// buildInUseSet collects the names and checksums of one resource kind
+// referenced by the given revisions.
+func buildInUseSet(res VersionedResource, revisions []TemplateRevision) InUseSet {
+	set := InUseSet{Names: make(map[string]bool), Checksums: make(map[string]bool)}
+	for _, rev := range revisions {
+		for _, name := range res.PodSpecRefs(rev.Template.Spec) {
+			set.Names[name] = true
+		}
+		checksum, ok := res.TemplateChecksum(rev.Template)
+		if !ok {
+			// Revision has no checksum annotation — skip silently.
+			continue
+		}
+		set.Checksums[checksum] = true
+	}
+	return set
+}
+
+// Resolve returns a set (map[string]bool) of raw checksum/config values that
+// are currently referenced by at least one retained pod-template revision in
+// the namespace.
 // Example return value: {"e6120fae": true, "b870a608": true}.
 //
-// Callers pass this set to FilterConfigMapsByChecksums to select the ConfigMaps
-// that are in use, regardless of service name or name prefix.
+// Callers compare the hash parsed from each candidate ConfigMap's name against
+// this set.
 func (r *InUseResolver) Resolve(ctx context.Context, namespace string) (map[string]bool, error) {
-	rsList, err := r.rsClient.ListNamespaceRolloutReplicaSets(ctx, namespace)
+	sets, err := r.ResolveResources(ctx, namespace, ConfigMapResource)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list replicasets in namespace %q: %w", namespace, err)
+		return nil, err
 	}
+	return sets[ResourceConfigMap].Checksums, nil
+}
 
-	checksums := make(map[string]bool)
-	for _, rs := range rsList {
-		checksum, ok := ExtractChecksum(rs)
-		if !ok {
-			// RS has no checksum/config annotation — skip silently.
-			continue
+// ResolveConfigMaps returns a set (map[string]bool) of ConfigMap names that are
+// referenced by the pod template of at least one retained revision in the
+// namespace.
+// Example return value: {"xzk0-seat-config-e6120fae": true}.
+func (r *InUseResolver) ResolveConfigMaps(ctx context.Context, namespace string) (map[string]bool, error) {
+	sets, err := r.ResolveResources(ctx, namespace, ConfigMapResource)
+	if err != nil {
+		return nil, err
+	}
+	return sets[ResourceConfigMap].Names, nil
+}
+
+// listRevisions concatenates the revisions of every configured lister.
+func (r *InUseResolver) listRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	var all []TemplateRevision
+	for _, lister := range r.revisions {
+		revisions, err := lister.ListRevisions(ctx, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pod template revisions in namespace %q: %w", namespace, err)
 		}
-		checksums[checksum] = true
+		all = append(all, revisions...)
 	}
-	return checksums, nil
+	return all, nil
 }
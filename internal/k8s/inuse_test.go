@@ -1,6 +1,6 @@
 package k8s
 
-// Unit tests for replicaset.go, rollout.go, and inuse.go.
+// Unit tests for replicaset.go, rollout.go, podspec.go, and inuse.go.
 // No live cluster required — all test doubles use the official fake clientsets:
 //   - k8s.io/client-go/kubernetes/fake  for ReplicaSets
 //   - github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake  for Rollouts
@@ -151,6 +151,107 @@ func TestExtractChecksum(t *testing.T) {
 	}
 }
 
+// ─── PodSpecConfigMapRefs ────────────────────────────────────────────────────
+
+func TestPodSpecConfigMapRefs(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     corev1.PodSpec
+		expected []string
+	}{
+		{
+			name:     "empty pod spec references nothing",
+			spec:     corev1.PodSpec{},
+			expected: []string{},
+		},
+		{
+			name: "configMap and projected volumes",
+			spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					configMapVolume("config", "xzk0-seat-config-e6120fae"),
+					{
+						Name: "bundle",
+						VolumeSource: corev1.VolumeSource{
+							Projected: &corev1.ProjectedVolumeSource{
+								Sources: []corev1.VolumeProjection{
+									{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "xzk0-seat-env-aaaa1111"}}},
+									{Secret: &corev1.SecretProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "not-a-configmap"}}},
+								},
+							},
+						},
+					},
+					{Name: "scratch", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+				},
+			},
+			expected: []string{"xzk0-seat-config-e6120fae", "xzk0-seat-env-aaaa1111"},
+		},
+		{
+			name: "envFrom and configMapKeyRef on init, regular and ephemeral containers",
+			spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{
+					Name:    "migrate",
+					EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "init-cm"}}}},
+				}},
+				Containers: []corev1.Container{{
+					Name: "app",
+					Env: []corev1.EnvVar{
+						{Name: "PLAIN", Value: "x"},
+						{Name: "FROM_CM", ValueFrom: &corev1.EnvVarSource{ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "app-cm"}, Key: "k"}}},
+					},
+				}},
+				EphemeralContainers: []corev1.EphemeralContainer{{
+					EphemeralContainerCommon: corev1.EphemeralContainerCommon{
+						Name:    "debug",
+						EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "debug-cm"}}}},
+					},
+				}},
+			},
+			expected: []string{"app-cm", "debug-cm", "init-cm"},
+		},
+		{
+			name: "same ConfigMap referenced twice is deduplicated",
+			spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{configMapVolume("config", "shared-cm")},
+				Containers: []corev1.Container{{
+					Name:    "app",
+					EnvFrom: []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "shared-cm"}}}},
+				}},
+			},
+			expected: []string{"shared-cm"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, PodSpecConfigMapRefs(tc.spec))
+		})
+	}
+}
+
+// ─── PodSpecSecretRefs ───────────────────────────────────────────────────────
+
+func TestPodSpecSecretRefs(t *testing.T) {
+	spec := corev1.PodSpec{
+		ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry-creds"}},
+		Volumes: []corev1.Volume{
+			{Name: "tls", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "xzk0-seat-secret-e6120fae"}}},
+			configMapVolume("config", "xzk0-seat-config-e6120fae"),
+		},
+		Containers: []corev1.Container{{
+			Name:    "app",
+			EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "env-secret"}}}},
+			Env: []corev1.EnvVar{
+				{Name: "TOKEN", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "token-secret"}, Key: "token"}}},
+			},
+		}},
+	}
+
+	assert.Equal(t,
+		[]string{"env-secret", "registry-creds", "token-secret", "xzk0-seat-secret-e6120fae"},
+		PodSpecSecretRefs(spec),
+	)
+}
+
 // ─── ListRolloutReplicaSets ───────────────────────────────────────────────────
 
 func TestListRolloutReplicaSets(t *testing.T) {
@@ -442,6 +543,156 @@ func TestInUseResolver_Resolve(t *testing.T) {
 	}
 }
 
+func TestInUseResolver_ResolveConfigMaps(t *testing.T) {
+	tests := []struct {
+		name       string
+		existingRS []appsv1.ReplicaSet
+		wantInUse  map[string]bool
+	}{
+		{
+			name: "exact names from every Rollout RS pod template",
+			existingRS: []appsv1.ReplicaSet{
+				withConfigMapVolume(makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"), "xzk0-seat-config-e6120fae"),
+				withConfigMapVolume(makeRS(testNamespace, "xzk0-seat-847848bbcf", testRolloutName, testRolloutUID, "b870a608"), "xzk0-seat-config-b870a608"),
+			},
+			wantInUse: map[string]bool{
+				"xzk0-seat-config-e6120fae": true,
+				"xzk0-seat-config-b870a608": true,
+			},
+		},
+		{
+			name: "RS without checksum annotation still protects its referenced ConfigMap",
+			existingRS: []appsv1.ReplicaSet{
+				withConfigMapVolume(makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, ""), "xzk0-seat-config-e6120fae"),
+			},
+			wantInUse: map[string]bool{
+				"xzk0-seat-config-e6120fae": true,
+			},
+		},
+		{
+			name: "RS not owned by a Rollout is excluded",
+			existingRS: []appsv1.ReplicaSet{
+				withConfigMapVolume(makeRSNoOwner(testNamespace, "standalone-rs", "cafecafe"), "standalone-config-cafecafe"),
+			},
+			wantInUse: map[string]bool{},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			fakeClient := fake.NewSimpleClientset(rsToRuntimeObjects(tc.existingRS)...)
+			resolver := NewInUseResolver(NewKubeReplicaSetClient(fakeClient))
+
+			got, err := resolver.ResolveConfigMaps(context.Background(), testNamespace)
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantInUse, got)
+		})
+	}
+}
+
+func TestInUseResolver_ResolveResources(t *testing.T) {
+	rs := makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae")
+	rs.Spec.Template.Annotations[AnnotationChecksumSecret] = "5ec7e700"
+	rs.Spec.Template.Spec.Volumes = []corev1.Volume{
+		configMapVolume("config", "xzk0-seat-config-e6120fae"),
+		{Name: "creds", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "xzk0-seat-secret-5ec7e700"}}},
+	}
+	fakeClient := fake.NewSimpleClientset(&rs)
+	resolver := NewInUseResolver(NewKubeReplicaSetClient(fakeClient))
+
+	sets, err := resolver.ResolveResources(context.Background(), testNamespace, ConfigMapResource, SecretResource)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"xzk0-seat-config-e6120fae": true}, sets[ResourceConfigMap].Names)
+	assert.Equal(t, map[string]bool{"e6120fae": true}, sets[ResourceConfigMap].Checksums)
+	assert.Equal(t, map[string]bool{"xzk0-seat-secret-5ec7e700": true}, sets[ResourceSecret].Names)
+	assert.Equal(t, map[string]bool{"5ec7e700": true}, sets[ResourceSecret].Checksums)
+}
+
+// TestInUseResolver_ResolveScoped verifies that a checksum retained by one
+// Rollout does not protect a ConfigMap of another Rollout sharing the hash.
+func TestInUseResolver_ResolveScoped(t *testing.T) {
+	const otherUID = "0b7a3c55-2d1e-4f7a-8c61-5a0c1f9e2b44"
+	rsList := []appsv1.ReplicaSet{
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"),
+		makeRS(testNamespace, "xzk0-seat-847848bbcf", testRolloutName, testRolloutUID, "b870a608"),
+		makeRS(testNamespace, "xzk0-order-5c8d7f9b6d", "xzk0-order", otherUID, "da8762a8"),
+	}
+	fakeClient := fake.NewSimpleClientset(rsToRuntimeObjects(rsList)...)
+	resolver := NewInUseResolver(NewKubeReplicaSetClient(fakeClient))
+
+	scoped, err := resolver.ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	assert.Equal(t, 2, scoped.Owners())
+
+	seat := scoped.For(ResourceConfigMap, testRolloutUID)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "b870a608": true}, seat.Checksums)
+	assert.True(t, seat.Matches("xzk0-seat-config-e6120fae", "e6120fae"))
+	assert.False(t, seat.Matches("xzk0-seat-config-da8762a8", "da8762a8"),
+		"da8762a8 is only retained by xzk0-order and must not protect xzk0-seat's ConfigMap")
+	assert.Equal(t, []string{"xzk0-order"},
+		scoped.OtherOwners(ResourceConfigMap, "xzk0-seat-config-da8762a8", "da8762a8", testRolloutUID))
+	assert.Empty(t, scoped.OtherOwners(ResourceConfigMap, "xzk0-seat-config-e6120fae", "e6120fae", testRolloutUID))
+
+	order := scoped.For(ResourceConfigMap, otherUID)
+	assert.Equal(t, map[string]bool{"da8762a8": true}, order.Checksums)
+
+	assert.Empty(t, scoped.For(ResourceConfigMap, "unknown-uid").Checksums)
+
+	shared := scoped.For(ResourceConfigMap, testRolloutUID, otherUID)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "b870a608": true, "da8762a8": true}, shared.Checksums)
+	assert.Empty(t, scoped.OtherOwners(ResourceConfigMap, "xzk0-seat-config-da8762a8", "da8762a8", testRolloutUID, otherUID))
+}
+
+// TestInUseResolver_ResolveScopedKeepsNamesNamespaceWide verifies that a
+// ConfigMap of one Rollout mounted by name by another workload's pod template
+// stays in use: only checksums are scoped to the owning workload.
+func TestInUseResolver_ResolveScopedKeepsNamesNamespaceWide(t *testing.T) {
+	const otherUID = "0b7a3c55-2d1e-4f7a-8c61-5a0c1f9e2b44"
+	rsList := []appsv1.ReplicaSet{
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"),
+		withConfigMapVolume(makeRS(testNamespace, "xzk0-order-5c8d7f9b6d", "xzk0-order", otherUID, ""), "xzk0-seat-config-da8762a8"),
+	}
+	fakeClient := fake.NewSimpleClientset(rsToRuntimeObjects(rsList)...)
+	resolver := NewInUseResolver(NewKubeReplicaSetClient(fakeClient))
+
+	scoped, err := resolver.ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+
+	seat := scoped.For(ResourceConfigMap, testRolloutUID)
+	assert.True(t, seat.Matches("xzk0-seat-config-da8762a8", "da8762a8"),
+		"xzk0-order mounts xzk0-seat's ConfigMap by name")
+	assert.Equal(t, map[string]bool{"e6120fae": true}, seat.Checksums)
+	assert.Empty(t, scoped.OtherOwners(ResourceConfigMap, "xzk0-seat-config-da8762a8", "da8762a8", testRolloutUID))
+}
+
+func TestOwnerOfKind_PrefersController(t *testing.T) {
+	notController := false
+	refs := []metav1.OwnerReference{
+		{Kind: KindRollout, Name: "adopter", UID: "adopter-uid", Controller: &notController},
+		makeRolloutOwnerRef(testRolloutName, testRolloutUID),
+	}
+
+	owner, ok := ownerOfKind(refs, KindRollout)
+	require.True(t, ok)
+	assert.Equal(t, testRolloutName, owner.Name)
+
+	_, ok = ownerOfKind(refs, KindDeployment)
+	assert.False(t, ok)
+}
+
+func TestInUseSet_Matches(t *testing.T) {
+	set := InUseSet{
+		Names:     map[string]bool{"xzk0-seat-config-e6120fae": true},
+		Checksums: map[string]bool{"b870a608": true},
+	}
+
+	assert.True(t, set.Matches("xzk0-seat-config-e6120fae", "e6120fae"), "referenced by name")
+	assert.True(t, set.Matches("xzk0-seat-config-b870a608", "b870a608"), "matched by checksum")
+	assert.False(t, set.Matches("xzk0-seat-config-da8762a8", "da8762a8"), "orphan")
+	assert.False(t, set.Matches("xzk0-seat-config-b870a608x", ""), "name does not parse, so only exact names count")
+}
+
 // TestInUseResolver_OrphanScenario verifies the key business scenario from
 // statusnow.md: exactly one ConfigMap (da8762a8) is orphaned and should be
 // eligible for deletion when combined with the planner.
@@ -487,5 +738,23 @@ func TestKubeRolloutClient_ImplementsInterface(t *testing.T) {
 
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
+// configMapVolume returns a pod volume that mounts the named ConfigMap.
+func configMapVolume(volumeName, cmName string) corev1.Volume {
+	return corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: cmName},
+			},
+		},
+	}
+}
+
+// withConfigMapVolume adds a ConfigMap volume to the ReplicaSet's pod template.
+func withConfigMapVolume(rs appsv1.ReplicaSet, cmName string) appsv1.ReplicaSet {
+	rs.Spec.Template.Spec.Volumes = append(rs.Spec.Template.Spec.Volumes, configMapVolume("config", cmName))
+	return rs
+}
+
 // int32Ptr returns a pointer to an int32 value, for use in Rollout specs.
 func int32Ptr(v int32) *int32 { return &v }
@@ -0,0 +1,33 @@
+package k8s
+
+// Namespace discovery for cluster-wide runs.
+// ListNamespaces lists Namespace objects matching a label selector, so a single
+// CronJob can cover every team namespace instead of a hard-coded list. It is
+// independent of Clients.LabelSelector, which selects apps, not namespaces.
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ListNamespaces returns the names of the namespaces matching selector ("" for
+// all), excluding those for which exclude returns true, in API order. Terminating
+// namespaces are skipped: their objects are being deleted anyway.
+func ListNamespaces(ctx context.Context, clients *Clients, selector string, exclude func(string) bool) ([]string, error) {
+	scope := listScope{selector: selector, pageSize: clients.PageSize}
+	items, err := listAll(ctx, "namespaces", scope, clients.Kube.CoreV1().Namespaces().List,
+		func(l *corev1.NamespaceList) []corev1.Namespace { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+	var names []string
+	for _, ns := range items {
+		if ns.Status.Phase == corev1.NamespaceTerminating || exclude(ns.Name) {
+			continue
+		}
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
@@ -0,0 +1,52 @@
+package k8s
+
+// Unit tests for namespace.go using fake.NewSimpleClientset(), which applies
+// label selectors to List calls like the API server.
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// makeNamespace builds a Namespace with the given labels.
+func makeNamespace(name string, labels map[string]string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestListNamespaces(t *testing.T) {
+	terminating := makeNamespace("team-old", map[string]string{"team": "old"})
+	terminating.Status.Phase = corev1.NamespaceTerminating
+	clients := &Clients{Kube: fake.NewSimpleClientset(
+		makeNamespace("kube-system", nil),
+		makeNamespace("mwpcloud", map[string]string{"team": "seat"}),
+		makeNamespace("order", map[string]string{"team": "order"}),
+		makeNamespace("order-sandbox", map[string]string{"team": "order"}),
+		terminating,
+	)}
+	excludeSandboxes := func(ns string) bool { return strings.HasSuffix(ns, "-sandbox") }
+
+	t.Run("all namespaces", func(t *testing.T) {
+		got, err := ListNamespaces(context.Background(), clients, "", excludeSandboxes)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"kube-system", "mwpcloud", "order"}, got)
+	})
+
+	t.Run("label selector", func(t *testing.T) {
+		got, err := ListNamespaces(context.Background(), clients, "team", excludeSandboxes)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"mwpcloud", "order"}, got)
+	})
+
+	t.Run("nothing matches", func(t *testing.T) {
+		got, err := ListNamespaces(context.Background(), clients, "team=billing", excludeSandboxes)
+		require.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
@@ -0,0 +1,110 @@
+package k8s
+
+// Paginated listing.
+// Every lister pages through its List calls with metav1.ListOptions Limit and
+// Continue, so namespaces with tens of thousands of objects are fetched in
+// bounded chunks instead of one response the API server may time out on.
+//
+// A continue token expires once the resource version it pins is compacted
+// away; the API server then answers 410 Gone (reason Expired). The listing is
+// restarted from the first page, up to maxListRestarts times, rather than
+// mixing pages from two different snapshots.
+//
+// Each listing, restarts included, is observed by the
+// cm_gc_api_list_duration_seconds histogram, labelled by the resource its
+// caller names, e.g. "configmaps" — typed and metadata-only listings of the
+// same resource share a label.
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/yujen77300/configmap-collector/internal/metrics"
+)
+
+// DefaultPageSize is the number of objects requested per List page.
+const DefaultPageSize = 500
+
+// maxListRestarts bounds how often an expired listing is restarted.
+const maxListRestarts = 3
+
+// listScope holds the options shared by every List call of one client: the
+// label selector and the page size (0 lists everything in one call).
+type listScope struct {
+	selector string
+	pageSize int64
+}
+
+// options returns the ListOptions for the first page.
+func (s listScope) options() metav1.ListOptions {
+	return metav1.ListOptions{LabelSelector: s.selector, Limit: s.pageSize}
+}
+
+// withSelector returns s further restricted by selector.
+func (s listScope) withSelector(selector string) listScope {
+	s.selector = joinSelectors(s.selector, selector)
+	return s
+}
+
+// listAll pages through a typed List call, e.g.
+// client.CoreV1().ConfigMaps(ns).List, until the continue token is empty and
+// returns the items of every page, as extracted by items. An expired continue
+// token restarts the listing. resource labels the listing's duration.
+func listAll[T any, L metav1.ListInterface](
+	ctx context.Context,
+	resource string,
+	scope listScope,
+	list func(context.Context, metav1.ListOptions) (L, error),
+	items func(L) []T,
+) ([]T, error) {
+	defer observeList(resource, time.Now())
+	for restarts := 0; ; restarts++ {
+		all, err := listPages(ctx, scope, list, items)
+		if err == nil {
+			return all, nil
+		}
+		if !isExpired(err) {
+			return nil, err
+		}
+		if restarts == maxListRestarts {
+			return nil, fmt.Errorf("list restarted %d times after expired continue tokens: %w", maxListRestarts, err)
+		}
+	}
+}
+
+// observeList records the duration of a listing of resource started at start.
+func observeList(resource string, start time.Time) {
+	metrics.APIListDuration.WithLabelValues(resource).Observe(time.Since(start).Seconds())
+}
+
+// listPages makes one attempt at listing every page.
+func listPages[T any, L metav1.ListInterface](
+	ctx context.Context,
+	scope listScope,
+	list func(context.Context, metav1.ListOptions) (L, error),
+	items func(L) []T,
+) ([]T, error) {
+	opts := scope.options()
+	var all []T
+	for {
+		page, err := list(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items(page)...)
+		if page.GetContinue() == "" {
+			return all, nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// isExpired reports whether err is the 410 Gone returned for an expired
+// continue token.
+func isExpired(err error) bool {
+	return apierrors.IsResourceExpired(err) || apierrors.IsGone(err)
+}
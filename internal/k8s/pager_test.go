@@ -0,0 +1,162 @@
+package k8s
+
+// Unit tests for pager.go. The fake clientset ignores Limit and Continue, so
+// listAll is driven by a stub List call that serves the pages and injects
+// expired continue tokens.
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/yujen77300/configmap-collector/internal/metrics"
+)
+
+// pager is a stub List call serving count ConfigMaps in pages of the
+// requested Limit, as the API server does; the fake clientset ignores Limit
+// and Continue. Continue tokens are the offset of the next page; the first
+// expire calls with a non-empty token fail with 410 Gone. calls records the
+// ListOptions of every call.
+type pager struct {
+	items  []corev1.ConfigMap
+	expire int
+	err    error
+	calls  []metav1.ListOptions
+}
+
+func newPager(count, expire int) *pager {
+	items := make([]corev1.ConfigMap, count)
+	for i := range items {
+		items[i] = makeConfigMap(testNamespace, fmt.Sprintf("xzk0-seat-config-%08x", i), nil)
+	}
+	return &pager{items: items, expire: expire}
+}
+
+func (p *pager) list(_ context.Context, opts metav1.ListOptions) (*corev1.ConfigMapList, error) {
+	p.calls = append(p.calls, opts)
+	if p.err != nil {
+		return nil, p.err
+	}
+	start := 0
+	if opts.Continue != "" {
+		if p.expire > 0 {
+			p.expire--
+			return nil, apierrors.NewResourceExpired("continue token expired")
+		}
+		start, _ = strconv.Atoi(opts.Continue)
+	}
+	end := len(p.items)
+	if opts.Limit > 0 && start+int(opts.Limit) < end {
+		end = start + int(opts.Limit)
+	}
+	list := &corev1.ConfigMapList{Items: p.items[start:end]}
+	if end < len(p.items) {
+		list.Continue = strconv.Itoa(end)
+	}
+	return list, nil
+}
+
+// listAll lists every ConfigMap p serves, pageSize at a time.
+func (p *pager) listAll(pageSize int64) ([]corev1.ConfigMap, error) {
+	return listAll(context.Background(), "configmaps", listScope{selector: "app=seat", pageSize: pageSize}, p.list,
+		func(l *corev1.ConfigMapList) []corev1.ConfigMap { return l.Items })
+}
+
+// continues returns the Continue token of every call.
+func (p *pager) continues() []string {
+	tokens := make([]string, 0, len(p.calls))
+	for _, opts := range p.calls {
+		tokens = append(tokens, opts.Continue)
+	}
+	return tokens
+}
+
+func TestListAll_Pages(t *testing.T) {
+	p := newPager(5, 0)
+
+	cms, err := p.listAll(2)
+	require.NoError(t, err)
+	assert.Equal(t, p.items, cms)
+	for _, opts := range p.calls {
+		assert.Equal(t, int64(2), opts.Limit)
+		assert.Equal(t, "app=seat", opts.LabelSelector)
+	}
+	assert.Equal(t, []string{"", "2", "4"}, p.continues())
+}
+
+func TestListAll_Unpaginated(t *testing.T) {
+	p := newPager(5, 0)
+
+	cms, err := p.listAll(0)
+	require.NoError(t, err)
+	assert.Len(t, cms, 5)
+	assert.Len(t, p.calls, 1)
+}
+
+// TestListAll_ExpiredContinueRestarts verifies that a 410 Gone restarts the
+// listing from the first page instead of returning a partial or mixed result.
+func TestListAll_ExpiredContinueRestarts(t *testing.T) {
+	p := newPager(5, 1)
+
+	cms, err := p.listAll(2)
+	require.NoError(t, err)
+	assert.Equal(t, p.items, cms, "no page may be lost or duplicated")
+	// page 1, expired page 2, then pages 1-3 again.
+	assert.Equal(t, []string{"", "2", "", "2", "4"}, p.continues())
+}
+
+func TestListAll_ExpiredTooOften(t *testing.T) {
+	p := newPager(5, maxListRestarts+1)
+
+	_, err := p.listAll(2)
+	require.Error(t, err)
+	assert.True(t, apierrors.IsResourceExpired(err))
+	assert.Len(t, p.calls, 2*(maxListRestarts+1))
+}
+
+func TestListAll_OtherErrorsAreNotRetried(t *testing.T) {
+	p := newPager(5, 0)
+	p.err = apierrors.NewForbidden(corev1.Resource("configmaps"), "", fmt.Errorf("denied"))
+
+	_, err := p.listAll(2)
+	require.Error(t, err)
+	assert.Len(t, p.calls, 1)
+}
+
+// listObservations returns how many listings of resource the
+// api_list_duration_seconds histogram has observed.
+func listObservations(t *testing.T, resource string) uint64 {
+	t.Helper()
+	var m dto.Metric
+	require.NoError(t, metrics.APIListDuration.WithLabelValues(resource).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
+
+// TestListAll_ObservesDuration verifies that a listing is observed once,
+// whatever its number of pages, labelled by the resource its caller names.
+func TestListAll_ObservesDuration(t *testing.T) {
+	before := listObservations(t, "configmaps")
+
+	_, err := newPager(5, 0).listAll(2)
+	require.NoError(t, err)
+	assert.Equal(t, before+1, listObservations(t, "configmaps"))
+}
+
+// TestListAll_ObservesRestartedListingOnce verifies that restarts after an
+// expired continue token belong to the same observed listing.
+func TestListAll_ObservesRestartedListingOnce(t *testing.T) {
+	before := listObservations(t, "configmaps")
+
+	_, err := newPager(5, 2).listAll(2)
+	require.NoError(t, err)
+	assert.Equal(t, before+1, listObservations(t, "configmaps"))
+}
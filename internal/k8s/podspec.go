@@ -0,0 +1,132 @@
+package k8s
+
+// Pod template ConfigMap and Secret reference extraction.
+// Walks every place a PodSpec can name a ConfigMap — volumes, projected
+// volume sources, envFrom and env[].valueFrom.configMapKeyRef on regular,
+// init and ephemeral containers — and returns the exact ConfigMap names.
+// Secrets are walked the same way, plus imagePullSecrets.
+// Pure functions only: no API calls, so any workload's pod template can be fed in.
+
+import (
+	"slices"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// PodSpecConfigMapRefs returns the sorted, deduplicated names of every
+// ConfigMap referenced by the given PodSpec. Optional references are included:
+// if the ConfigMap exists it will be consumed by the pod, so it must be kept.
+func PodSpecConfigMapRefs(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" {
+			seen[name] = true
+		}
+	}
+
+	for _, vol := range spec.Volumes {
+		if vol.ConfigMap != nil {
+			add(vol.ConfigMap.Name)
+		}
+		if vol.Projected != nil {
+			for _, src := range vol.Projected.Sources {
+				if src.ConfigMap != nil {
+					add(src.ConfigMap.Name)
+				}
+			}
+		}
+	}
+
+	addEnv := func(envFrom []corev1.EnvFromSource, env []corev1.EnvVar) {
+		for _, ef := range envFrom {
+			if ef.ConfigMapRef != nil {
+				add(ef.ConfigMapRef.Name)
+			}
+		}
+		for _, e := range env {
+			if e.ValueFrom != nil && e.ValueFrom.ConfigMapKeyRef != nil {
+				add(e.ValueFrom.ConfigMapKeyRef.Name)
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+	for _, c := range spec.Containers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+	for _, c := range spec.EphemeralContainers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+
+	return sortedKeys(seen)
+}
+
+// PodSpecSecretRefs returns the sorted, deduplicated names of every Secret
+// referenced by the given PodSpec: secret and projected volumes, envFrom,
+// env[].valueFrom.secretKeyRef and imagePullSecrets.
+func PodSpecSecretRefs(spec corev1.PodSpec) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if name != "" {
+			seen[name] = true
+		}
+	}
+
+	for _, vol := range spec.Volumes {
+		if vol.Secret != nil {
+			add(vol.Secret.SecretName)
+		}
+		if vol.Projected != nil {
+			for _, src := range vol.Projected.Sources {
+				if src.Secret != nil {
+					add(src.Secret.Name)
+				}
+			}
+		}
+	}
+	for _, ref := range spec.ImagePullSecrets {
+		add(ref.Name)
+	}
+
+	addEnv := func(envFrom []corev1.EnvFromSource, env []corev1.EnvVar) {
+		for _, ef := range envFrom {
+			if ef.SecretRef != nil {
+				add(ef.SecretRef.Name)
+			}
+		}
+		for _, e := range env {
+			if e.ValueFrom != nil && e.ValueFrom.SecretKeyRef != nil {
+				add(e.ValueFrom.SecretKeyRef.Name)
+			}
+		}
+	}
+	for _, c := range spec.InitContainers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+	for _, c := range spec.Containers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+	for _, c := range spec.EphemeralContainers {
+		addEnv(c.EnvFrom, c.Env)
+	}
+
+	return sortedKeys(seen)
+}
+
+// sortedKeys returns the keys of a set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	names := make([]string, 0, len(set))
+	for name := range set {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ExtractConfigMapRefs returns the names of every ConfigMap referenced by a
+// ReplicaSet's pod template. Returns an empty slice when nothing is referenced.
+func ExtractConfigMapRefs(rs appsv1.ReplicaSet) []string {
+	return PodSpecConfigMapRefs(rs.Spec.Template.Spec)
+}
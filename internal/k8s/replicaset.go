@@ -10,6 +10,7 @@ import (
 	"fmt"
 
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -18,6 +19,11 @@ const (
 	// AnnotationChecksumConfig is the pod template annotation written by Helm
 	// that contains the 8-char SHA256 hash of the mounted ConfigMap's content.
 	AnnotationChecksumConfig = "checksum/config"
+	// AnnotationRolloutRevision and AnnotationDeploymentRevision carry the
+	// revision number Argo Rollouts and the Deployment controller give each
+	// ReplicaSet they own.
+	AnnotationRolloutRevision    = "rollout.argoproj.io/revision"
+	AnnotationDeploymentRevision = "deployment.kubernetes.io/revision"
 )
 
 // ReplicaSetLister lists ReplicaSets owned by a named Argo Rollout.
@@ -30,12 +36,21 @@ type ReplicaSetLister interface {
 	// owned by any Argo Rollout (kind=Rollout), regardless of rollout name.
 	// Used for multi-namespace GC where rollout names are not pre-configured.
 	ListNamespaceRolloutReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error)
+
+	// ListNamespaceOwnedReplicaSets returns all RS in the namespace that are
+	// owned by any controller of the given kind (e.g. "Rollout", "Deployment").
+	ListNamespaceOwnedReplicaSets(ctx context.Context, namespace, ownerKind string) ([]appsv1.ReplicaSet, error)
+
+	// ListNamespaceReplicaSets returns every RS in the namespace, whatever
+	// its owner.
+	ListNamespaceReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error)
 }
 
 // KubeReplicaSetClient is the production implementation backed by a real
 // (or fake) kubernetes.Interface.
 type KubeReplicaSetClient struct {
 	client kubernetes.Interface
+	scope  listScope
 }
 
 // NewKubeReplicaSetClient creates a KubeReplicaSetClient wrapping the provided
@@ -44,18 +59,32 @@ func NewKubeReplicaSetClient(client kubernetes.Interface) *KubeReplicaSetClient
 	return &KubeReplicaSetClient{client: client}
 }
 
+// WithLabelSelector restricts every list made by k to ReplicaSets matching
+// selector and returns k.
+func (k *KubeReplicaSetClient) WithLabelSelector(selector string) *KubeReplicaSetClient {
+	k.scope.selector = selector
+	return k
+}
+
+// WithPageSize makes every list made by k fetch at most size ReplicaSets per
+// request (0 disables pagination) and returns k.
+func (k *KubeReplicaSetClient) WithPageSize(size int64) *KubeReplicaSetClient {
+	k.scope.pageSize = size
+	return k
+}
+
 // ListRolloutReplicaSets returns all ReplicaSets in the namespace whose
 // ownerReferences contain an entry with kind=Rollout and name=rolloutName.
 // This covers both the active RS and every history revision retained by the
 // Rollout's revisionHistoryLimit.
 func (k *KubeReplicaSetClient) ListRolloutReplicaSets(ctx context.Context, namespace, rolloutName string) ([]appsv1.ReplicaSet, error) {
-	list, err := k.client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	items, err := k.ListNamespaceReplicaSets(ctx, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list replicasets in namespace %q: %w", namespace, err)
+		return nil, err
 	}
 
 	var owned []appsv1.ReplicaSet
-	for _, rs := range list.Items {
+	for _, rs := range items {
 		if isOwnedByRollout(rs, rolloutName) {
 			owned = append(owned, rs)
 		}
@@ -68,45 +97,89 @@ func (k *KubeReplicaSetClient) ListRolloutReplicaSets(ctx context.Context, names
 // This is the preferred method for multi-namespace GC: no rollout name is
 // required, and all Rollout-managed RS are included in one API call.
 func (k *KubeReplicaSetClient) ListNamespaceRolloutReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
-	list, err := k.client.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	return k.ListNamespaceOwnedReplicaSets(ctx, namespace, KindRollout)
+}
+
+// ListNamespaceOwnedReplicaSets returns all ReplicaSets in the namespace whose
+// ownerReferences contain any entry with the given kind. Deployments and
+// Rollouts both retain their revision history as ReplicaSets, so one lister
+// serves both workload kinds.
+func (k *KubeReplicaSetClient) ListNamespaceOwnedReplicaSets(ctx context.Context, namespace, ownerKind string) ([]appsv1.ReplicaSet, error) {
+	items, err := k.ListNamespaceReplicaSets(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ownedByKind(items, ownerKind), nil
+}
+
+// ListNamespaceReplicaSets returns every ReplicaSet in the namespace.
+func (k *KubeReplicaSetClient) ListNamespaceReplicaSets(ctx context.Context, namespace string) ([]appsv1.ReplicaSet, error) {
+	items, err := listAll(ctx, "replicasets", k.scope, k.client.AppsV1().ReplicaSets(namespace).List,
+		func(l *appsv1.ReplicaSetList) []appsv1.ReplicaSet { return l.Items })
 	if err != nil {
 		return nil, fmt.Errorf("failed to list replicasets in namespace %q: %w", namespace, err)
 	}
+	return items, nil
+}
 
+// ownedByKind returns the ReplicaSets with an ownerReference of the given kind.
+func ownedByKind(items []appsv1.ReplicaSet, ownerKind string) []appsv1.ReplicaSet {
 	var owned []appsv1.ReplicaSet
-	for _, rs := range list.Items {
-		if isOwnedByAnyRollout(rs) {
+	for _, rs := range items {
+		if isOwnedByKind(rs.OwnerReferences, ownerKind) {
 			owned = append(owned, rs)
 		}
 	}
-	return owned, nil
+	return owned
 }
 
 // isOwnedByRollout returns true when any ownerReference on the ReplicaSet
 // has kind="Rollout" and name=rolloutName.
 func isOwnedByRollout(rs appsv1.ReplicaSet, rolloutName string) bool {
 	for _, ref := range rs.OwnerReferences {
-		if ref.Kind == "Rollout" && ref.Name == rolloutName {
+		if ref.Kind == KindRollout && ref.Name == rolloutName {
 			return true
 		}
 	}
 	return false
 }
 
-// isOwnedByAnyRollout returns true when any ownerReference on the ReplicaSet
-// has kind="Rollout", regardless of the rollout name.
-func isOwnedByAnyRollout(rs appsv1.ReplicaSet) bool {
-	for _, ref := range rs.OwnerReferences {
-		if ref.Kind == "Rollout" {
-			return true
+// isOwnedByKind returns true when any of the ownerReferences has the given
+// kind, regardless of the owner's name.
+func isOwnedByKind(refs []metav1.OwnerReference, kind string) bool {
+	_, ok := ownerOfKind(refs, kind)
+	return ok
+}
+
+// ownerOfKind returns the ownerReference with the given kind, preferring the
+// controller reference when several match.
+func ownerOfKind(refs []metav1.OwnerReference, kind string) (metav1.OwnerReference, bool) {
+	var found *metav1.OwnerReference
+	for i := range refs {
+		if refs[i].Kind != kind {
+			continue
+		}
+		if refs[i].Controller != nil && *refs[i].Controller {
+			return refs[i], true
+		}
+		if found == nil {
+			found = &refs[i]
 		}
 	}
-	return false
+	if found == nil {
+		return metav1.OwnerReference{}, false
+	}
+	return *found, true
 }
 
 // ExtractChecksum returns the value of the checksum/config annotation from a
 // ReplicaSet's pod template. Returns ("", false) when the annotation is absent.
 func ExtractChecksum(rs appsv1.ReplicaSet) (string, bool) {
-	checksum, ok := rs.Spec.Template.Annotations[AnnotationChecksumConfig]
-	return checksum, ok && checksum != ""
+	return TemplateChecksum(rs.Spec.Template)
+}
+
+// TemplateChecksum returns the value of the checksum/config annotation from any
+// pod template. Returns ("", false) when the annotation is absent or empty.
+func TemplateChecksum(tpl corev1.PodTemplateSpec) (string, bool) {
+	return ConfigMapResource.TemplateChecksum(tpl)
 }
@@ -0,0 +1,128 @@
+package k8s
+
+// Versioned resource kinds and the kind-agnostic candidate client.
+// ConfigMaps and Secrets generated by Helm follow the same lifecycle:
+// "{app}-config-{hash8}" / "{app}-secret-{hash8}" objects referenced from the
+// pod template and fingerprinted by a "checksum/config" / "checksum/secret"
+// annotation. VersionedResource captures what differs between the two kinds
+// so listing, in-use resolution, planning and deletion can share one pipeline.
+//
+// Candidates are returned as metav1.ObjectMeta only: ConfigMap data and Secret
+// payloads never leave the client, so they cannot end up in logs.
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Supported versioned resource kinds.
+const (
+	ResourceConfigMap = "ConfigMap"
+	ResourceSecret    = "Secret"
+)
+
+const (
+	// AnnotationChecksumSecret is the pod template annotation written by Helm
+	// that contains the hash of the mounted Secret's content.
+	AnnotationChecksumSecret = "checksum/secret"
+)
+
+// VersionedResource describes one kind of versioned object collected by cm-gc.
+type VersionedResource struct {
+	// Kind is the Kubernetes kind, e.g. "ConfigMap".
+	Kind string
+	// ChecksumAnnotation is the pod template annotation carrying the hash.
+	ChecksumAnnotation string
+	// PodSpecRefs returns the names of objects of this kind referenced by a PodSpec.
+	PodSpecRefs func(spec corev1.PodSpec) []string
+}
+
+// ConfigMapResource describes Helm-versioned ConfigMaps ("{app}-config-{hash8}").
+var ConfigMapResource = VersionedResource{
+	Kind:               ResourceConfigMap,
+	ChecksumAnnotation: AnnotationChecksumConfig,
+	PodSpecRefs:        PodSpecConfigMapRefs,
+}
+
+// SecretResource describes Helm-versioned Secrets ("{app}-secret-{hash8}").
+var SecretResource = VersionedResource{
+	Kind:               ResourceSecret,
+	ChecksumAnnotation: AnnotationChecksumSecret,
+	PodSpecRefs:        PodSpecSecretRefs,
+}
+
+// TemplateChecksum returns the value of this resource's checksum annotation
+// from a pod template. Returns ("", false) when absent or empty.
+func (r VersionedResource) TemplateChecksum(tpl corev1.PodTemplateSpec) (string, bool) {
+	checksum, ok := tpl.Annotations[r.ChecksumAnnotation]
+	return checksum, ok && checksum != ""
+}
+
+// CandidateClient lists and deletes versioned objects of a single kind without
+// ever exposing their data.
+type CandidateClient interface {
+	// Resource describes the kind handled by this client.
+	Resource() VersionedResource
+
+	// ListCandidates returns the metadata of every object in the namespace
+	// whose name starts with namePrefix; an empty prefix returns them all.
+	ListCandidates(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error)
+
+	// DeleteCandidate deletes the named object. The caller is responsible for
+	// enforcing dry-run logic.
+	DeleteCandidate(ctx context.Context, namespace, name string) error
+}
+
+// CandidateSizer is implemented by CandidateClients that can tell the size of
+// objects' payloads, for plan reports. Sizing lists the objects in full, so it
+// is only done on demand; the payloads themselves are never returned.
+type CandidateSizer interface {
+	// CandidateSizes returns, by object name, the total size in bytes of the
+	// keys and values of every object in the namespace, from one paginated
+	// List.
+	CandidateSizes(ctx context.Context, namespace string) (map[string]int64, error)
+}
+
+// objectSize is the payload size of one listed object. Listings keep only
+// these, so each page's payloads are dropped as soon as it is sized.
+type objectSize struct {
+	name string
+	size int64
+}
+
+// sizesByName returns sizes keyed by object name.
+func sizesByName(sizes []objectSize) map[string]int64 {
+	byName := make(map[string]int64, len(sizes))
+	for _, s := range sizes {
+		byName[s.name] = s.size
+	}
+	return byName
+}
+
+// payloadSize returns the total size of the keys and values of maps.
+func payloadSize[V string | []byte](maps ...map[string]V) int64 {
+	var size int64
+	for _, m := range maps {
+		for key, value := range m {
+			size += int64(len(key) + len(value))
+		}
+	}
+	return size
+}
+
+// NewCandidateClient returns the CandidateClient for the given resource kind,
+// listing only objects that match clients.LabelSelector, clients.PageSize at a
+// time.
+func NewCandidateClient(kind string, clients *Clients) (CandidateClient, error) {
+	switch kind {
+	case ResourceConfigMap:
+		return &KubeConfigMapClient{client: clients.Kube, metadata: clients.Metadata, scope: clients.scope()}, nil
+	case ResourceSecret:
+		return &KubeSecretClient{client: clients.Kube, metadata: clients.Metadata, scope: clients.scope()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported resource kind %q", kind)
+	}
+}
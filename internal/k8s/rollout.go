@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
 	rolloutclientset "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -20,17 +21,20 @@ const DefaultRevisionHistoryLimit = 10
 // RolloutGetter retrieves a single Rollout by name.
 type RolloutGetter interface {
 	GetRevisionHistoryLimit(ctx context.Context, namespace, rolloutName string) (int, error)
+	GetRolloutStatus(ctx context.Context, namespace, rolloutName string) (rolloutsv1alpha1.RolloutStatus, error)
 }
 
-// RolloutLister lists all Rollout names in a namespace.
+// RolloutLister lists all Rollouts in a namespace.
 type RolloutLister interface {
 	ListRolloutNames(ctx context.Context, namespace string) ([]string, error)
+	ListRollouts(ctx context.Context, namespace string) ([]rolloutsv1alpha1.Rollout, error)
 }
 
 // KubeRolloutClient is the production implementation backed by the Argo
 // Rollouts clientset. Pass a fake rollout clientset in unit tests.
 type KubeRolloutClient struct {
 	client rolloutclientset.Interface
+	scope  listScope
 }
 
 // NewKubeRolloutClient creates a KubeRolloutClient wrapping the provided
@@ -39,22 +43,46 @@ func NewKubeRolloutClient(client rolloutclientset.Interface) *KubeRolloutClient
 	return &KubeRolloutClient{client: client}
 }
 
+// WithLabelSelector restricts every list made by k to Rollouts matching
+// selector and returns k.
+func (k *KubeRolloutClient) WithLabelSelector(selector string) *KubeRolloutClient {
+	k.scope.selector = selector
+	return k
+}
+
+// WithPageSize makes every list made by k fetch at most size Rollouts per
+// request (0 disables pagination) and returns k.
+func (k *KubeRolloutClient) WithPageSize(size int64) *KubeRolloutClient {
+	k.scope.pageSize = size
+	return k
+}
+
 // ListRolloutNames returns the names of all Argo Rollouts in the given namespace.
 // This is used to auto-derive ConfigMap name prefixes without requiring
 // manual configuration — each Rollout named "foo" manages ConfigMaps with
 // prefix "foo-config-".
 func (k *KubeRolloutClient) ListRolloutNames(ctx context.Context, namespace string) ([]string, error) {
-	list, err := k.client.ArgoprojV1alpha1().Rollouts(namespace).List(ctx, metav1.ListOptions{})
+	rollouts, err := k.ListRollouts(ctx, namespace)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list rollouts in namespace %q: %w", namespace, err)
+		return nil, err
 	}
-	names := make([]string, 0, len(list.Items))
-	for _, r := range list.Items {
+	names := make([]string, 0, len(rollouts))
+	for _, r := range rollouts {
 		names = append(names, r.Name)
 	}
 	return names, nil
 }
 
+// ListRollouts returns every Argo Rollout object in the given namespace.
+func (k *KubeRolloutClient) ListRollouts(ctx context.Context, namespace string) ([]rolloutsv1alpha1.Rollout, error) {
+	items, err := listAll(ctx, "rollouts", k.scope, k.client.ArgoprojV1alpha1().Rollouts(namespace).List,
+		func(l *rolloutsv1alpha1.RolloutList) []rolloutsv1alpha1.Rollout { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rollouts in namespace %q: %w", namespace, err)
+	}
+	return items, nil
+}
+
 // GetRevisionHistoryLimit returns the revisionHistoryLimit from the named
 // Rollout's spec. If the field is nil (unset), DefaultRevisionHistoryLimit is
 // returned so callers always receive a usable integer.
@@ -64,8 +92,25 @@ func (k *KubeRolloutClient) GetRevisionHistoryLimit(ctx context.Context, namespa
 		return 0, fmt.Errorf("failed to get rollout %q in namespace %q: %w", rolloutName, namespace, err)
 	}
 
-	if rollout.Spec.RevisionHistoryLimit == nil {
-		return DefaultRevisionHistoryLimit, nil
+	return revisionHistoryLimit(rollout.Spec.RevisionHistoryLimit), nil
+}
+
+// GetRolloutStatus returns the full status of the named Rollout, including
+// the canary and blue-green fields StrategyHashes reads.
+func (k *KubeRolloutClient) GetRolloutStatus(ctx context.Context, namespace, rolloutName string) (rolloutsv1alpha1.RolloutStatus, error) {
+	rollout, err := k.client.ArgoprojV1alpha1().Rollouts(namespace).Get(ctx, rolloutName, metav1.GetOptions{})
+	if err != nil {
+		return rolloutsv1alpha1.RolloutStatus{}, fmt.Errorf("failed to get rollout %q in namespace %q: %w", rolloutName, namespace, err)
+	}
+	return rollout.Status, nil
+}
+
+// revisionHistoryLimit dereferences a spec.revisionHistoryLimit field,
+// falling back to DefaultRevisionHistoryLimit when it is unset. Deployments,
+// StatefulSets and DaemonSets share the same default of 10.
+func revisionHistoryLimit(limit *int32) int {
+	if limit == nil {
+		return DefaultRevisionHistoryLimit
 	}
-	return int(*rollout.Spec.RevisionHistoryLimit), nil
+	return int(*limit)
 }
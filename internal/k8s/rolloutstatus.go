@@ -0,0 +1,150 @@
+package k8s
+
+// Rollout status pinning.
+// status.currentPodHash, status.stableRS and the blue-green
+// status.blueGreen.activeSelector / previewSelector name the pod-template-hash
+// of the ReplicaSets the Rollout strategy relies on. Those ReplicaSets are
+// normally owned by the Rollout and already listed; statusReplicaSetRevisions
+// covers the cases where they are not (yet), e.g. while the controller is
+// adopting them, by looking them up in the same ReplicaSet listing through the
+// rollouts-pod-template-hash label instead of the ownerReference.
+//
+// markStrategyRevisions flags those ReplicaSets, plus any ReplicaSet still
+// within its scaleDownDelay, so their ConfigMaps are reported as "protected by
+// rollout strategy" rather than merely in use.
+//
+// RolloutPhase reports whether the Rollout is Healthy or mid-update, so
+// callers can leave a Rollout's candidates alone until it settles.
+
+import (
+	"slices"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// StrategyHashes returns the pod-template hashes pinned by the Rollout
+// status: currentPodHash, stableRS and the blue-green active and preview
+// selectors, without blanks or duplicates.
+func StrategyHashes(r rolloutsv1alpha1.Rollout) []string {
+	var hashes []string
+	for _, h := range []string{
+		r.Status.CurrentPodHash,
+		r.Status.StableRS,
+		r.Status.BlueGreen.ActiveSelector,
+		r.Status.BlueGreen.PreviewSelector,
+	} {
+		if h != "" && !slices.Contains(hashes, h) {
+			hashes = append(hashes, h)
+		}
+	}
+	return hashes
+}
+
+// RolloutPhase returns the Rollout's status.phase and status.message. Rollouts
+// reconciled by controllers that predate status.phase report none; their
+// phase is derived from the status conditions instead.
+func RolloutPhase(r rolloutsv1alpha1.Rollout) (phase, message string) {
+	if r.Status.Phase != "" {
+		return string(r.Status.Phase), r.Status.Message
+	}
+	conditions := make(map[rolloutsv1alpha1.RolloutConditionType]rolloutsv1alpha1.RolloutCondition, len(r.Status.Conditions))
+	for _, c := range r.Status.Conditions {
+		conditions[c.Type] = c
+	}
+	if c, ok := conditions[rolloutsv1alpha1.InvalidSpec]; ok && c.Status == corev1.ConditionTrue {
+		return string(rolloutsv1alpha1.RolloutPhaseDegraded), c.Message
+	}
+	if r.Status.Abort {
+		return string(rolloutsv1alpha1.RolloutPhaseDegraded), "rollout aborted"
+	}
+	if c, ok := conditions[rolloutsv1alpha1.RolloutProgressing]; ok && c.Reason == "ProgressDeadlineExceeded" {
+		return string(rolloutsv1alpha1.RolloutPhaseDegraded), c.Message
+	}
+	if r.Spec.Paused || r.Status.ControllerPause || len(r.Status.PauseConditions) > 0 {
+		return string(rolloutsv1alpha1.RolloutPhasePaused), "rollout is paused"
+	}
+	if c, ok := conditions[rolloutsv1alpha1.RolloutHealthy]; ok && c.Status == corev1.ConditionTrue {
+		return string(rolloutsv1alpha1.RolloutPhaseHealthy), c.Message
+	}
+	return string(rolloutsv1alpha1.RolloutPhaseProgressing), "rollout status has no phase and is not healthy yet"
+}
+
+// InScaleDownDelay reports whether a ReplicaSet's scale-down-deadline
+// annotation lies after now, i.e. Argo Rollouts still keeps it running after
+// a promotion so traffic can shift back.
+func InScaleDownDelay(annotations map[string]string, now time.Time) bool {
+	raw, ok := annotations[rolloutsv1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey]
+	if !ok {
+		return false
+	}
+	deadline, err := time.Parse(time.RFC3339, raw)
+	return err == nil && deadline.After(now)
+}
+
+// markStrategyRevisions flags every ReplicaSet revision a Rollout's strategy
+// relies on: those whose rollouts-pod-template-hash label is a StrategyHashes
+// value of their owner, and those still within their scaleDownDelay.
+func markStrategyRevisions(rollouts []rolloutsv1alpha1.Rollout, revisions []TemplateRevision, now time.Time) {
+	hashes := make(map[types.UID][]string, len(rollouts))
+	for _, r := range rollouts {
+		hashes[r.UID] = StrategyHashes(r)
+	}
+	for i := range revisions {
+		rev := &revisions[i]
+		hash, ok := rev.Labels[rolloutsv1alpha1.DefaultRolloutUniqueLabelKey]
+		if ok && slices.Contains(hashes[rev.OwnerUID], hash) {
+			rev.Strategy = true
+		}
+		if InScaleDownDelay(rev.Annotations, now) {
+			rev.Strategy = true
+		}
+	}
+}
+
+// statusReplicaSetRevisions returns, for every Rollout, the ReplicaSets of
+// items whose rollouts-pod-template-hash label matches a status hash and that
+// are not already among known. A ReplicaSet controlled by a different Rollout
+// is never attributed to this one.
+func statusReplicaSetRevisions(items []appsv1.ReplicaSet, rollouts []rolloutsv1alpha1.Rollout, known []TemplateRevision) []TemplateRevision {
+	byHash := make(map[string][]appsv1.ReplicaSet)
+	for _, rs := range items {
+		hash, ok := rs.Labels[rolloutsv1alpha1.DefaultRolloutUniqueLabelKey]
+		if ok {
+			byHash[hash] = append(byHash[hash], rs)
+		}
+	}
+	seen := make(map[string]bool, len(known))
+	for _, rev := range known {
+		seen[rev.Name] = true
+	}
+
+	var revisions []TemplateRevision
+	for _, r := range rollouts {
+		for _, hash := range StrategyHashes(r) {
+			for _, rs := range byHash[hash] {
+				if seen[rs.Name] {
+					continue
+				}
+				if owner := metav1.GetControllerOf(&rs); owner != nil && owner.UID != r.UID {
+					continue
+				}
+				seen[rs.Name] = true
+				revisions = append(revisions, TemplateRevision{
+					Name:        rs.Name,
+					OwnerName:   r.Name,
+					OwnerUID:    r.UID,
+					Labels:      rs.Labels,
+					Annotations: rs.Annotations,
+					Revision:    replicaSetRevision(rs),
+					Template:    rs.Spec.Template,
+				})
+			}
+		}
+	}
+	return revisions
+}
@@ -0,0 +1,220 @@
+package k8s
+
+// Unit tests for rolloutstatus.go and the desired-template revisions: a GC run
+// during `helm upgrade` must protect the ConfigMap about to be rolled out even
+// though no ReplicaSet carries it yet.
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// withPodTemplateHash labels a ReplicaSet the way Argo Rollouts does.
+func withPodTemplateHash(rs appsv1.ReplicaSet, hash string) appsv1.ReplicaSet {
+	if rs.Labels == nil {
+		rs.Labels = map[string]string{}
+	}
+	rs.Labels[rolloutsv1alpha1.DefaultRolloutUniqueLabelKey] = hash
+	return rs
+}
+
+func TestStrategyHashes(t *testing.T) {
+	r := rolloutsv1alpha1.Rollout{}
+	assert.Empty(t, StrategyHashes(r))
+
+	r.Status.CurrentPodHash = "65df947c4c"
+	r.Status.StableRS = "65df947c4c"
+	assert.Equal(t, []string{"65df947c4c"}, StrategyHashes(r))
+
+	r.Status.StableRS = "847848bbcf"
+	r.Status.BlueGreen.ActiveSelector = "847848bbcf"
+	r.Status.BlueGreen.PreviewSelector = "6977fddb67"
+	assert.Equal(t, []string{"65df947c4c", "847848bbcf", "6977fddb67"}, StrategyHashes(r))
+}
+
+func TestInScaleDownDelay(t *testing.T) {
+	now := time.Date(2026, 2, 13, 12, 0, 0, 0, time.UTC)
+	key := rolloutsv1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey
+
+	assert.False(t, InScaleDownDelay(nil, now))
+	assert.True(t, InScaleDownDelay(map[string]string{key: "2026-02-13T12:00:30Z"}, now))
+	assert.False(t, InScaleDownDelay(map[string]string{key: "2026-02-13T11:59:30Z"}, now))
+	assert.False(t, InScaleDownDelay(map[string]string{key: "not-a-time"}, now))
+}
+
+// TestStrategyRevisions_BlueGreen verifies that the blue-green active and
+// preview ReplicaSets and a ReplicaSet within scaleDownDelay are reported as
+// strategy-protected, while an ordinary history revision is only in use.
+func TestStrategyRevisions_BlueGreen(t *testing.T) {
+	deadline := time.Now().Add(30 * time.Second).UTC().Format(time.RFC3339)
+
+	active := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"), "65df947c4c")
+	preview := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-seat-847848bbcf", testRolloutName, testRolloutUID, "b870a608"), "847848bbcf")
+	delayed := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-seat-6977fddb67", testRolloutName, testRolloutUID, "f3bca2cb"), "6977fddb67")
+	delayed.Annotations = map[string]string{rolloutsv1alpha1.DefaultReplicaSetScaleDownDeadlineAnnotationKey: deadline}
+	history := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-seat-68b7bd46c8", testRolloutName, testRolloutUID, "d5eb6ebf"), "68b7bd46c8")
+
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.UID = k8stypes.UID(testRolloutUID)
+	r.Spec.Template = preview.Spec.Template
+	r.Status.CurrentPodHash = "847848bbcf"
+	r.Status.StableRS = "65df947c4c"
+	r.Status.BlueGreen.ActiveSelector = "65df947c4c"
+	r.Status.BlueGreen.PreviewSelector = "847848bbcf"
+
+	clients := &Clients{
+		Kube:    fake.NewSimpleClientset(&active, &preview, &delayed, &history),
+		Rollout: rolloutfake.NewSimpleClientset(r),
+	}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"e6120fae": true, "b870a608": true, "f3bca2cb": true},
+		scoped.StrategyFor(ResourceConfigMap, testRolloutUID).Checksums)
+	assert.True(t, scoped.For(ResourceConfigMap, testRolloutUID).Checksums["d5eb6ebf"],
+		"history revisions stay in use without being strategy-protected")
+}
+
+func TestKubeRolloutClient_GetRolloutStatus(t *testing.T) {
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.Status.StableRS = "65df947c4c"
+	r.Status.BlueGreen.PreviewSelector = "847848bbcf"
+	client := NewKubeRolloutClient(rolloutfake.NewSimpleClientset(r))
+
+	status, err := client.GetRolloutStatus(context.Background(), testNamespace, testRolloutName)
+	require.NoError(t, err)
+	assert.Equal(t, "65df947c4c", status.StableRS)
+	assert.Equal(t, "847848bbcf", status.BlueGreen.PreviewSelector)
+
+	_, err = client.GetRolloutStatus(context.Background(), testNamespace, "missing")
+	assert.Error(t, err)
+}
+
+// TestDesiredTemplate_InUse reproduces the upgrade window: the Rollout spec
+// already carries checksum c0ffee00, but only the old ReplicaSet exists.
+func TestDesiredTemplate_InUse(t *testing.T) {
+	oldRS := makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae")
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.UID = k8stypes.UID(testRolloutUID)
+	r.Spec.Template = makeTemplate("xzk0-seat-config-c0ffee00", "c0ffee00")
+
+	clients := &Clients{
+		Kube:    fake.NewSimpleClientset(&oldRS),
+		Rollout: rolloutfake.NewSimpleClientset(r),
+	}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+
+	set := scoped.For(ResourceConfigMap, testRolloutUID)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "c0ffee00": true}, set.Checksums)
+	assert.True(t, set.Names["xzk0-seat-config-c0ffee00"])
+}
+
+// TestStatusReplicaSets_InUse verifies that ReplicaSets pinned by
+// status.stableRS / status.currentPodHash are in use even when they carry no
+// ownerReference, and that another Rollout's ReplicaSet is never attributed.
+func TestStatusReplicaSets_InUse(t *testing.T) {
+	const otherUID = "0b7a3c55-2d1e-4f7a-8c61-5a0c1f9e2b44"
+
+	orphanStable := withPodTemplateHash(makeRSNoOwner(testNamespace, "xzk0-seat-6f7d8c9b5", "d5eb6ebf"), "6f7d8c9b5")
+	otherRolloutRS := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-order-847848bbcf", "xzk0-order", otherUID, "da8762a8"), "847848bbcf")
+	owned := withPodTemplateHash(
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"), "65df947c4c")
+
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.UID = k8stypes.UID(testRolloutUID)
+	r.Spec.Template = owned.Spec.Template
+	r.Status.CurrentPodHash = "847848bbcf" // collides with the other Rollout's RS
+	r.Status.StableRS = "6f7d8c9b5"
+
+	clients := &Clients{
+		Kube:    fake.NewSimpleClientset(&orphanStable, &otherRolloutRS, &owned),
+		Rollout: rolloutfake.NewSimpleClientset(r),
+	}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	revisions, err := source.ListRevisions(context.Background(), testNamespace)
+	require.NoError(t, err)
+	owners := make(map[string]string, len(revisions))
+	for _, rev := range revisions {
+		_, dup := owners[rev.Name]
+		assert.False(t, dup, "revision %q listed twice", rev.Name)
+		owners[rev.Name] = rev.OwnerName
+	}
+	assert.Equal(t, map[string]string{
+		"xzk0-seat-65df947c4c":  testRolloutName,
+		"Rollout/xzk0-seat":     testRolloutName,
+		"xzk0-seat-6f7d8c9b5":   testRolloutName,
+		"xzk0-order-847848bbcf": "xzk0-order",
+	}, owners)
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "d5eb6ebf": true},
+		scoped.For(ResourceConfigMap, testRolloutUID).Checksums)
+}
+
+func TestRolloutPhase(t *testing.T) {
+	r := rolloutsv1alpha1.Rollout{}
+	r.Status.Phase = rolloutsv1alpha1.RolloutPhasePaused
+	r.Status.Message = "CanaryPauseStep"
+	phase, message := RolloutPhase(r)
+	assert.Equal(t, "Paused", phase)
+	assert.Equal(t, "CanaryPauseStep", message)
+
+	tests := []struct {
+		name   string
+		status rolloutsv1alpha1.RolloutStatus
+		want   string
+	}{
+		{name: "no conditions", want: "Progressing"},
+		{
+			name: "healthy condition",
+			status: rolloutsv1alpha1.RolloutStatus{Conditions: []rolloutsv1alpha1.RolloutCondition{
+				{Type: rolloutsv1alpha1.RolloutHealthy, Status: corev1.ConditionTrue},
+			}},
+			want: "Healthy",
+		},
+		{
+			name:   "pause conditions",
+			status: rolloutsv1alpha1.RolloutStatus{PauseConditions: []rolloutsv1alpha1.PauseCondition{{Reason: "CanaryPauseStep"}}},
+			want:   "Paused",
+		},
+		{
+			name: "progress deadline exceeded",
+			status: rolloutsv1alpha1.RolloutStatus{Conditions: []rolloutsv1alpha1.RolloutCondition{
+				{Type: rolloutsv1alpha1.RolloutProgressing, Status: corev1.ConditionFalse, Reason: "ProgressDeadlineExceeded"},
+			}},
+			want: "Degraded",
+		},
+		{name: "aborted", status: rolloutsv1alpha1.RolloutStatus{Abort: true}, want: "Degraded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phase, _ := RolloutPhase(rolloutsv1alpha1.Rollout{Status: tt.status})
+			assert.Equal(t, tt.want, phase)
+		})
+	}
+}
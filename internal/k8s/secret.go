@@ -0,0 +1,129 @@
+package k8s
+
+// Secret list and delete operations for versioned Secret GC.
+// Mirrors KubeConfigMapClient, but Secrets are only ever listed through the
+// metadata client: their payload is never transferred, and there is no
+// fallback to listing full Secrets. Metadata that can embed the payload is
+// dropped inside ListCandidates and never returned, wrapped into errors, or
+// logged.
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/metadata"
+)
+
+// secretsResource is the Secret resource for the metadata client and informer.
+var secretsResource = corev1.SchemeGroupVersion.WithResource("secrets")
+
+// KubeSecretClient is the production CandidateClient for Secrets, backed by a
+// real (or fake) kubernetes.Interface.
+type KubeSecretClient struct {
+	client kubernetes.Interface
+	// metadata serves ListCandidates; without it Secrets cannot be listed.
+	metadata metadata.Interface
+	scope    listScope
+}
+
+// NewKubeSecretClient creates a KubeSecretClient wrapping the provided
+// kubernetes.Interface. Pass fake.NewSimpleClientset() in tests.
+func NewKubeSecretClient(client kubernetes.Interface) *KubeSecretClient {
+	return &KubeSecretClient{client: client}
+}
+
+// WithLabelSelector restricts every list made by k to Secrets matching
+// selector and returns k.
+func (k *KubeSecretClient) WithLabelSelector(selector string) *KubeSecretClient {
+	k.scope.selector = selector
+	return k
+}
+
+// WithMetadataClient makes ListCandidates list Secret PartialObjectMetadata
+// through m, and returns k.
+func (k *KubeSecretClient) WithMetadataClient(m metadata.Interface) *KubeSecretClient {
+	k.metadata = m
+	return k
+}
+
+// WithPageSize makes every list made by k fetch at most size Secrets per
+// request (0 disables pagination) and returns k.
+func (k *KubeSecretClient) WithPageSize(size int64) *KubeSecretClient {
+	k.scope.pageSize = size
+	return k
+}
+
+// Resource returns SecretResource.
+func (k *KubeSecretClient) Resource() VersionedResource {
+	return SecretResource
+}
+
+// ListCandidates returns the metadata of every Secret in the namespace whose
+// name starts with namePrefix. Only PartialObjectMetadata is listed, so Secret
+// payloads are never fetched; it fails when k has no metadata client.
+func (k *KubeSecretClient) ListCandidates(ctx context.Context, namespace, namePrefix string) ([]metav1.ObjectMeta, error) {
+	if k.metadata == nil {
+		return nil, fmt.Errorf("failed to list secrets in namespace %q: no metadata client configured", namespace)
+	}
+	items, err := listAll(ctx, secretsResource.Resource, k.scope, k.metadata.Resource(secretsResource).Namespace(namespace).List,
+		func(l *metav1.PartialObjectMetadataList) []metav1.PartialObjectMetadata { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list secret metadata in namespace %q: %w", namespace, err)
+	}
+
+	var matched []metav1.ObjectMeta
+	for _, item := range items {
+		if strings.HasPrefix(item.Name, namePrefix) {
+			matched = append(matched, redactSecretMeta(item.ObjectMeta))
+		}
+	}
+	return matched, nil
+}
+
+// redactSecretMeta drops metadata that can embed the Secret payload:
+// kubectl's last-applied-configuration annotation holds the full manifest,
+// including data, and managedFields are not needed for GC decisions.
+func redactSecretMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	meta.ManagedFields = nil
+	if _, ok := meta.Annotations[corev1.LastAppliedConfigAnnotation]; ok {
+		annotations := make(map[string]string, len(meta.Annotations)-1)
+		for k, v := range meta.Annotations {
+			if k != corev1.LastAppliedConfigAnnotation {
+				annotations[k] = v
+			}
+		}
+		meta.Annotations = annotations
+	}
+	return meta
+}
+
+// CandidateSizes returns the size of the data of every Secret in the
+// namespace, by name; only the sizes leave this function.
+func (k *KubeSecretClient) CandidateSizes(ctx context.Context, namespace string) (map[string]int64, error) {
+	sizes, err := listAll(ctx, "secrets", k.scope, k.client.CoreV1().Secrets(namespace).List,
+		func(l *corev1.SecretList) []objectSize {
+			sizes := make([]objectSize, 0, len(l.Items))
+			for _, secret := range l.Items {
+				sizes = append(sizes, objectSize{name: secret.Name, size: payloadSize(secret.Data)})
+			}
+			return sizes
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to size secrets in namespace %q: %w", namespace, err)
+	}
+	return sizesByName(sizes), nil
+}
+
+// DeleteCandidate deletes the named Secret from the given namespace.
+// The caller is responsible for enforcing dry-run logic.
+func (k *KubeSecretClient) DeleteCandidate(ctx context.Context, namespace, name string) error {
+	err := k.client.CoreV1().Secrets(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to delete secret %q in namespace %q: %w", name, namespace, err)
+	}
+	return nil
+}
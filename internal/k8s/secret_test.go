@@ -0,0 +1,145 @@
+package k8s
+
+// Unit tests for KubeSecretClient and the Secret side of the candidate
+// pipeline, using fake.NewSimpleClientset().
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// makeSecret builds a Secret carrying a payload, so tests can assert that the
+// payload never leaves the client.
+func makeSecret(namespace, name string, annotations map[string]string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   namespace,
+			Name:        name,
+			Annotations: annotations,
+		},
+		Data: map[string][]byte{"password": []byte("hunter2")},
+	}
+}
+
+// makeSecretMetadata builds the PartialObjectMetadata the metadata client
+// returns for a Secret.
+func makeSecretMetadata(namespace, name string, annotations map[string]string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Annotations: annotations},
+	}
+}
+
+// TestKubeSecretClient_ListCandidates verifies that Secrets are listed as
+// PartialObjectMetadata only — the typed Secret API, which would transfer the
+// payload, is never called — and that last-applied-configuration is dropped.
+func TestKubeSecretClient_ListCandidates(t *testing.T) {
+	scheme := metadatafake.NewTestScheme()
+	require.NoError(t, metav1.AddMetaToScheme(scheme))
+	meta := metadatafake.NewSimpleMetadataClient(scheme,
+		makeSecretMetadata(testNamespace, "xzk0-seat-secret-e6120fae", map[string]string{
+			"gc.k8s.io/protect":                "true",
+			corev1.LastAppliedConfigAnnotation: `{"data":{"password":"aHVudGVyMg=="}}`,
+		}),
+		makeSecretMetadata(testNamespace, "xzk0-seat-secret-b870a608", nil),
+		makeSecretMetadata(testNamespace, "other-app-secret-abc123", nil),
+		makeSecretMetadata("other-ns", "xzk0-seat-secret-da8762a8", nil),
+	)
+	kube := fake.NewSimpleClientset()
+	client := NewKubeSecretClient(kube).WithMetadataClient(meta)
+
+	got, err := client.ListCandidates(context.Background(), testNamespace, "xzk0-seat-secret-")
+	require.NoError(t, err)
+
+	byName := make(map[string]metav1.ObjectMeta, len(got))
+	for _, meta := range got {
+		byName[meta.Name] = meta
+		assert.NotContains(t, meta.Annotations, corev1.LastAppliedConfigAnnotation,
+			"last-applied-configuration embeds the Secret payload and must be dropped")
+	}
+	assert.ElementsMatch(t, []string{"xzk0-seat-secret-e6120fae", "xzk0-seat-secret-b870a608"}, mapKeysOf(byName))
+	assert.Equal(t, "true", byName["xzk0-seat-secret-e6120fae"].Annotations["gc.k8s.io/protect"])
+	assert.Empty(t, kube.Actions(), "the typed Secret API must not be used")
+}
+
+// TestKubeSecretClient_ListCandidates_WithoutMetadataClient verifies that
+// without a metadata client listing fails instead of fetching full Secrets.
+func TestKubeSecretClient_ListCandidates_WithoutMetadataClient(t *testing.T) {
+	kube := fake.NewSimpleClientset(makeSecret(testNamespace, "xzk0-seat-secret-e6120fae", nil))
+
+	_, err := NewKubeSecretClient(kube).ListCandidates(context.Background(), testNamespace, "")
+	assert.Error(t, err)
+	assert.Empty(t, kube.Actions())
+}
+
+func TestKubeSecretClient_DeleteCandidate(t *testing.T) {
+	fakeClient := fake.NewSimpleClientset(
+		makeSecret(testNamespace, "xzk0-seat-secret-da8762a8", nil),
+		makeSecret(testNamespace, "xzk0-seat-secret-e6120fae", nil),
+	)
+	client := NewKubeSecretClient(fakeClient)
+
+	require.NoError(t, client.DeleteCandidate(context.Background(), testNamespace, "xzk0-seat-secret-da8762a8"))
+	assert.Error(t, client.DeleteCandidate(context.Background(), testNamespace, "xzk0-seat-secret-missing"))
+
+	remaining, err := fakeClient.CoreV1().Secrets(testNamespace).List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, remaining.Items, 1)
+	assert.Equal(t, "xzk0-seat-secret-e6120fae", remaining.Items[0].Name)
+}
+
+func TestKubeSecretClient_CandidateSizes(t *testing.T) {
+	kube := fake.NewSimpleClientset(makeSecret(testNamespace, "xzk0-seat-secret-da8762a8", nil))
+	client := NewKubeSecretClient(kube)
+
+	sizes, err := client.CandidateSizes(context.Background(), testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"xzk0-seat-secret-da8762a8": int64(len("password") + len("hunter2"))}, sizes)
+	assert.Len(t, kube.Actions(), 1, "one List sizes the namespace")
+
+	kube.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	_, err = client.CandidateSizes(context.Background(), testNamespace)
+	assert.Error(t, err)
+}
+
+func TestNewCandidateClient(t *testing.T) {
+	clients := &Clients{Kube: fake.NewSimpleClientset()}
+
+	cmClient, err := NewCandidateClient(ResourceConfigMap, clients)
+	require.NoError(t, err)
+	assert.Equal(t, ConfigMapResource.Kind, cmClient.Resource().Kind)
+
+	secretClient, err := NewCandidateClient(ResourceSecret, clients)
+	require.NoError(t, err)
+	assert.Equal(t, AnnotationChecksumSecret, secretClient.Resource().ChecksumAnnotation)
+
+	_, err = NewCandidateClient("PersistentVolumeClaim", clients)
+	assert.Error(t, err)
+}
+
+// TestCandidateClients_ImplementInterface is a compile-time check.
+func TestCandidateClients_ImplementInterface(t *testing.T) {
+	var _ CandidateClient = (*KubeConfigMapClient)(nil)
+	var _ CandidateClient = (*KubeSecretClient)(nil)
+}
+
+// mapKeysOf returns the keys of a map keyed by object name.
+func mapKeysOf(m map[string]metav1.ObjectMeta) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
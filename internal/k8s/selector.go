@@ -0,0 +1,24 @@
+package k8s
+
+// Label selector scoping.
+// Clients.LabelSelector (config APP_LABEL) restricts every list of workloads
+// and candidates to matching objects. The selector is evaluated server-side
+// through metav1.ListOptions.LabelSelector, so objects of other apps never
+// leave the API server. Revisions (ReplicaSets, ControllerRevisions,
+// Experiments, AnalysisRuns) are never filtered: one missing the label would
+// otherwise leave the objects it references unprotected.
+
+import (
+	"strings"
+)
+
+// joinSelectors ANDs label selectors, skipping empty ones.
+func joinSelectors(selectors ...string) string {
+	var parts []string
+	for _, s := range selectors {
+		if s != "" {
+			parts = append(parts, s)
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,80 @@
+package k8s
+
+// Unit tests for selector.go: Clients.LabelSelector scopes workloads and
+// candidates server-side, never revisions.
+
+import (
+	"context"
+	"testing"
+
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestJoinSelectors(t *testing.T) {
+	assert.Equal(t, "", joinSelectors())
+	assert.Equal(t, "app=seat", joinSelectors("", "app=seat", ""))
+	assert.Equal(t, "app in (seat,order),rollouts-pod-template-hash",
+		joinSelectors("app in (seat,order)", "rollouts-pod-template-hash"))
+}
+
+// TestLabelSelector_ScopesLists verifies that only the selected app's Rollout
+// and ConfigMaps are returned when a selector is configured, while every
+// ReplicaSet is: one missing the label still protects what it references.
+func TestLabelSelector_ScopesLists(t *testing.T) {
+	const orderUID = "0b7a3c55-2d1e-4f7a-8c61-5a0c1f9e2b44"
+
+	seat := makeRollout(testNamespace, testRolloutName, nil)
+	seat.UID = k8stypes.UID(testRolloutUID)
+	seat.Labels = map[string]string{"app": "seat"}
+	order := makeRollout(testNamespace, "xzk0-order", nil)
+	order.UID = k8stypes.UID(orderUID)
+	order.Labels = map[string]string{"app": "order"}
+
+	seatRS := makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae")
+	seatRS.Labels = map[string]string{"app": "seat"}
+	unlabelledRS := makeRS(testNamespace, "xzk0-seat-847848bbcf", testRolloutName, testRolloutUID, "b870a608")
+	orderRS := makeRS(testNamespace, "xzk0-order-847848bbcf", "xzk0-order", orderUID, "da8762a8")
+	orderRS.Labels = map[string]string{"app": "order"}
+
+	seatCM := makeConfigMap(testNamespace, "xzk0-seat-config-e6120fae", nil)
+	seatCM.Labels = map[string]string{"app": "seat"}
+	unlabelledCM := makeConfigMap(testNamespace, "xzk0-seat-config-b870a608", nil)
+
+	clients := &Clients{
+		Kube:          fake.NewSimpleClientset(&seatRS, &unlabelledRS, &orderRS, &seatCM, &unlabelledCM),
+		Rollout:       rolloutfake.NewSimpleClientset(seat, order),
+		LabelSelector: "app=seat",
+	}
+
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+	workloads, err := source.ListWorkloads(context.Background(), testNamespace)
+	require.NoError(t, err)
+	require.Len(t, workloads, 1)
+	assert.Equal(t, testRolloutName, workloads[0].Name)
+
+	revisions, err := source.ListRevisions(context.Background(), testNamespace)
+	require.NoError(t, err)
+	var names []string
+	for _, rev := range revisions {
+		names = append(names, rev.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"xzk0-seat-65df947c4c", "xzk0-seat-847848bbcf", "xzk0-order-847848bbcf", KindRollout + "/" + testRolloutName,
+	}, names)
+	sets, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	assert.True(t, sets.For(ResourceConfigMap, testRolloutUID).Checksums["b870a608"],
+		"the unlabelled ReplicaSet protects its checksum")
+
+	client, err := NewCandidateClient(ResourceConfigMap, clients)
+	require.NoError(t, err)
+	candidates, err := client.ListCandidates(context.Background(), testNamespace, "xzk0-seat-config-")
+	require.NoError(t, err)
+	require.Len(t, candidates, 1)
+	assert.Equal(t, "xzk0-seat-config-e6120fae", candidates[0].Name)
+}
@@ -0,0 +1,91 @@
+package k8s
+
+// Namespace snapshots.
+// A GC cycle reads a namespace exactly once: every workload source lists its
+// workloads and retained revisions in one pass, and every candidate client
+// lists the metadata of all of its objects in the namespace once. Planning
+// then reads only from the snapshot — families look their candidates up in an
+// in-memory index by name prefix instead of re-listing the namespace, so the
+// number of API calls per cycle no longer grows with the number of workloads.
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Snapshot holds everything one GC cycle reads from a namespace.
+type Snapshot struct {
+	Namespace string
+	// Workloads are the workloads of every source, in source order.
+	Workloads []Workload
+	// Revisions are the pod-template revisions every source retains.
+	Revisions []TemplateRevision
+	// candidates holds the metadata of every candidate of each resource kind,
+	// sorted by name.
+	candidates map[string][]metav1.ObjectMeta
+}
+
+// TakeSnapshot lists the workloads and revisions of every source, then the
+// candidates of every client, in namespace. Candidates are not listed when the
+// namespace has no workloads: nothing could own them.
+func TakeSnapshot(ctx context.Context, namespace string, sources []WorkloadSource, clients []CandidateClient) (*Snapshot, error) {
+	snap := &Snapshot{
+		Namespace:  namespace,
+		candidates: make(map[string][]metav1.ObjectMeta, len(clients)),
+	}
+	for _, source := range sources {
+		workloads, revisions, err := source.ListWorkloadRevisions(ctx, namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s workloads in namespace %q: %w", source.Kind(), namespace, err)
+		}
+		snap.Workloads = append(snap.Workloads, workloads...)
+		snap.Revisions = append(snap.Revisions, revisions...)
+	}
+	if len(snap.Workloads) == 0 {
+		return snap, nil
+	}
+	for _, client := range clients {
+		kind := client.Resource().Kind
+		objects, err := client.ListCandidates(ctx, namespace, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s candidates in namespace %q: %w", kind, namespace, err)
+		}
+		sort.Slice(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+		snap.candidates[kind] = objects
+	}
+	return snap, nil
+}
+
+// WorkloadsOfKind returns the snapshot's workloads of one kind.
+func (s *Snapshot) WorkloadsOfKind(kind string) []Workload {
+	var workloads []Workload
+	for _, w := range s.Workloads {
+		if w.Kind == kind {
+			workloads = append(workloads, w)
+		}
+	}
+	return workloads
+}
+
+// InUse groups the snapshot's revisions by owning workload; see
+// InUseResolver.ResolveScoped.
+func (s *Snapshot) InUse(resources ...VersionedResource) ScopedInUseSets {
+	return ScopeRevisions(s.Revisions, resources...)
+}
+
+// Candidates returns the metadata of the candidates of one resource kind whose
+// name starts with prefix, sorted by name. The result shares the snapshot's
+// backing array and must not be modified.
+func (s *Snapshot) Candidates(kind, prefix string) []metav1.ObjectMeta {
+	objects := s.candidates[kind]
+	start := sort.Search(len(objects), func(i int) bool { return objects[i].Name >= prefix })
+	end := start
+	for end < len(objects) && strings.HasPrefix(objects[end].Name, prefix) {
+		end++
+	}
+	return objects[start:end:end]
+}
@@ -0,0 +1,178 @@
+package k8s
+
+// Unit tests and benchmark for snapshot.go: one namespace snapshot replaces
+// the per-family candidate listings, so API calls per cycle stay constant as
+// the number of Rollouts grows.
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// snapshotFixture builds a namespace with the given number of Rollouts, each
+// with two ReplicaSets and three ConfigMaps "<rollout>-config-<hash8>".
+func snapshotFixture(rollouts int) (*fake.Clientset, *rolloutfake.Clientset) {
+	var kubeObjs, rolloutObjs []runtime.Object
+	for i := 0; i < rollouts; i++ {
+		name := fmt.Sprintf("app-%03d", i)
+		uid := fmt.Sprintf("uid-%03d", i)
+		r := makeRollout(testNamespace, name, nil)
+		r.UID = k8stypes.UID(uid)
+		rolloutObjs = append(rolloutObjs, r)
+		for j := 0; j < 3; j++ {
+			hash := fmt.Sprintf("%08x", i*3+j)
+			cm := makeConfigMap(testNamespace, name+"-config-"+hash, nil)
+			kubeObjs = append(kubeObjs, &cm)
+			if j < 2 {
+				rs := withConfigMapVolume(makeRS(testNamespace, fmt.Sprintf("%s-%d", name, j), name, uid, hash), cm.Name)
+				kubeObjs = append(kubeObjs, &rs)
+			}
+		}
+	}
+	return fake.NewSimpleClientset(kubeObjs...), rolloutfake.NewSimpleClientset(rolloutObjs...)
+}
+
+// apiCalls returns the number of requests both fake clientsets received and
+// forgets them.
+func apiCalls(kube *fake.Clientset, rollout *rolloutfake.Clientset) int {
+	n := len(kube.Actions()) + len(rollout.Actions())
+	kube.ClearActions()
+	rollout.ClearActions()
+	return n
+}
+
+// perFamilyCycle reads a namespace the way a cycle did before snapshots:
+// workloads and revisions separately, then one candidate listing per family.
+func perFamilyCycle(ctx context.Context, source WorkloadSource, client CandidateClient) error {
+	workloads, err := source.ListWorkloads(ctx, testNamespace)
+	if err != nil {
+		return err
+	}
+	if _, err := NewWorkloadInUseResolver(source).ResolveScoped(ctx, testNamespace, ConfigMapResource); err != nil {
+		return err
+	}
+	for _, w := range workloads {
+		if _, err := client.ListCandidates(ctx, testNamespace, w.Name+"-config-"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotCycle reads a namespace through one snapshot.
+func snapshotCycle(ctx context.Context, source WorkloadSource, client CandidateClient) error {
+	snap, err := TakeSnapshot(ctx, testNamespace, []WorkloadSource{source}, []CandidateClient{client})
+	if err != nil {
+		return err
+	}
+	snap.InUse(ConfigMapResource)
+	for _, w := range snap.Workloads {
+		snap.Candidates(ResourceConfigMap, w.Name+"-config-")
+	}
+	return nil
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	kube, rollout := snapshotFixture(3)
+	clients := &Clients{Kube: kube, Rollout: rollout}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+	client, err := NewCandidateClient(ResourceConfigMap, clients)
+	require.NoError(t, err)
+
+	snap, err := TakeSnapshot(context.Background(), testNamespace, []WorkloadSource{source}, []CandidateClient{client})
+	require.NoError(t, err)
+
+	assert.Len(t, snap.Workloads, 3)
+	assert.Len(t, snap.WorkloadsOfKind(KindRollout), 3)
+	assert.Empty(t, snap.WorkloadsOfKind(KindDeployment))
+
+	var names []string
+	for _, obj := range snap.Candidates(ResourceConfigMap, "app-001-config-") {
+		names = append(names, obj.Name)
+	}
+	assert.Equal(t, []string{"app-001-config-00000003", "app-001-config-00000004", "app-001-config-00000005"}, names)
+	assert.Empty(t, snap.Candidates(ResourceConfigMap, "missing-"))
+	assert.Empty(t, snap.Candidates(ResourceSecret, "app-001-"), "kinds without a client have no candidates")
+
+	inUse := snap.InUse(ConfigMapResource).For(ResourceConfigMap, "uid-001")
+	assert.True(t, inUse.Names["app-001-config-00000003"])
+	assert.True(t, inUse.Names["app-001-config-00000004"])
+	assert.True(t, inUse.Names["app-000-config-00000000"], "name references are namespace-wide")
+	assert.False(t, inUse.Names["app-001-config-00000005"])
+
+	// Rollouts, ReplicaSets, ConfigMaps, Experiments, AnalysisRuns: one list each.
+	assert.Equal(t, 5, apiCalls(kube, rollout))
+}
+
+// TestTakeSnapshot_NoWorkloads verifies that candidates are not listed for a
+// namespace without workloads.
+func TestTakeSnapshot_NoWorkloads(t *testing.T) {
+	kube := fake.NewSimpleClientset()
+	clients := &Clients{Kube: kube, Rollout: rolloutfake.NewSimpleClientset()}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+	client, err := NewCandidateClient(ResourceConfigMap, clients)
+	require.NoError(t, err)
+
+	snap, err := TakeSnapshot(context.Background(), testNamespace, []WorkloadSource{source}, []CandidateClient{client})
+	require.NoError(t, err)
+	assert.Empty(t, snap.Workloads)
+	for _, action := range kube.Actions() {
+		assert.NotEqual(t, "configmaps", action.GetResource().Resource)
+	}
+}
+
+// TestSnapshot_CandidatesArePrefixRange verifies the prefix index does not
+// stop at or skip names sorting next to the prefix.
+func TestSnapshot_CandidatesArePrefixRange(t *testing.T) {
+	snap := &Snapshot{candidates: map[string][]metav1.ObjectMeta{
+		ResourceConfigMap: {{Name: "foo"}, {Name: "foo-bar-1"}, {Name: "foo-config-1"}, {Name: "foo-config-2"}, {Name: "foo-configx"}, {Name: "fop"}},
+	}}
+	var names []string
+	for _, obj := range snap.Candidates(ResourceConfigMap, "foo-config") {
+		names = append(names, obj.Name)
+	}
+	assert.Equal(t, []string{"foo-config-1", "foo-config-2", "foo-configx"}, names)
+}
+
+// BenchmarkNamespaceCycle compares the API calls needed to read a namespace
+// with per-family candidate listings against a single snapshot. Run with
+// -bench NamespaceCycle and compare the api-calls/op metric: per-family grows
+// with the number of Rollouts, snapshot stays constant.
+func BenchmarkNamespaceCycle(b *testing.B) {
+	cycles := map[string]func(context.Context, WorkloadSource, CandidateClient) error{
+		"per-family": perFamilyCycle,
+		"snapshot":   snapshotCycle,
+	}
+	for _, rollouts := range []int{10, 50} {
+		for _, mode := range []string{"per-family", "snapshot"} {
+			b.Run(fmt.Sprintf("%s/rollouts=%d", mode, rollouts), func(b *testing.B) {
+				kube, rollout := snapshotFixture(rollouts)
+				clients := &Clients{Kube: kube, Rollout: rollout}
+				source, err := NewWorkloadSource(KindRollout, clients)
+				require.NoError(b, err)
+				client, err := NewCandidateClient(ResourceConfigMap, clients)
+				require.NoError(b, err)
+				apiCalls(kube, rollout)
+
+				calls := 0
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					require.NoError(b, cycles[mode](context.Background(), source, client))
+					calls += apiCalls(kube, rollout)
+				}
+				b.ReportMetric(float64(calls)/float64(b.N), "api-calls/op")
+			})
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package k8s
+
+// Workload sources: the controllers whose pod templates consume versioned
+// ConfigMaps. Each supported kind lists its workloads (used to derive the
+// ConfigMap name prefix) and the pod-template revisions it retains (used to
+// build the in-use set):
+//   - Rollout     → ReplicaSets owned by kind=Rollout, plus the Deployment
+//     template referenced by spec.workloadRef and the templates of its
+//     Experiments and AnalysisRuns
+//   - Deployment  → ReplicaSets owned by kind=Deployment
+//   - StatefulSet → ControllerRevisions owned by kind=StatefulSet
+//   - DaemonSet   → ControllerRevisions owned by kind=DaemonSet
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Supported workload kinds, matching the ownerReference kind of their revisions.
+const (
+	KindRollout     = "Rollout"
+	KindDeployment  = "Deployment"
+	KindStatefulSet = "StatefulSet"
+	KindDaemonSet   = "DaemonSet"
+)
+
+// Workload identifies a single controller object that owns pod templates.
+type Workload struct {
+	Kind string
+	Name string
+	UID  types.UID
+	// Labels and Annotations are those of the workload object itself.
+	Labels      map[string]string
+	Annotations map[string]string
+	// RevisionHistoryLimit is spec.revisionHistoryLimit, or
+	// DefaultRevisionHistoryLimit when unset.
+	RevisionHistoryLimit int
+	// Template is the desired pod template (spec.template).
+	Template corev1.PodTemplateSpec
+	// Phase and Message are the Rollout status phase (Healthy, Progressing,
+	// Paused, Degraded) and its explanation; empty for other kinds, which
+	// have no phase.
+	Phase   string
+	Message string
+	// TemplateError is set when a Rollout's spec.workloadRef could not be
+	// resolved; Template is then empty and the Rollout must be held back,
+	// since the objects its desired template references are unknown.
+	TemplateError error
+	// RevisionError is set when a revision the workload retains could not be
+	// decoded; that revision is skipped, and the workload must be held back
+	// since the objects it references are unknown.
+	RevisionError error
+}
+
+// TemplateRevision is one pod template retained by a workload controller —
+// a ReplicaSet for Rollouts and Deployments, a ControllerRevision for
+// StatefulSets and DaemonSets.
+type TemplateRevision struct {
+	// Name is the ReplicaSet or ControllerRevision name, "<Kind>/<name>" of
+	// the workload for its desired template, or "<Kind>/<name>/<template>" for
+	// an Experiment or AnalysisRun template.
+	Name string
+	// OwnerName and OwnerUID identify the workload that retains this revision,
+	// taken from its ownerReference.
+	OwnerName string
+	OwnerUID  types.UID
+	// Labels and Annotations are those of the ReplicaSet or ControllerRevision
+	// itself (not of its pod template); nil for desired templates.
+	Labels      map[string]string
+	Annotations map[string]string
+	// Strategy is set when the Rollout strategy still relies on this revision:
+	// stable, canary, blue-green active/preview, within scaleDownDelay, or a
+	// running Experiment or AnalysisRun.
+	Strategy bool
+	// Shared is set for a running Experiment or AnalysisRun not started by a
+	// Rollout: its Rollout is unknown, so it protects the candidates of every
+	// workload in the namespace.
+	Shared bool
+	// Revision is the ReplicaSet's revision annotation or the
+	// ControllerRevision's .revision; 0 for desired, Experiment and
+	// AnalysisRun templates, which are not part of the revision history.
+	Revision int64
+	Template corev1.PodTemplateSpec
+	// TemplateError is set when Template could not be decoded from a
+	// ControllerRevision; Template is then empty.
+	TemplateError error
+}
+
+// RevisionLister lists every pod-template revision retained by one workload
+// kind in a namespace.
+type RevisionLister interface {
+	ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error)
+}
+
+// workloadLister is the ListWorkloads half of WorkloadSource.
+type workloadLister interface {
+	ListWorkloads(ctx context.Context, namespace string) ([]Workload, error)
+}
+
+// WorkloadSource lists the workloads of one kind together with the
+// pod-template revisions they retain.
+type WorkloadSource interface {
+	RevisionLister
+	Kind() string
+	ListWorkloads(ctx context.Context, namespace string) ([]Workload, error)
+
+	// ListWorkloadRevisions returns both in one pass, listing each API
+	// resource once; ListRevisions is its second result.
+	ListWorkloadRevisions(ctx context.Context, namespace string) ([]Workload, []TemplateRevision, error)
+}
+
+// NewWorkloadSource returns the WorkloadSource for the given kind, backed by
+// the provided clients. Workloads are listed with clients.LabelSelector and
+// revisions in full, clients.PageSize at a time. Returns an error for
+// unsupported kinds.
+func NewWorkloadSource(kind string, clients *Clients) (WorkloadSource, error) {
+	scope, revisionScope := clients.scope(), clients.revisionScope()
+	switch kind {
+	case KindRollout:
+		return NewRolloutSource(
+			&KubeRolloutClient{client: clients.Rollout, scope: scope},
+			&KubeReplicaSetClient{client: clients.Kube, scope: revisionScope},
+			&KubeDeploymentClient{client: clients.Kube},
+			&KubeAnalysisClient{client: clients.Rollout, scope: revisionScope},
+		), nil
+	case KindDeployment:
+		return &deploymentSource{
+			client:              clients.Kube,
+			scope:               scope,
+			replicaSetRevisions: replicaSetRevisions{ownerKind: KindDeployment, client: &KubeReplicaSetClient{client: clients.Kube, scope: revisionScope}},
+		}, nil
+	case KindStatefulSet:
+		return &statefulSetSource{
+			client:                      clients.Kube,
+			scope:                       scope,
+			controllerRevisionRevisions: controllerRevisionRevisions{ownerKind: KindStatefulSet, client: &KubeControllerRevisionClient{client: clients.Kube, scope: revisionScope}},
+		}, nil
+	case KindDaemonSet:
+		return &daemonSetSource{
+			client:                      clients.Kube,
+			scope:                       scope,
+			controllerRevisionRevisions: controllerRevisionRevisions{ownerKind: KindDaemonSet, client: &KubeControllerRevisionClient{client: clients.Kube, scope: revisionScope}},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+}
+
+// NewRolloutSource returns the Rollout WorkloadSource listing Rollouts,
+// ReplicaSets, Experiments and AnalysisRuns and resolving spec.workloadRef
+// through the given listers — e.g. backed by informer caches.
+func NewRolloutSource(rollouts RolloutLister, replicaSets ReplicaSetLister, deployments DeploymentGetter, analysis AnalysisLister) WorkloadSource {
+	return &rolloutSource{
+		rollouts:    rollouts,
+		replicaSets: replicaSets,
+		deployments: deployments,
+		analysis:    analysisRevisions{client: analysis, now: time.Now},
+	}
+}
+
+// NewWorkloadSources returns one WorkloadSource per kind, in the given order.
+func NewWorkloadSources(kinds []string, clients *Clients) ([]WorkloadSource, error) {
+	sources := make([]WorkloadSource, 0, len(kinds))
+	for _, kind := range kinds {
+		source, err := NewWorkloadSource(kind, clients)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// ─── Revision listers ────────────────────────────────────────────────────────
+
+// replicaSetRevisions lists ReplicaSets owned by ownerKind as template revisions.
+type replicaSetRevisions struct {
+	ownerKind string
+	client    ReplicaSetLister
+}
+
+func (r replicaSetRevisions) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	rsList, err := r.client.ListNamespaceOwnedReplicaSets(ctx, namespace, r.ownerKind)
+	if err != nil {
+		return nil, err
+	}
+	return ownedReplicaSetRevisions(rsList, r.ownerKind), nil
+}
+
+// ownedReplicaSetRevisions returns the ReplicaSets of rsList owned by
+// ownerKind as template revisions.
+func ownedReplicaSetRevisions(rsList []appsv1.ReplicaSet, ownerKind string) []TemplateRevision {
+	revisions := make([]TemplateRevision, 0, len(rsList))
+	for _, rs := range rsList {
+		owner, ok := ownerOfKind(rs.OwnerReferences, ownerKind)
+		if !ok {
+			continue
+		}
+		revisions = append(revisions, TemplateRevision{
+			Name:        rs.Name,
+			OwnerName:   owner.Name,
+			OwnerUID:    owner.UID,
+			Labels:      rs.Labels,
+			Annotations: rs.Annotations,
+			Revision:    replicaSetRevision(rs),
+			Template:    rs.Spec.Template,
+		})
+	}
+	return revisions
+}
+
+// replicaSetRevision returns the revision number the owning Rollout or
+// Deployment gave rs, or 0 when it carries none.
+func replicaSetRevision(rs appsv1.ReplicaSet) int64 {
+	for _, key := range []string{AnnotationRolloutRevision, AnnotationDeploymentRevision} {
+		if revision, err := strconv.ParseInt(rs.Annotations[key], 10, 64); err == nil {
+			return revision
+		}
+	}
+	return 0
+}
+
+// controllerRevisionRevisions lists ControllerRevisions owned by ownerKind as
+// template revisions.
+type controllerRevisionRevisions struct {
+	ownerKind string
+	client    ControllerRevisionLister
+}
+
+func (r controllerRevisionRevisions) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	crList, err := r.client.ListNamespaceOwnedControllerRevisions(ctx, namespace, r.ownerKind)
+	if err != nil {
+		return nil, err
+	}
+	// A ControllerRevision that fails to decode is returned with
+	// TemplateError set rather than failing the whole namespace.
+	revisions := make([]TemplateRevision, 0, len(crList))
+	for _, cr := range crList {
+		tpl, err := ExtractControllerRevisionTemplate(cr)
+		owner, _ := ownerOfKind(cr.OwnerReferences, r.ownerKind)
+		revisions = append(revisions, TemplateRevision{
+			Name:          cr.Name,
+			OwnerName:     owner.Name,
+			OwnerUID:      owner.UID,
+			Labels:        cr.Labels,
+			Annotations:   cr.Annotations,
+			Revision:      cr.Revision,
+			Template:      tpl,
+			TemplateError: err,
+		})
+	}
+	return revisions, nil
+}
+
+// desiredRevision returns the workload's desired pod template as a revision.
+// It covers the window during an upgrade in which spec.template already
+// carries a new checksum but no ReplicaSet or ControllerRevision exists yet.
+func desiredRevision(w Workload) TemplateRevision {
+	return TemplateRevision{
+		Name:      w.Kind + "/" + w.Name,
+		OwnerName: w.Name,
+		OwnerUID:  w.UID,
+		Template:  w.Template,
+	}
+}
+
+// listWithDesired returns the workloads of one kind, and the revisions they
+// retain followed by one desired-template revision per workload. A retained
+// revision whose template could not be decoded is dropped, and its owner is
+// returned with RevisionError set.
+func listWithDesired(ctx context.Context, namespace string, workloads workloadLister, retained RevisionLister) ([]Workload, []TemplateRevision, error) {
+	retainedList, err := retained.ListRevisions(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	list, err := workloads.ListWorkloads(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	undecoded := make(map[types.UID]error)
+	revisions := make([]TemplateRevision, 0, len(retainedList)+len(list))
+	for _, rev := range retainedList {
+		if rev.TemplateError != nil {
+			undecoded[rev.OwnerUID] = errors.Join(undecoded[rev.OwnerUID], rev.TemplateError)
+			continue
+		}
+		revisions = append(revisions, rev)
+	}
+	for i, w := range list {
+		list[i].RevisionError = undecoded[w.UID]
+		revisions = append(revisions, desiredRevision(w))
+	}
+	return list, revisions, nil
+}
+
+// RetainedRevisions returns the revisions that make up each workload's
+// revision history: its revisionHistoryLimit+1 highest-numbered revisions —
+// the current one and those `kubectl argo rollouts undo` can return to —
+// plus its desired template, the revisions its strategy relies on, and every
+// revision without a number. Revisions of other owners are returned as is.
+func RetainedRevisions(workloads []Workload, revisions []TemplateRevision) []TemplateRevision {
+	limits := make(map[types.UID]int, len(workloads))
+	for _, w := range workloads {
+		limits[w.UID] = w.RevisionHistoryLimit + 1
+	}
+	numbered := make(map[types.UID][]int64)
+	for _, rev := range revisions {
+		if _, ok := limits[rev.OwnerUID]; ok && rev.Revision > 0 {
+			numbered[rev.OwnerUID] = append(numbered[rev.OwnerUID], rev.Revision)
+		}
+	}
+	// oldest holds the lowest revision number each workload still retains.
+	oldest := make(map[types.UID]int64, len(numbered))
+	for uid, numbers := range numbered {
+		slices.Sort(numbers)
+		numbers = slices.Compact(numbers)
+		oldest[uid] = numbers[max(0, len(numbers)-limits[uid])]
+	}
+	retained := make([]TemplateRevision, 0, len(revisions))
+	for _, rev := range revisions {
+		if rev.Revision > 0 && !rev.Strategy && rev.Revision < oldest[rev.OwnerUID] {
+			continue
+		}
+		retained = append(retained, rev)
+	}
+	return retained
+}
+
+// secondOf returns the revisions of a ListWorkloadRevisions result.
+func secondOf(_ []Workload, revisions []TemplateRevision, err error) ([]TemplateRevision, error) {
+	return revisions, err
+}
+
+// ─── Workload sources ────────────────────────────────────────────────────────
+
+type rolloutSource struct {
+	rollouts    RolloutLister
+	replicaSets ReplicaSetLister
+	deployments DeploymentGetter
+	analysis    analysisRevisions
+}
+
+func (s *rolloutSource) Kind() string { return KindRollout }
+
+func (s *rolloutSource) ListWorkloads(ctx context.Context, namespace string) ([]Workload, error) {
+	list, err := s.rollouts.ListRollouts(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return s.workloads(ctx, list), nil
+}
+
+// workloads converts listed Rollouts to Workloads, resolving spec.workloadRef.
+// A Rollout whose workloadRef cannot be resolved is returned with
+// TemplateError set rather than failing the whole namespace.
+func (s *rolloutSource) workloads(ctx context.Context, list []rolloutsv1alpha1.Rollout) []Workload {
+	workloads := make([]Workload, 0, len(list))
+	for _, r := range list {
+		tpl, err := RolloutTemplate(ctx, s.deployments, r)
+		phase, message := RolloutPhase(r)
+		workloads = append(workloads, Workload{
+			Kind:                 KindRollout,
+			Name:                 r.Name,
+			UID:                  r.UID,
+			Labels:               r.Labels,
+			Annotations:          r.Annotations,
+			RevisionHistoryLimit: revisionHistoryLimit(r.Spec.RevisionHistoryLimit),
+			Template:             tpl,
+			Phase:                phase,
+			Message:              message,
+			TemplateError:        err,
+		})
+	}
+	return workloads
+}
+
+func (s *rolloutSource) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	return secondOf(s.ListWorkloadRevisions(ctx, namespace))
+}
+
+// ListWorkloadRevisions lists Rollouts and ReplicaSets once each. Revisions
+// are the Rollout ReplicaSets, every Rollout's desired template (spec.template
+// or the spec.workloadRef Deployment's), the ReplicaSets pinned by the Rollout
+// status, and the templates of running or recent Experiments and AnalysisRuns.
+// Revisions the strategy still relies on are flagged with Strategy.
+func (s *rolloutSource) ListWorkloadRevisions(ctx context.Context, namespace string) ([]Workload, []TemplateRevision, error) {
+	list, err := s.rollouts.ListRollouts(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloads := s.workloads(ctx, list)
+	rsList, err := s.replicaSets.ListNamespaceReplicaSets(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions := ownedReplicaSetRevisions(rsList, KindRollout)
+	for _, w := range workloads {
+		if w.TemplateError == nil {
+			revisions = append(revisions, desiredRevision(w))
+		}
+	}
+	revisions = append(revisions, statusReplicaSetRevisions(rsList, list, revisions)...)
+	analysis, err := s.analysis.ListRevisions(ctx, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	revisions = append(revisions, analysis...)
+	markStrategyRevisions(list, revisions, time.Now())
+	return workloads, revisions, nil
+}
+
+type deploymentSource struct {
+	client kubernetes.Interface
+	scope  listScope
+	replicaSetRevisions
+}
+
+func (s *deploymentSource) Kind() string { return KindDeployment }
+
+func (s *deploymentSource) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	return secondOf(s.ListWorkloadRevisions(ctx, namespace))
+}
+
+func (s *deploymentSource) ListWorkloadRevisions(ctx context.Context, namespace string) ([]Workload, []TemplateRevision, error) {
+	return listWithDesired(ctx, namespace, s, s.replicaSetRevisions)
+}
+
+func (s *deploymentSource) ListWorkloads(ctx context.Context, namespace string) ([]Workload, error) {
+	items, err := listAll(ctx, "deployments", s.scope, s.client.AppsV1().Deployments(namespace).List,
+		func(l *appsv1.DeploymentList) []appsv1.Deployment { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments in namespace %q: %w", namespace, err)
+	}
+	workloads := make([]Workload, 0, len(items))
+	for _, d := range items {
+		workloads = append(workloads, Workload{
+			Kind:                 KindDeployment,
+			Name:                 d.Name,
+			UID:                  d.UID,
+			Labels:               d.Labels,
+			Annotations:          d.Annotations,
+			RevisionHistoryLimit: revisionHistoryLimit(d.Spec.RevisionHistoryLimit),
+			Template:             d.Spec.Template,
+		})
+	}
+	return workloads, nil
+}
+
+type statefulSetSource struct {
+	client kubernetes.Interface
+	scope  listScope
+	controllerRevisionRevisions
+}
+
+func (s *statefulSetSource) Kind() string { return KindStatefulSet }
+
+func (s *statefulSetSource) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	return secondOf(s.ListWorkloadRevisions(ctx, namespace))
+}
+
+func (s *statefulSetSource) ListWorkloadRevisions(ctx context.Context, namespace string) ([]Workload, []TemplateRevision, error) {
+	return listWithDesired(ctx, namespace, s, s.controllerRevisionRevisions)
+}
+
+func (s *statefulSetSource) ListWorkloads(ctx context.Context, namespace string) ([]Workload, error) {
+	items, err := listAll(ctx, "statefulsets", s.scope, s.client.AppsV1().StatefulSets(namespace).List,
+		func(l *appsv1.StatefulSetList) []appsv1.StatefulSet { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list statefulsets in namespace %q: %w", namespace, err)
+	}
+	workloads := make([]Workload, 0, len(items))
+	for _, ss := range items {
+		workloads = append(workloads, Workload{
+			Kind:                 KindStatefulSet,
+			Name:                 ss.Name,
+			UID:                  ss.UID,
+			Labels:               ss.Labels,
+			Annotations:          ss.Annotations,
+			RevisionHistoryLimit: revisionHistoryLimit(ss.Spec.RevisionHistoryLimit),
+			Template:             ss.Spec.Template,
+		})
+	}
+	return workloads, nil
+}
+
+type daemonSetSource struct {
+	client kubernetes.Interface
+	scope  listScope
+	controllerRevisionRevisions
+}
+
+func (s *daemonSetSource) Kind() string { return KindDaemonSet }
+
+func (s *daemonSetSource) ListRevisions(ctx context.Context, namespace string) ([]TemplateRevision, error) {
+	return secondOf(s.ListWorkloadRevisions(ctx, namespace))
+}
+
+func (s *daemonSetSource) ListWorkloadRevisions(ctx context.Context, namespace string) ([]Workload, []TemplateRevision, error) {
+	return listWithDesired(ctx, namespace, s, s.controllerRevisionRevisions)
+}
+
+func (s *daemonSetSource) ListWorkloads(ctx context.Context, namespace string) ([]Workload, error) {
+	items, err := listAll(ctx, "daemonsets", s.scope, s.client.AppsV1().DaemonSets(namespace).List,
+		func(l *appsv1.DaemonSetList) []appsv1.DaemonSet { return l.Items })
+	if err != nil {
+		return nil, fmt.Errorf("failed to list daemonsets in namespace %q: %w", namespace, err)
+	}
+	workloads := make([]Workload, 0, len(items))
+	for _, ds := range items {
+		workloads = append(workloads, Workload{
+			Kind:                 KindDaemonSet,
+			Name:                 ds.Name,
+			UID:                  ds.UID,
+			Labels:               ds.Labels,
+			Annotations:          ds.Annotations,
+			RevisionHistoryLimit: revisionHistoryLimit(ds.Spec.RevisionHistoryLimit),
+			Template:             ds.Spec.Template,
+		})
+	}
+	return workloads, nil
+}
@@ -0,0 +1,292 @@
+package k8s
+
+// Unit tests for workload.go and controllerrevision.go.
+// Deployments reuse the ReplicaSet path; StatefulSets and DaemonSets are
+// exercised through ControllerRevisions built the way the apps controllers
+// store them (a {"spec":{"template":...}} patch in Data.Raw).
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// ─── Helpers ──────────────────────────────────────────────────────────────────
+
+// makeOwnerRef returns a controller OwnerReference for the given kind.
+func makeOwnerRef(kind, name, uid string) metav1.OwnerReference {
+	isController := true
+	return metav1.OwnerReference{
+		APIVersion: "apps/v1",
+		Kind:       kind,
+		Name:       name,
+		UID:        k8stypes.UID(uid),
+		Controller: &isController,
+	}
+}
+
+// makeTemplate returns a pod template mounting cmName with the given checksum.
+func makeTemplate(cmName, checksum string) corev1.PodTemplateSpec {
+	return corev1.PodTemplateSpec{
+		ObjectMeta: metav1.ObjectMeta{
+			Annotations: map[string]string{AnnotationChecksumConfig: checksum},
+		},
+		Spec: corev1.PodSpec{
+			Volumes: []corev1.Volume{configMapVolume("config", cmName)},
+		},
+	}
+}
+
+// makeDeploymentRS builds a ReplicaSet owned by a Deployment.
+func makeDeploymentRS(name, deployName, cmName, checksum string) *appsv1.ReplicaSet {
+	return &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{makeOwnerRef(KindDeployment, deployName, "deploy-uid")},
+		},
+		Spec: appsv1.ReplicaSetSpec{Template: makeTemplate(cmName, checksum)},
+	}
+}
+
+// makeControllerRevision builds a ControllerRevision owned by ownerKind whose
+// Data holds the pod template patch the apps controllers write.
+func makeControllerRevision(t *testing.T, name, ownerKind, ownerName, cmName, checksum string) *appsv1.ControllerRevision {
+	t.Helper()
+	raw, err := json.Marshal(map[string]any{
+		"spec": map[string]any{
+			"template": makeTemplate(cmName, checksum),
+		},
+	})
+	require.NoError(t, err)
+	return &appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       testNamespace,
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{makeOwnerRef(ownerKind, ownerName, ownerName+"-uid")},
+		},
+		Data:     runtime.RawExtension{Raw: raw},
+		Revision: 1,
+	}
+}
+
+// ─── ExtractControllerRevisionTemplate ───────────────────────────────────────
+
+func TestExtractControllerRevisionTemplate(t *testing.T) {
+	cr := makeControllerRevision(t, "db-7c9d", KindStatefulSet, "db", "db-config-aaaa1111", "aaaa1111")
+
+	tpl, err := ExtractControllerRevisionTemplate(*cr)
+	require.NoError(t, err)
+	assert.Equal(t, "aaaa1111", tpl.Annotations[AnnotationChecksumConfig])
+	assert.Equal(t, []string{"db-config-aaaa1111"}, PodSpecConfigMapRefs(tpl.Spec))
+
+	_, err = ExtractControllerRevisionTemplate(appsv1.ControllerRevision{
+		ObjectMeta: metav1.ObjectMeta{Name: "broken"},
+		Data:       runtime.RawExtension{Raw: []byte("not-json")},
+	})
+	assert.Error(t, err)
+}
+
+// ─── NewWorkloadSource ───────────────────────────────────────────────────────
+
+func TestNewWorkloadSource_UnsupportedKind(t *testing.T) {
+	_, err := NewWorkloadSource("CronJob", &Clients{Kube: fake.NewSimpleClientset()})
+	assert.Error(t, err)
+}
+
+func TestWorkloadSources(t *testing.T) {
+	webHistory := int32(3)
+	kube := fake.NewSimpleClientset(
+		&appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "web", UID: "deploy-uid"},
+			Spec:       appsv1.DeploymentSpec{RevisionHistoryLimit: &webHistory},
+		},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "db", UID: "db-uid"}},
+		&appsv1.DaemonSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "agent", UID: "agent-uid"}},
+		makeDeploymentRS("web-5d4f", "web", "web-config-11112222", "11112222"),
+		makeControllerRevision(t, "db-7c9d", KindStatefulSet, "db", "db-config-33334444", "33334444"),
+		makeControllerRevision(t, "agent-6b8f", KindDaemonSet, "agent", "agent-config-55556666", "55556666"),
+	)
+	clients := &Clients{Kube: kube, Rollout: rolloutfake.NewSimpleClientset()}
+
+	tests := []struct {
+		kind          string
+		wantWorkloads []string
+		wantRevisions []string
+		// wantHistory is the expected RevisionHistoryLimit of every workload.
+		wantHistory int
+	}{
+		{kind: KindRollout, wantWorkloads: []string{}, wantRevisions: []string{}},
+		{kind: KindDeployment, wantWorkloads: []string{"web"}, wantRevisions: []string{"web-5d4f", "Deployment/web"}, wantHistory: 3},
+		{kind: KindStatefulSet, wantWorkloads: []string{"db"}, wantRevisions: []string{"db-7c9d", "StatefulSet/db"}, wantHistory: DefaultRevisionHistoryLimit},
+		{kind: KindDaemonSet, wantWorkloads: []string{"agent"}, wantRevisions: []string{"agent-6b8f", "DaemonSet/agent"}, wantHistory: DefaultRevisionHistoryLimit},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.kind, func(t *testing.T) {
+			source, err := NewWorkloadSource(tc.kind, clients)
+			require.NoError(t, err)
+			assert.Equal(t, tc.kind, source.Kind())
+
+			workloads, err := source.ListWorkloads(context.Background(), testNamespace)
+			require.NoError(t, err)
+			gotWorkloads := []string{}
+			for _, w := range workloads {
+				assert.Equal(t, tc.kind, w.Kind)
+				assert.Equal(t, tc.wantHistory, w.RevisionHistoryLimit)
+				gotWorkloads = append(gotWorkloads, w.Name)
+			}
+			assert.ElementsMatch(t, tc.wantWorkloads, gotWorkloads)
+
+			revisions, err := source.ListRevisions(context.Background(), testNamespace)
+			require.NoError(t, err)
+			gotRevisions := []string{}
+			for _, rev := range revisions {
+				gotRevisions = append(gotRevisions, rev.Name)
+			}
+			assert.ElementsMatch(t, tc.wantRevisions, gotRevisions)
+		})
+	}
+}
+
+// TestWorkloadSources_LabelsAndAnnotations verifies that workloads carry the
+// labels and annotations of the workload object, which the Rollout filters
+// match against.
+func TestWorkloadSources_LabelsAndAnnotations(t *testing.T) {
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.Labels = map[string]string{"gc": "off"}
+	r.Annotations = map[string]string{"cm-gc/exclude": "true"}
+	source, err := NewWorkloadSource(KindRollout, &Clients{Kube: fake.NewSimpleClientset(), Rollout: rolloutfake.NewSimpleClientset(r)})
+	require.NoError(t, err)
+
+	workloads, err := source.ListWorkloads(context.Background(), testNamespace)
+	require.NoError(t, err)
+	require.Len(t, workloads, 1)
+	assert.Equal(t, r.Labels, workloads[0].Labels)
+	assert.Equal(t, r.Annotations, workloads[0].Annotations)
+}
+
+// TestWorkloadSources_UndecodableRevision verifies that a ControllerRevision
+// that fails to decode is skipped and its workload carries RevisionError,
+// while the other workloads of the namespace are listed as usual.
+func TestWorkloadSources_UndecodableRevision(t *testing.T) {
+	broken := makeControllerRevision(t, "db-0bad", KindStatefulSet, "db", "", "")
+	broken.Data = runtime.RawExtension{Raw: []byte("not-json")}
+	kube := fake.NewSimpleClientset(
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "db", UID: "db-uid"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "cache", UID: "cache-uid"}},
+		broken,
+		makeControllerRevision(t, "db-7c9d", KindStatefulSet, "db", "db-config-33334444", "33334444"),
+		makeControllerRevision(t, "cache-4e2a", KindStatefulSet, "cache", "cache-config-77778888", "77778888"),
+	)
+	source, err := NewWorkloadSource(KindStatefulSet, &Clients{Kube: kube, Rollout: rolloutfake.NewSimpleClientset()})
+	require.NoError(t, err)
+
+	workloads, revisions, err := source.ListWorkloadRevisions(context.Background(), testNamespace)
+	require.NoError(t, err)
+	failed := map[string]bool{}
+	for _, w := range workloads {
+		failed[w.Name] = w.RevisionError != nil
+	}
+	assert.Equal(t, map[string]bool{"db": true, "cache": false}, failed)
+
+	names := []string{}
+	for _, rev := range revisions {
+		assert.NoError(t, rev.TemplateError, rev.Name)
+		names = append(names, rev.Name)
+	}
+	assert.ElementsMatch(t, []string{"db-7c9d", "StatefulSet/db", "cache-4e2a", "StatefulSet/cache"}, names)
+}
+
+// TestRetainedRevisions verifies that each workload keeps its
+// revisionHistoryLimit+1 highest-numbered revisions, its desired template and
+// its strategy revisions, and that other owners' revisions are kept as is.
+func TestRetainedRevisions(t *testing.T) {
+	workloads := []Workload{
+		{Kind: KindRollout, Name: "seat", UID: "seat-uid", RevisionHistoryLimit: 1},
+		{Kind: KindStatefulSet, Name: "db", UID: "db-uid", RevisionHistoryLimit: 0},
+	}
+	revisions := []TemplateRevision{
+		{Name: "seat-1", OwnerUID: "seat-uid", Revision: 1},
+		{Name: "seat-2", OwnerUID: "seat-uid", Revision: 2, Strategy: true},
+		{Name: "seat-3", OwnerUID: "seat-uid", Revision: 3},
+		{Name: "seat-4", OwnerUID: "seat-uid", Revision: 4},
+		{Name: "Rollout/seat", OwnerUID: "seat-uid"},
+		{Name: "db-1", OwnerUID: "db-uid", Revision: 1},
+		{Name: "db-2", OwnerUID: "db-uid", Revision: 2},
+		{Name: "StatefulSet/db", OwnerUID: "db-uid"},
+		{Name: "other-1", OwnerUID: "other-uid", Revision: 1},
+	}
+
+	names := []string{}
+	for _, rev := range RetainedRevisions(workloads, revisions) {
+		names = append(names, rev.Name)
+	}
+	assert.Equal(t, []string{"seat-2", "seat-3", "seat-4", "Rollout/seat", "db-2", "StatefulSet/db", "other-1"}, names)
+}
+
+// TestWorkloadSources_RevisionNumbers verifies that revisions carry the
+// ReplicaSet revision annotation or the ControllerRevision number.
+func TestWorkloadSources_RevisionNumbers(t *testing.T) {
+	rs := makeDeploymentRS("web-5d4f", "web", "web-config-11112222", "11112222")
+	rs.Annotations = map[string]string{AnnotationDeploymentRevision: "7"}
+	cr := makeControllerRevision(t, "db-7c9d", KindStatefulSet, "db", "db-config-33334444", "33334444")
+	cr.Revision = 4
+	kube := fake.NewSimpleClientset(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "web", UID: "deploy-uid"}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: "db", UID: "db-uid"}},
+		rs, cr,
+	)
+	sources, err := NewWorkloadSources([]string{KindDeployment, KindStatefulSet}, &Clients{Kube: kube, Rollout: rolloutfake.NewSimpleClientset()})
+	require.NoError(t, err)
+
+	got := map[string]int64{}
+	for _, source := range sources {
+		revisions, err := source.ListRevisions(context.Background(), testNamespace)
+		require.NoError(t, err)
+		for _, rev := range revisions {
+			got[rev.Name] = rev.Revision
+		}
+	}
+	assert.Equal(t, map[string]int64{"web-5d4f": 7, "Deployment/web": 0, "db-7c9d": 4, "StatefulSet/db": 0}, got)
+}
+
+// TestWorkloadInUseResolver verifies that revisions of every enabled kind feed
+// the same in-use set, while kinds that are not enabled contribute nothing.
+func TestWorkloadInUseResolver(t *testing.T) {
+	rolloutRS := withConfigMapVolume(
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"),
+		"xzk0-seat-config-e6120fae",
+	)
+	kube := fake.NewSimpleClientset(
+		&rolloutRS,
+		makeDeploymentRS("web-5d4f", "web", "web-config-11112222", "11112222"),
+		makeControllerRevision(t, "db-7c9d", KindStatefulSet, "db", "db-config-33334444", "33334444"),
+	)
+	clients := &Clients{Kube: kube, Rollout: rolloutfake.NewSimpleClientset()}
+
+	sources, err := NewWorkloadSources([]string{KindRollout, KindDeployment}, clients)
+	require.NoError(t, err)
+	resolver := NewWorkloadInUseResolver(sources[0], sources[1])
+
+	referenced, err := resolver.ResolveConfigMaps(context.Background(), testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"xzk0-seat-config-e6120fae": true,
+		"web-config-11112222":       true,
+	}, referenced)
+
+	checksums, err := resolver.Resolve(context.Background(), testNamespace)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "11112222": true}, checksums)
+}
@@ -0,0 +1,62 @@
+package k8s
+
+// Rollout spec.workloadRef support.
+// A Rollout may omit spec.template and instead reference a Deployment's pod
+// template via spec.workloadRef. The checksum annotation and ConfigMap
+// references then live on the Deployment: it is the Rollout's desired
+// template, and it is treated as in-use for the Rollout even before a
+// ReplicaSet for it exists. A Rollout whose workloadRef cannot be resolved is
+// listed with Workload.TemplateError set, so that only it is held back.
+
+import (
+	"context"
+	"fmt"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// DeploymentGetter gets a Deployment by name; spec.workloadRef targets are
+// resolved through it.
+type DeploymentGetter interface {
+	GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error)
+}
+
+// KubeDeploymentClient is the DeploymentGetter backed by a real (or fake)
+// kubernetes.Interface.
+type KubeDeploymentClient struct {
+	client kubernetes.Interface
+}
+
+// NewKubeDeploymentClient creates a KubeDeploymentClient wrapping the provided
+// kubernetes.Interface. Pass fake.NewSimpleClientset() in tests.
+func NewKubeDeploymentClient(client kubernetes.Interface) *KubeDeploymentClient {
+	return &KubeDeploymentClient{client: client}
+}
+
+// GetDeployment gets the named Deployment from the API server.
+func (k *KubeDeploymentClient) GetDeployment(ctx context.Context, namespace, name string) (*appsv1.Deployment, error) {
+	return k.client.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// RolloutTemplate returns the pod template the Rollout currently desires:
+// the template of the Deployment named by spec.workloadRef when set,
+// otherwise spec.template. Only Deployment targets are supported, matching
+// Argo Rollouts itself.
+func RolloutTemplate(ctx context.Context, deployments DeploymentGetter, r rolloutsv1alpha1.Rollout) (corev1.PodTemplateSpec, error) {
+	ref := r.Spec.WorkloadRef
+	if ref == nil {
+		return r.Spec.Template, nil
+	}
+	if ref.Kind != KindDeployment {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("rollout %q: unsupported workloadRef kind %q", r.Name, ref.Kind)
+	}
+	d, err := deployments.GetDeployment(ctx, r.Namespace, ref.Name)
+	if err != nil {
+		return corev1.PodTemplateSpec{}, fmt.Errorf("rollout %q: failed to get workloadRef deployment %q: %w", r.Name, ref.Name, err)
+	}
+	return d.Spec.Template, nil
+}
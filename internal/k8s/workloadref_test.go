@@ -0,0 +1,144 @@
+package k8s
+
+// Unit tests for workloadref.go: Rollouts that take their pod template from a
+// Deployment via spec.workloadRef. Mirrors inuse_test.go — fake clientsets for
+// both the core API (Deployments, ReplicaSets) and Argo Rollouts.
+
+import (
+	"context"
+	"testing"
+
+	rolloutsv1alpha1 "github.com/argoproj/argo-rollouts/pkg/apis/rollouts/v1alpha1"
+	rolloutfake "github.com/argoproj/argo-rollouts/pkg/client/clientset/versioned/fake"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testWorkloadRefDeployment = "xzk0-seat-deploy"
+
+// makeWorkloadRefRollout builds a Rollout whose template comes from the named
+// Deployment.
+func makeWorkloadRefRollout(deployName string) *rolloutsv1alpha1.Rollout {
+	r := makeRollout(testNamespace, testRolloutName, nil)
+	r.UID = k8stypes.UID(testRolloutUID)
+	r.Spec.WorkloadRef = &rolloutsv1alpha1.ObjectRef{
+		APIVersion: "apps/v1",
+		Kind:       KindDeployment,
+		Name:       deployName,
+	}
+	return r
+}
+
+// makeRefDeployment builds the scaled-down Deployment a Rollout references.
+func makeRefDeployment(cmName, checksum string) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testNamespace, Name: testWorkloadRefDeployment},
+		Spec:       appsv1.DeploymentSpec{Template: makeTemplate(cmName, checksum)},
+	}
+}
+
+func TestRolloutTemplate(t *testing.T) {
+	kube := fake.NewSimpleClientset(makeRefDeployment("xzk0-seat-config-a1b2c3d4", "a1b2c3d4"))
+
+	t.Run("spec.template when workloadRef is unset", func(t *testing.T) {
+		r := makeRollout(testNamespace, testRolloutName, nil)
+		r.Spec.Template = makeTemplate("xzk0-seat-config-e6120fae", "e6120fae")
+
+		tpl, err := RolloutTemplate(context.Background(), NewKubeDeploymentClient(kube), *r)
+		require.NoError(t, err)
+		assert.Equal(t, "e6120fae", tpl.Annotations[AnnotationChecksumConfig])
+	})
+
+	t.Run("referenced Deployment template", func(t *testing.T) {
+		tpl, err := RolloutTemplate(context.Background(), NewKubeDeploymentClient(kube), *makeWorkloadRefRollout(testWorkloadRefDeployment))
+		require.NoError(t, err)
+		assert.Equal(t, "a1b2c3d4", tpl.Annotations[AnnotationChecksumConfig])
+		assert.Equal(t, []string{"xzk0-seat-config-a1b2c3d4"}, PodSpecConfigMapRefs(tpl.Spec))
+	})
+
+	t.Run("missing Deployment is an error", func(t *testing.T) {
+		_, err := RolloutTemplate(context.Background(), NewKubeDeploymentClient(kube), *makeWorkloadRefRollout("missing"))
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported workloadRef kind is an error", func(t *testing.T) {
+		r := makeWorkloadRefRollout(testWorkloadRefDeployment)
+		r.Spec.WorkloadRef.Kind = "ReplicaSet"
+		_, err := RolloutTemplate(context.Background(), NewKubeDeploymentClient(kube), *r)
+		assert.Error(t, err)
+	})
+}
+
+// TestWorkloadRef_InUse verifies that the Deployment template a Rollout
+// references is in use for that Rollout even before a ReplicaSet carrying it
+// exists, alongside the checksums of the Rollout's existing ReplicaSets.
+func TestWorkloadRef_InUse(t *testing.T) {
+	oldRS := withConfigMapVolume(
+		makeRS(testNamespace, "xzk0-seat-65df947c4c", testRolloutName, testRolloutUID, "e6120fae"),
+		"xzk0-seat-config-e6120fae",
+	)
+	kube := fake.NewSimpleClientset(
+		&oldRS,
+		makeRefDeployment("xzk0-seat-config-a1b2c3d4", "a1b2c3d4"),
+	)
+	clients := &Clients{
+		Kube:    kube,
+		Rollout: rolloutfake.NewSimpleClientset(makeWorkloadRefRollout(testWorkloadRefDeployment)),
+	}
+
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	workloads, err := source.ListWorkloads(context.Background(), testNamespace)
+	require.NoError(t, err)
+	require.Len(t, workloads, 1)
+	assert.Equal(t, []string{"xzk0-seat-config-a1b2c3d4"}, PodSpecConfigMapRefs(workloads[0].Template.Spec),
+		"the desired template must come from the referenced Deployment")
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+
+	set := scoped.For(ResourceConfigMap, testRolloutUID)
+	assert.Equal(t, map[string]bool{"e6120fae": true, "a1b2c3d4": true}, set.Checksums)
+	assert.Equal(t, map[string]bool{
+		"xzk0-seat-config-e6120fae": true,
+		"xzk0-seat-config-a1b2c3d4": true,
+	}, set.Names)
+}
+
+// TestWorkloadRef_Unresolved verifies that a dangling workloadRef only marks
+// its own Rollout, which the caller then holds back, instead of failing the
+// namespace: a well-formed Rollout next to it still resolves.
+func TestWorkloadRef_Unresolved(t *testing.T) {
+	const goodUID = "0b7a3c55-2d1e-4f7a-8c61-5a0c1f9e2b44"
+	good := makeRollout(testNamespace, "xzk0-order", nil)
+	good.UID = goodUID
+	good.Spec.Template = makeTemplate("xzk0-order-config-da8762a8", "da8762a8")
+	unsupported := makeWorkloadRefRollout(testWorkloadRefDeployment)
+	unsupported.Name, unsupported.UID = "xzk0-cart", "uid-cart"
+	unsupported.Spec.WorkloadRef.Kind = "ReplicaSet"
+	clients := &Clients{
+		Kube:    fake.NewSimpleClientset(),
+		Rollout: rolloutfake.NewSimpleClientset(good, makeWorkloadRefRollout("missing"), unsupported),
+	}
+	source, err := NewWorkloadSource(KindRollout, clients)
+	require.NoError(t, err)
+
+	workloads, err := source.ListWorkloads(context.Background(), testNamespace)
+	require.NoError(t, err)
+	require.Len(t, workloads, 3)
+	unresolved := make(map[string]bool)
+	for _, w := range workloads {
+		unresolved[w.Name] = w.TemplateError != nil
+	}
+	assert.Equal(t, map[string]bool{"xzk0-order": false, testRolloutName: true, "xzk0-cart": true}, unresolved)
+
+	scoped, err := NewWorkloadInUseResolver(source).ResolveScoped(context.Background(), testNamespace, ConfigMapResource)
+	require.NoError(t, err)
+	assert.True(t, scoped.For(ResourceConfigMap, goodUID).Checksums["da8762a8"])
+	assert.Equal(t, 1, scoped.Owners(), "unresolved Rollouts have no desired revision")
+}
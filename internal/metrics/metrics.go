@@ -0,0 +1,140 @@
+package metrics
+
+// Prometheus metrics.
+// Every metric is registered on Registry, which the controller serves on
+// /metrics and a one-shot run can dump at exit: to a file for the node
+// exporter's textfile collector, or to a Pushgateway. Per-family gauges and
+// counters are labelled by namespace, owning rollout(s) and resource kind,
+// never by object name, so their cardinality is bounded by the number of
+// Rollouts.
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Namespace prefixes every metric name.
+const Namespace = "cm_gc"
+
+// Registry holds every cm-gc metric plus the Go runtime and process
+// collectors.
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+var factory = promauto.With(Registry)
+
+// Per-family gauges, set each time the family is planned: a controller
+// re-plans a family on every sync, so these hold the latest plan instead of
+// accumulating.
+var (
+	// Candidates is the number of objects matching a family's naming
+	// template.
+	Candidates = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "candidates",
+		Help:      "Versioned objects considered for deletion by the latest plan.",
+	}, []string{"namespace", "rollout", "resource"})
+
+	// Protected is the number of candidates the planner kept, by planner
+	// reason.
+	Protected = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "protected",
+		Help:      "Candidates kept by the latest plan, by protection reason.",
+	}, []string{"namespace", "rollout", "resource", "reason"})
+
+	// WouldDelete is the number of candidates the planner marked for
+	// deletion; in dry-run mode they are never deleted.
+	WouldDelete = factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: Namespace,
+		Name:      "would_delete",
+		Help:      "Candidates marked for deletion by the latest plan, deleted or not (dry run).",
+	}, []string{"namespace", "rollout", "resource"})
+)
+
+// Per-family counters.
+var (
+	// Deletions counts deleted objects.
+	Deletions = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "deletions_total",
+		Help:      "Versioned objects deleted.",
+	}, []string{"namespace", "rollout", "resource"})
+
+	// DeletionFailures counts deletions the API server rejected.
+	DeletionFailures = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: Namespace,
+		Name:      "deletion_failures_total",
+		Help:      "Deletions of versioned objects that failed.",
+	}, []string{"namespace", "rollout", "resource"})
+)
+
+// Latency histograms.
+var (
+	// APIListDuration observes paginated List calls, all pages included.
+	APIListDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "api_list_duration_seconds",
+		Help:      "Duration of paginated List calls to the API server, by listed resource.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	}, []string{"resource"})
+
+	// RunDuration observes one-shot runs over every namespace.
+	RunDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "run_duration_seconds",
+		Help:      "Duration of one-shot runs over every target namespace.",
+		Buckets:   prometheus.ExponentialBuckets(1, 2, 12),
+	})
+
+	// SyncDuration observes controller syncs of a single Rollout.
+	SyncDuration = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: Namespace,
+		Name:      "rollout_sync_duration_seconds",
+		Help:      "Duration of controller syncs of a single Rollout.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+	})
+)
+
+// Serve serves Registry on addr under /metrics until ctx is done.
+func Serve(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{}))
+	server := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx) //nolint:errcheck
+	}()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// WriteTextfile writes Registry to path in the Prometheus text format,
+// atomically, for the node exporter's textfile collector.
+func WriteTextfile(path string) error {
+	return prometheus.WriteToTextfile(path, Registry)
+}
+
+// Push replaces the metrics of job "cm-gc" on the Pushgateway at url with
+// Registry.
+func Push(url string) error {
+	return push.New(url, "cm-gc").Gatherer(Registry).Push()
+}
@@ -0,0 +1,47 @@
+package metrics
+
+// Unit tests for the one-shot exporters. Each test uses its own label values
+// since Registry is shared by the whole process.
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTextfile(t *testing.T) {
+	WouldDelete.WithLabelValues("textfile-ns", "seat", "ConfigMap").Set(2)
+	Protected.WithLabelValues("textfile-ns", "seat", "ConfigMap", "in-use").Set(1)
+	path := filepath.Join(t.TempDir(), "cm-gc.prom")
+
+	require.NoError(t, WriteTextfile(path))
+
+	out, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `cm_gc_would_delete{namespace="textfile-ns",resource="ConfigMap",rollout="seat"} 2`)
+	assert.Contains(t, string(out), `cm_gc_protected{namespace="textfile-ns",reason="in-use",resource="ConfigMap",rollout="seat"} 1`)
+}
+
+func TestPush(t *testing.T) {
+	Candidates.WithLabelValues("push-ns", "seat", "ConfigMap").Set(3)
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	require.NoError(t, Push(server.URL))
+
+	assert.Equal(t, http.MethodPut, method, "a push replaces the job's previous metrics")
+	assert.Equal(t, "/metrics/job/cm-gc", path)
+	assert.Contains(t, body, "cm_gc_candidates")
+}
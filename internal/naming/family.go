@@ -0,0 +1,93 @@
+package naming
+
+// Families: the set of versioned objects that are successive versions of one
+// logical ConfigMap or Secret, e.g. every "foo-env-{hash}".
+// A family is derived either from a workload name through the naming template
+// (Template.Family) or from an object name referenced by the workload's pod
+// template with its hash suffix stripped (Template.StemFamily). Families of
+// different stems never overlap: all hashes have the same length, so a name
+// can only be stem+hash for a single stem.
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Family matches the names of every version of one logical object.
+type Family interface {
+	// Key identifies the family, e.g. "foo-env-{hash}". Two families with the
+	// same key match exactly the same names.
+	Key() string
+	// Prefix is the longest fixed prefix shared by every name in the family.
+	Prefix() string
+	// Match returns the hash of name when it belongs to the family.
+	Match(name string) (hash string, ok bool)
+}
+
+// Family returns the family of objects the template names after owner.
+// Templates of the form "...{workload}...{hash}" yield a stem family, so they
+// deduplicate against stems derived from pod template references.
+func (t *Template) Family(owner string) Family {
+	if t.ownerFirst && t.suffix == "" {
+		return t.StemFamily(t.Prefix(owner))
+	}
+	return ownerFamily{template: t, owner: owner}
+}
+
+// StemFamily returns the family of names consisting of stem followed by a
+// hash of this template's length and alphabet.
+func (t *Template) StemFamily(stem string) Family {
+	return stemFamily{template: t, stem: stem}
+}
+
+// Stem strips the hash suffix from a name, e.g. "foo-env-e6120fae" becomes
+// "foo-env-". Returns ok=false when the name does not end in a hash, or when
+// nothing but the hash would remain. The stem must end in a character outside
+// the hash alphabet (typically "-") so that unversioned names whose tail
+// happens to look like a hash are not mistaken for versioned ones.
+func (t *Template) Stem(name string) (stem string, ok bool) {
+	if len(name) <= t.hashLength {
+		return "", false
+	}
+	stem, hash := name[:len(name)-t.hashLength], name[len(name)-t.hashLength:]
+	if !t.hashRe.MatchString(hash) {
+		return "", false
+	}
+	if last, _ := utf8.DecodeLastRuneInString(stem); strings.ContainsRune(t.alphabet, last) {
+		return "", false
+	}
+	return stem, true
+}
+
+// stemFamily matches stem+hash exactly.
+type stemFamily struct {
+	template *Template
+	stem     string
+}
+
+func (f stemFamily) Key() string    { return f.stem + "{hash}" }
+func (f stemFamily) Prefix() string { return f.stem }
+
+func (f stemFamily) Match(name string) (string, bool) {
+	hash, ok := strings.CutPrefix(name, f.stem)
+	if !ok || !f.template.hashRe.MatchString(hash) {
+		return "", false
+	}
+	return hash, true
+}
+
+// ownerFamily matches names the template parses into one owner.
+type ownerFamily struct {
+	template *Template
+	owner    string
+}
+
+func (f ownerFamily) Key() string {
+	return strings.Replace(f.template.pattern, f.template.ownerPlaceholder, f.owner, 1)
+}
+
+func (f ownerFamily) Prefix() string { return f.template.Prefix(f.owner) }
+
+func (f ownerFamily) Match(name string) (string, bool) {
+	return f.template.Match(name, f.owner)
+}
@@ -0,0 +1,71 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplate_Stem(t *testing.T) {
+	tpl, err := New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    string
+		wantStem string
+		wantOK   bool
+	}{
+		{name: "versioned name", input: "foo-env-e6120fae", wantStem: "foo-env-", wantOK: true},
+		{name: "not named after any workload", input: "shared-nginx-b870a608", wantStem: "shared-nginx-", wantOK: true},
+		{name: "unversioned name", input: "kube-root-ca.crt"},
+		{name: "tail longer than a hash", input: "foo-env-1e6120fae"},
+		{name: "only a hash", input: "e6120fae"},
+		{name: "shorter than a hash", input: "abc"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stem, ok := tpl.Stem(tc.input)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantStem, stem)
+		})
+	}
+}
+
+func TestStemFamily_Match(t *testing.T) {
+	tpl, err := New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+	family := tpl.StemFamily("foo-env-")
+
+	assert.Equal(t, "foo-env-{hash}", family.Key())
+	assert.Equal(t, "foo-env-", family.Prefix())
+
+	hash, ok := family.Match("foo-env-b870a608")
+	assert.True(t, ok)
+	assert.Equal(t, "b870a608", hash)
+
+	_, ok = family.Match("foo-env-extra-b870a608")
+	assert.False(t, ok)
+	_, ok = family.Match("foo-config-b870a608")
+	assert.False(t, ok)
+}
+
+func TestTemplate_Family(t *testing.T) {
+	helm, err := New("{workload}-config-{hash}", 0, "")
+	require.NoError(t, err)
+	family := helm.Family("foo")
+	assert.Equal(t, helm.StemFamily("foo-config-").Key(), family.Key(),
+		"hash-suffixed templates must deduplicate against stems from pod template references")
+
+	hashFirst, err := New("{hash}.{rollout}", 0, "")
+	require.NoError(t, err)
+	family = hashFirst.Family("foo")
+	assert.Equal(t, "{hash}.foo", family.Key())
+	hash, ok := family.Match("e6120fae.foo")
+	assert.True(t, ok)
+	assert.Equal(t, "e6120fae", hash)
+	_, ok = family.Match("e6120fae.bar")
+	assert.False(t, ok)
+}
@@ -0,0 +1,187 @@
+package naming
+
+// Naming templates for versioned objects.
+// A template describes how a workload's versioned ConfigMaps or Secrets are
+// named, e.g. "{workload}-config-{hash}" (Helm checksum pattern, the default)
+// or "{workload}-{hash10}" (Kustomize configMapGenerator suffix).
+//
+// Placeholders:
+//   - {workload} — the owning workload's name; {rollout} and {name} are aliases.
+//   - {hash}     — the content hash, DefaultHashLength characters long unless a
+//     length is configured; {hashN} fixes the length to N, e.g. {hash10}.
+//
+// Each placeholder must appear exactly once. Every other character is literal.
+// Parsing is exact: a name matches only when the whole name fits the template
+// and the hash consists of exactly the configured number of characters from
+// the configured alphabet — never by substring.
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultHashLength is the length of Helm's truncated sha256 checksum.
+	DefaultHashLength = 8
+	// DefaultHashAlphabet is the lowercase hex alphabet of sha256 checksums.
+	DefaultHashAlphabet = "0123456789abcdef"
+	// KustomizeHashAlphabet is the alphabet of Kustomize generator suffixes.
+	KustomizeHashAlphabet = "bcdfghkmt2456789"
+)
+
+// placeholderPattern matches "{word}" placeholders inside a template.
+var placeholderPattern = regexp.MustCompile(`\{([a-z]+)(\d*)\}`)
+
+// Template parses and builds versioned object names.
+type Template struct {
+	pattern string
+	// prefix, infix and suffix are the literal text before, between and after
+	// the two placeholders; ownerFirst reports whether {workload} comes
+	// before {hash}.
+	prefix           string
+	infix            string
+	suffix           string
+	ownerFirst       bool
+	ownerPlaceholder string
+	hashLength       int
+	alphabet         string
+	re               *regexp.Regexp
+	// hashRe matches a complete hash.
+	hashRe *regexp.Regexp
+}
+
+// New compiles a naming template. hashLength applies when the template uses a
+// bare {hash}; a length of 0 means DefaultHashLength, and an empty alphabet
+// means DefaultHashAlphabet.
+func New(pattern string, hashLength int, alphabet string) (*Template, error) {
+	if hashLength < 0 {
+		return nil, fmt.Errorf("invalid naming template %q: hash length must not be negative", pattern)
+	}
+	if hashLength == 0 {
+		hashLength = DefaultHashLength
+	}
+	if alphabet == "" {
+		alphabet = DefaultHashAlphabet
+	}
+
+	t := &Template{pattern: pattern}
+	var expr strings.Builder
+	expr.WriteString("^")
+	ownerSeen, hashSeen := false, false
+	last := 0
+	for _, loc := range placeholderPattern.FindAllStringSubmatchIndex(pattern, -1) {
+		literal := pattern[last:loc[0]]
+		last = loc[1]
+		expr.WriteString(regexp.QuoteMeta(literal))
+		switch {
+		case !ownerSeen && !hashSeen:
+			t.prefix = literal
+		case !(ownerSeen && hashSeen):
+			t.infix = literal
+		}
+
+		name, digits := pattern[loc[2]:loc[3]], pattern[loc[4]:loc[5]]
+		switch {
+		case isOwnerPlaceholder(name) && digits == "":
+			if ownerSeen {
+				return nil, fmt.Errorf("invalid naming template %q: {%s} appears more than once", pattern, name)
+			}
+			ownerSeen = true
+			t.ownerFirst = !hashSeen
+			t.ownerPlaceholder = pattern[loc[0]:loc[1]]
+			expr.WriteString("(?P<owner>.+)")
+		case name == "hash":
+			if hashSeen {
+				return nil, fmt.Errorf("invalid naming template %q: {hash} appears more than once", pattern)
+			}
+			hashSeen = true
+			if digits != "" {
+				n, err := strconv.Atoi(digits)
+				if err != nil || n == 0 {
+					return nil, fmt.Errorf("invalid naming template %q: bad hash length %q", pattern, digits)
+				}
+				hashLength = n
+			}
+			fmt.Fprintf(&expr, "(?P<hash>%s{%d})", alphabetClass(alphabet), hashLength)
+		default:
+			return nil, fmt.Errorf("invalid naming template %q: unknown placeholder %q", pattern, pattern[loc[0]:loc[1]])
+		}
+	}
+	t.suffix = pattern[last:]
+	expr.WriteString(regexp.QuoteMeta(t.suffix))
+	expr.WriteString("$")
+
+	if !ownerSeen || !hashSeen {
+		return nil, fmt.Errorf("invalid naming template %q: both {workload} and {hash} are required", pattern)
+	}
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid naming template %q: %w", pattern, err)
+	}
+	t.re = re
+	t.hashRe = regexp.MustCompile(fmt.Sprintf("^%s{%d}$", alphabetClass(alphabet), hashLength))
+	t.hashLength = hashLength
+	t.alphabet = alphabet
+	return t, nil
+}
+
+// isOwnerPlaceholder reports whether name is {workload} or one of its aliases.
+func isOwnerPlaceholder(name string) bool {
+	return name == "workload" || name == "rollout" || name == "name"
+}
+
+// alphabetClass returns a regexp character class matching one alphabet symbol.
+func alphabetClass(alphabet string) string {
+	var b strings.Builder
+	b.WriteString("[")
+	for _, r := range alphabet {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			b.WriteString(`\`)
+		}
+		b.WriteRune(r)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// String returns the template pattern.
+func (t *Template) String() string {
+	return t.pattern
+}
+
+// HashLength returns the number of hash characters in a matching name.
+func (t *Template) HashLength() int {
+	return t.hashLength
+}
+
+// Prefix returns the longest fixed name prefix shared by every object of the
+// given workload, suitable for server-side or client-side prefix filtering.
+// Always use Match on the listed names: a prefix alone is not exact.
+func (t *Template) Prefix(owner string) string {
+	if !t.ownerFirst {
+		return t.prefix
+	}
+	return t.prefix + owner + t.infix
+}
+
+// Parse splits a name into its owning workload and hash.
+// Returns ok=false when the name does not match the template.
+func (t *Template) Parse(name string) (owner, hash string, ok bool) {
+	m := t.re.FindStringSubmatch(name)
+	if m == nil {
+		return "", "", false
+	}
+	return m[t.re.SubexpIndex("owner")], m[t.re.SubexpIndex("hash")], true
+}
+
+// Match returns the hash of name when it is a versioned object of the given
+// workload. Returns ok=false for names of other workloads or other shapes.
+func (t *Template) Match(name, owner string) (hash string, ok bool) {
+	parsedOwner, hash, ok := t.Parse(name)
+	if !ok || parsedOwner != owner {
+		return "", false
+	}
+	return hash, true
+}
@@ -0,0 +1,134 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+	}{
+		{name: "missing hash", pattern: "{workload}-config"},
+		{name: "missing workload", pattern: "app-config-{hash}"},
+		{name: "duplicate hash", pattern: "{workload}-{hash}-{hash}"},
+		{name: "duplicate workload", pattern: "{workload}-{rollout}-{hash}"},
+		{name: "unknown placeholder", pattern: "{release}-{workload}-{hash}"},
+		{name: "zero hash length", pattern: "{workload}-{hash0}"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := New(tc.pattern, 0, "")
+			assert.Error(t, err)
+		})
+	}
+
+	_, err := New("{workload}-{hash}", -1, "")
+	assert.Error(t, err, "negative hash length")
+}
+
+func TestTemplate_Parse(t *testing.T) {
+	tests := []struct {
+		name      string
+		pattern   string
+		length    int
+		alphabet  string
+		input     string
+		wantOwner string
+		wantHash  string
+		wantOK    bool
+	}{
+		{
+			name:    "helm default",
+			pattern: "{workload}-config-{hash}", input: "xzk0-seat-config-e6120fae",
+			wantOwner: "xzk0-seat", wantHash: "e6120fae", wantOK: true,
+		},
+		{
+			name:    "hash one character too long",
+			pattern: "{workload}-config-{hash}", input: "xzk0-seat-config-e6120fae1",
+		},
+		{
+			name:    "hash outside the alphabet",
+			pattern: "{workload}-config-{hash}", input: "xzk0-seat-config-e6120fag",
+		},
+		{
+			name:    "infix missing",
+			pattern: "{workload}-config-{hash}", input: "xzk0-seat-secret-e6120fae",
+		},
+		{
+			name:    "inline hash length with cm infix",
+			pattern: "{rollout}-cm-{hash10}", input: "billing-api-cm-0123456789",
+			wantOwner: "billing-api", wantHash: "0123456789", wantOK: true,
+		},
+		{
+			name:    "kustomize generator suffix",
+			pattern: "{name}-{hash}", length: 10, alphabet: KustomizeHashAlphabet,
+			input:     "app-config-7m2b5d8cgh",
+			wantOwner: "app-config", wantHash: "7m2b5d8cgh", wantOK: true,
+		},
+		{
+			name:    "hash before workload",
+			pattern: "{hash}.{workload}", input: "e6120fae.xzk0-seat",
+			wantOwner: "xzk0-seat", wantHash: "e6120fae", wantOK: true,
+		},
+		{
+			name:    "regexp metacharacters in literals are literal",
+			pattern: "{workload}.cfg+{hash}", input: "app.cfg+e6120fae",
+			wantOwner: "app", wantHash: "e6120fae", wantOK: true,
+		},
+		{
+			name:    "dash in alphabet is not a range",
+			pattern: "{workload}_{hash4}", alphabet: "a-z", input: "app_a-za",
+			wantOwner: "app", wantHash: "a-za", wantOK: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tpl, err := New(tc.pattern, tc.length, tc.alphabet)
+			require.NoError(t, err)
+
+			owner, hash, ok := tpl.Parse(tc.input)
+			assert.Equal(t, tc.wantOK, ok)
+			assert.Equal(t, tc.wantOwner, owner)
+			assert.Equal(t, tc.wantHash, hash)
+		})
+	}
+}
+
+func TestTemplate_Match(t *testing.T) {
+	tpl, err := New("{workload}-{hash10}", 0, KustomizeHashAlphabet)
+	require.NoError(t, err)
+	assert.Equal(t, 10, tpl.HashLength())
+
+	hash, ok := tpl.Match("foo-7m2b5d8cgh", "foo")
+	assert.True(t, ok)
+	assert.Equal(t, "7m2b5d8cgh", hash)
+
+	_, ok = tpl.Match("foo-bar-7m2b5d8cgh", "foo")
+	assert.False(t, ok, "object of workload foo-bar must not match workload foo")
+}
+
+func TestTemplate_Prefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{pattern: "{workload}-config-{hash}", want: "xzk0-seat-config-"},
+		{pattern: "cfg-{workload}-{hash}", want: "cfg-xzk0-seat-"},
+		{pattern: "{hash}-{workload}", want: ""},
+		{pattern: "v1-{hash}-{workload}", want: "v1-"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.pattern, func(t *testing.T) {
+			tpl, err := New(tc.pattern, 0, "")
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, tpl.Prefix("xzk0-seat"))
+		})
+	}
+}
@@ -6,50 +6,86 @@ import (
 	"time"
 )
 
-type ConfigMapCandidate struct {
+// Candidate is the kind-agnostic metadata the planner needs about one
+// versioned ConfigMap or Secret. It never carries object data.
+type Candidate struct {
 	Name              string
 	CreationTimestamp time.Time
 	Annotations       map[string]string
 }
 
-func Plan(cms []ConfigMapCandidate, inUse map[string]bool, keepLast int, keepDays int, now time.Time) []string {
-	sorted := make([]ConfigMapCandidate, len(cms))
-	copy(sorted, cms)
-	slices.SortFunc(sorted, func(a, b ConfigMapCandidate) int {
-		return b.CreationTimestamp.Compare(a.CreationTimestamp)
-	})
+// Reason explains the outcome for one candidate.
+type Reason string
 
-	notInKeepLast := make(map[string]bool)
-	for i, cm := range sorted {
-		if i >= keepLast {
-			notInKeepLast[cm.Name] = true
-		}
-	}
+const (
+	// ReasonStrategy: referenced by a ReplicaSet the Rollout strategy still
+	// relies on (stable, canary, blue-green active/preview, scale-down delay).
+	ReasonStrategy Reason = "protected by rollout strategy"
+	ReasonInUse    Reason = "in-use"
+	ReasonKeepLast Reason = "within keep-last"
+	ReasonProtect  Reason = "gc.k8s.io/protect annotation"
+	ReasonPrune    Reason = "argocd PruneLast sync option"
+	ReasonKeepDays Reason = "younger than keep-days"
+	ReasonDelete   Reason = "not in-use, not in keep-last, older than keep-days"
+)
 
-	keepDuration := time.Duration(keepDays) * 24 * time.Hour
+// KeepReasons lists every Reason a candidate is kept for.
+var KeepReasons = []Reason{ReasonStrategy, ReasonInUse, ReasonKeepLast, ReasonProtect, ReasonPrune, ReasonKeepDays}
+
+// Decision is the planner outcome for one candidate.
+type Decision struct {
+	Name   string
+	Delete bool
+	Reason Reason
+}
 
+func Plan(cms []Candidate, inUse map[string]bool, keepLast int, keepDays int, now time.Time) []string {
 	var toDelete []string
-	for _, cm := range sorted {
-		if !notInKeepLast[cm.Name] {
-			continue
-		}
-		if inUse[cm.Name] {
-			continue
-		}
-		if cm.Annotations["gc.k8s.io/protect"] == "true" {
-			continue
-		}
-		if strings.Contains(cm.Annotations["argocd.argoproj.io/sync-options"], "PruneLast=true") {
-			continue
+	for _, d := range Explain(cms, inUse, nil, keepLast, keepDays, now) {
+		if d.Delete {
+			toDelete = append(toDelete, d.Name)
 		}
+	}
+	return toDelete
+}
 
-		// Skip if ConfigMap is too new (not older than keepDays)
-		age := now.Sub(cm.CreationTimestamp)
-		if age < keepDuration {
-			continue
-		}
-		toDelete = append(toDelete, cm.Name)
+// Explain returns one Decision per candidate, newest first. strategy marks
+// candidates protected by the Rollout strategy; it takes precedence over every
+// other reason so such candidates are always reported as strategy-protected.
+func Explain(cms []Candidate, inUse, strategy map[string]bool, keepLast int, keepDays int, now time.Time) []Decision {
+	sorted := make([]Candidate, len(cms))
+	copy(sorted, cms)
+	slices.SortFunc(sorted, func(a, b Candidate) int {
+		return b.CreationTimestamp.Compare(a.CreationTimestamp)
+	})
+
+	keepDuration := time.Duration(keepDays) * 24 * time.Hour
+
+	decisions := make([]Decision, 0, len(sorted))
+	for i, cm := range sorted {
+		reason := decide(cm, i, inUse, strategy, keepLast, keepDuration, now)
+		decisions = append(decisions, Decision{Name: cm.Name, Delete: reason == ReasonDelete, Reason: reason})
 	}
+	return decisions
+}
 
-	return toDelete
+// decide returns the reason for the candidate at index i of the newest-first order.
+func decide(cm Candidate, i int, inUse, strategy map[string]bool, keepLast int, keepDuration time.Duration, now time.Time) Reason {
+	switch {
+	case strategy[cm.Name]:
+		return ReasonStrategy
+	case inUse[cm.Name]:
+		return ReasonInUse
+	case i < keepLast:
+		return ReasonKeepLast
+	case cm.Annotations["gc.k8s.io/protect"] == "true":
+		return ReasonProtect
+	case strings.Contains(cm.Annotations["argocd.argoproj.io/sync-options"], "PruneLast=true"):
+		return ReasonPrune
+	// Skip if ConfigMap is too new (not older than keepDays)
+	case now.Sub(cm.CreationTimestamp) < keepDuration:
+		return ReasonKeepDays
+	default:
+		return ReasonDelete
+	}
 }
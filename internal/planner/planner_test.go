@@ -12,7 +12,7 @@ var baseTime = time.Date(2026, 2, 13, 0, 0, 0, 0, time.UTC)
 func TestPlan(t *testing.T) {
 	tests := []struct {
 		name            string
-		configMaps      []ConfigMapCandidate
+		candidates      []Candidate
 		inUse           map[string]bool
 		keepLast        int
 		keepDays        int
@@ -21,7 +21,7 @@ func TestPlan(t *testing.T) {
 	}{
 		{
 			name:            "No ConfigMaps",
-			configMaps:      []ConfigMapCandidate{},
+			candidates:      []Candidate{},
 			inUse:           map[string]bool{},
 			keepLast:        5, // revisiotnHistoryLimit + 2
 			keepDays:        7,
@@ -30,7 +30,7 @@ func TestPlan(t *testing.T) {
 		},
 		{
 			name: "keepLast=5 with 5 CMs and 4 in-use: no deletion",
-			configMaps: []ConfigMapCandidate{
+			candidates: []Candidate{
 				{Name: "e6120fae", CreationTimestamp: baseTime.Add(-5 * 24 * time.Hour)},
 				{Name: "b870a608", CreationTimestamp: baseTime.Add(-10 * 24 * time.Hour)},
 				{Name: "f3bca2cb", CreationTimestamp: baseTime.Add(-15 * 24 * time.Hour)},
@@ -45,7 +45,7 @@ func TestPlan(t *testing.T) {
 		},
 		{
 			name: "keepLast=4 with 5 CMs and 4 in-use: delete oldest one",
-			configMaps: []ConfigMapCandidate{
+			candidates: []Candidate{
 				{Name: "e6120fae", CreationTimestamp: baseTime.Add(-5 * 24 * time.Hour)},
 				{Name: "b870a608", CreationTimestamp: baseTime.Add(-10 * 24 * time.Hour)},
 				{Name: "f3bca2cb", CreationTimestamp: baseTime.Add(-15 * 24 * time.Hour)},
@@ -58,12 +58,52 @@ func TestPlan(t *testing.T) {
 			now:             baseTime,
 			expectedDeletes: []string{"da8762a8"},
 		},
+		{
+			name: "revision-history retention (limit=2 → keepLast=3, keepDays=0): young CMs beyond the limit are deleted",
+			candidates: []Candidate{
+				{Name: "e6120fae", CreationTimestamp: baseTime.Add(-1 * time.Hour)},
+				{Name: "b870a608", CreationTimestamp: baseTime.Add(-2 * time.Hour)},
+				{Name: "f3bca2cb", CreationTimestamp: baseTime.Add(-3 * time.Hour)},
+				{Name: "d5eb6ebf", CreationTimestamp: baseTime.Add(-4 * time.Hour)},
+				{Name: "da8762a8", CreationTimestamp: baseTime.Add(-5 * time.Hour)},
+			},
+			inUse:           map[string]bool{"e6120fae": true, "b870a608": true, "f3bca2cb": true},
+			keepLast:        3,
+			keepDays:        0,
+			now:             baseTime,
+			expectedDeletes: []string{"d5eb6ebf", "da8762a8"},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := Plan(tt.configMaps, tt.inUse, tt.keepLast, tt.keepDays, tt.now)
+			result := Plan(tt.candidates, tt.inUse, tt.keepLast, tt.keepDays, tt.now)
 			assert.ElementsMatch(t, tt.expectedDeletes, result)
 		})
 	}
 }
+
+func TestExplain(t *testing.T) {
+	cms := []Candidate{
+		{Name: "e6120fae", CreationTimestamp: baseTime.Add(-1 * 24 * time.Hour)},
+		{Name: "b870a608", CreationTimestamp: baseTime.Add(-10 * 24 * time.Hour)},
+		{Name: "f3bca2cb", CreationTimestamp: baseTime.Add(-15 * 24 * time.Hour)},
+		{Name: "d5eb6ebf", CreationTimestamp: baseTime.Add(-20 * 24 * time.Hour), Annotations: map[string]string{"gc.k8s.io/protect": "true"}},
+		{Name: "da8762a8", CreationTimestamp: baseTime.Add(-30 * 24 * time.Hour)},
+		{Name: "c0ffee00", CreationTimestamp: baseTime.Add(-40 * 24 * time.Hour)},
+	}
+	inUse := map[string]bool{"e6120fae": true, "f3bca2cb": true}
+	// f3bca2cb is both in use and the blue-green preview: the strategy reason wins.
+	strategy := map[string]bool{"f3bca2cb": true, "c0ffee00": true}
+
+	got := Explain(cms, inUse, strategy, 2, 7, baseTime)
+
+	assert.Equal(t, []Decision{
+		{Name: "e6120fae", Reason: ReasonInUse},
+		{Name: "b870a608", Reason: ReasonKeepLast},
+		{Name: "f3bca2cb", Reason: ReasonStrategy},
+		{Name: "d5eb6ebf", Reason: ReasonProtect},
+		{Name: "da8762a8", Delete: true, Reason: ReasonDelete},
+		{Name: "c0ffee00", Reason: ReasonStrategy},
+	}, got)
+}
@@ -0,0 +1,220 @@
+package report
+
+// Machine-readable plan reports.
+// A run records every candidate it considered — planned or held back — with
+// its age, size, in-use status, decision and reason, and writes them at exit
+// as JSON, YAML, an aligned text table or Markdown. Entries are sorted by
+// namespace, rollout, resource, family and name, so reports of two runs can
+// be diffed.
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Supported report formats.
+const (
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatTable    = "table"
+	FormatMarkdown = "markdown"
+)
+
+// Formats lists the supported report formats.
+var Formats = []string{FormatJSON, FormatYAML, FormatTable, FormatMarkdown}
+
+// Decisions recorded for a candidate. A dry run records the candidates it
+// would delete as DecisionDelete; a real run records the deletion outcome.
+const (
+	DecisionDelete  = "delete"
+	DecisionDeleted = "deleted"
+	DecisionFailed  = "failed"
+	// DecisionNotAttempted marks candidates planned for deletion that the run
+	// stopped before, e.g. on a signal.
+	DecisionNotAttempted = "not-attempted"
+	DecisionKeep         = "keep"
+	// DecisionSkip marks candidates of a family that was not planned because
+	// an owning rollout was held back.
+	DecisionSkip = "skip"
+)
+
+// Report is the plan of one run. It is safe for concurrent use.
+type Report struct {
+	GeneratedAt time.Time   `json:"generatedAt"`
+	DryRun      bool        `json:"dryRun"`
+	Namespaces  []Namespace `json:"namespaces"`
+
+	mu sync.Mutex
+}
+
+// Namespace groups the candidates of one namespace by owning rollout.
+type Namespace struct {
+	Name     string    `json:"name"`
+	Rollouts []Rollout `json:"rollouts"`
+}
+
+// Rollout lists the candidates of the families owned by one rollout; Name is
+// comma-separated when families are shared by several rollouts.
+type Rollout struct {
+	Name       string      `json:"name"`
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Candidate is one versioned object and what the run decided for it.
+type Candidate struct {
+	Resource  string    `json:"resource"`
+	Family    string    `json:"family"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	AgeDays   int       `json:"ageDays"`
+	// SizeBytes is the size of the object's keys and values; nil when it
+	// could not be determined.
+	SizeBytes *int64 `json:"sizeBytes,omitempty"`
+	InUse     bool   `json:"inUse"`
+	Decision  string `json:"decision"`
+	Reason    string `json:"reason"`
+}
+
+// New returns an empty report generated at now.
+func New(now time.Time, dryRun bool) *Report {
+	return &Report{GeneratedAt: now.UTC(), DryRun: dryRun, Namespaces: []Namespace{}}
+}
+
+// Add records candidate c of rollout in namespace ns.
+func (r *Report) Add(ns, rollout string, c Candidate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := slices.IndexFunc(r.Namespaces, func(n Namespace) bool { return n.Name == ns })
+	if i < 0 {
+		r.Namespaces = append(r.Namespaces, Namespace{Name: ns})
+		i = len(r.Namespaces) - 1
+	}
+	namespace := &r.Namespaces[i]
+	j := slices.IndexFunc(namespace.Rollouts, func(ro Rollout) bool { return ro.Name == rollout })
+	if j < 0 {
+		namespace.Rollouts = append(namespace.Rollouts, Rollout{Name: rollout})
+		j = len(namespace.Rollouts) - 1
+	}
+	namespace.Rollouts[j].Candidates = append(namespace.Rollouts[j].Candidates, c)
+}
+
+// Write sorts the report and writes it to w in the given format.
+func (r *Report) Write(w io.Writer, format string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sort()
+	switch format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r)
+	case FormatYAML:
+		out, err := yaml.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(out)
+		return err
+	case FormatTable:
+		return r.writeTable(w)
+	case FormatMarkdown:
+		return r.writeMarkdown(w)
+	default:
+		return fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+// sort orders namespaces, rollouts and candidates deterministically.
+func (r *Report) sort() {
+	slices.SortFunc(r.Namespaces, func(a, b Namespace) int { return strings.Compare(a.Name, b.Name) })
+	for _, ns := range r.Namespaces {
+		slices.SortFunc(ns.Rollouts, func(a, b Rollout) int { return strings.Compare(a.Name, b.Name) })
+		for _, ro := range ns.Rollouts {
+			slices.SortFunc(ro.Candidates, func(a, b Candidate) int {
+				if c := strings.Compare(a.Resource, b.Resource); c != 0 {
+					return c
+				}
+				if c := strings.Compare(a.Family, b.Family); c != 0 {
+					return c
+				}
+				return strings.Compare(a.Name, b.Name)
+			})
+		}
+	}
+}
+
+// columns are the headers of the table and Markdown formats.
+var columns = []string{"NAMESPACE", "ROLLOUT", "RESOURCE", "NAME", "AGE", "SIZE", "IN-USE", "DECISION", "REASON"}
+
+// rows returns one table row per candidate.
+func (r *Report) rows() [][]string {
+	var rows [][]string
+	for _, ns := range r.Namespaces {
+		for _, ro := range ns.Rollouts {
+			for _, c := range ro.Candidates {
+				rows = append(rows, []string{
+					ns.Name, ro.Name, c.Resource, c.Name,
+					strconv.Itoa(c.AgeDays) + "d", c.size(),
+					strconv.FormatBool(c.InUse), c.Decision, c.Reason,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+// size returns the human-readable size, "-" when unknown.
+func (c Candidate) size() string {
+	if c.SizeBytes == nil {
+		return "-"
+	}
+	const unit = 1024
+	size := *c.SizeBytes
+	if size < unit {
+		return strconv.FormatInt(size, 10) + "B"
+	}
+	value, suffix := float64(size)/unit, "KiB"
+	if value >= unit {
+		value, suffix = value/unit, "MiB"
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + suffix
+}
+
+// writeTable writes an aligned text table, kubectl style.
+func (r *Report) writeTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(columns, "\t"))
+	for _, row := range r.rows() {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// writeMarkdown writes a heading stating the run mode and a Markdown table.
+func (r *Report) writeMarkdown(w io.Writer) error {
+	mode := "deletions performed"
+	if r.DryRun {
+		mode = "dry run"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# cm-gc plan\n\nGenerated at %s (%s).\n\n", r.GeneratedAt.Format(time.RFC3339), mode)
+	b.WriteString("| " + strings.Join(columns, " | ") + " |\n")
+	b.WriteString(strings.Repeat("| --- ", len(columns)) + "|\n")
+	for _, row := range r.rows() {
+		for i, cell := range row {
+			row[i] = strings.ReplaceAll(cell, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
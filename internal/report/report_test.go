@@ -0,0 +1,131 @@
+package report
+
+// Unit tests for report.go: every format must list the same candidates in the
+// same deterministic order, whatever order they were added in.
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+var generatedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+// sampleReport returns a report with candidates added out of order.
+func sampleReport() *Report {
+	size := int64(2048)
+	r := New(generatedAt, true)
+	r.Add("team-b", "web", Candidate{
+		Resource: "ConfigMap", Family: "web-config-{hash}", Name: "web-config-bbbbbbbb",
+		CreatedAt: generatedAt.AddDate(0, 0, -3), AgeDays: 3, SizeBytes: &size,
+		Decision: DecisionKeep, Reason: "within keep-last",
+	})
+	r.Add("team-a", "seat", Candidate{
+		Resource: "Secret", Family: "seat-secret-{hash}", Name: "seat-secret-cccccccc",
+		CreatedAt: generatedAt.AddDate(0, 0, -1), AgeDays: 1,
+		Decision: DecisionSkip, Reason: "rollout not in an allowed phase",
+	})
+	r.Add("team-a", "seat", Candidate{
+		Resource: "ConfigMap", Family: "seat-config-{hash}", Name: "seat-config-bbbbbbbb",
+		CreatedAt: generatedAt.AddDate(0, 0, -2), AgeDays: 2, InUse: true,
+		Decision: DecisionKeep, Reason: "in-use",
+	})
+	r.Add("team-a", "seat", Candidate{
+		Resource: "ConfigMap", Family: "seat-config-{hash}", Name: "seat-config-aaaaaaaa",
+		CreatedAt: generatedAt.AddDate(0, 0, -30), AgeDays: 30,
+		Decision: DecisionDelete, Reason: "not in-use, not in keep-last, older than keep-days",
+	})
+	return r
+}
+
+func TestReport_JSONIsSorted(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, sampleReport().Write(&buf, FormatJSON))
+
+	var got Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.True(t, got.DryRun)
+	assert.Equal(t, generatedAt, got.GeneratedAt)
+	require.Len(t, got.Namespaces, 2)
+	assert.Equal(t, "team-a", got.Namespaces[0].Name)
+	var names []string
+	for _, c := range got.Namespaces[0].Rollouts[0].Candidates {
+		names = append(names, c.Name)
+	}
+	assert.Equal(t, []string{"seat-config-aaaaaaaa", "seat-config-bbbbbbbb", "seat-secret-cccccccc"}, names)
+	assert.Nil(t, got.Namespaces[0].Rollouts[0].Candidates[0].SizeBytes, "unknown sizes are omitted")
+	assert.Equal(t, int64(2048), *got.Namespaces[1].Rollouts[0].Candidates[0].SizeBytes)
+}
+
+func TestReport_YAMLMatchesJSON(t *testing.T) {
+	var jsonOut, yamlOut bytes.Buffer
+	require.NoError(t, sampleReport().Write(&jsonOut, FormatJSON))
+	require.NoError(t, sampleReport().Write(&yamlOut, FormatYAML))
+
+	converted, err := yaml.YAMLToJSON(yamlOut.Bytes())
+	require.NoError(t, err)
+	assert.JSONEq(t, jsonOut.String(), string(converted))
+}
+
+func TestReport_Table(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, sampleReport().Write(&buf, FormatTable))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	assert.Equal(t, []string{"NAMESPACE", "ROLLOUT", "RESOURCE", "NAME", "AGE", "SIZE", "IN-USE", "DECISION", "REASON"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"team-a", "seat", "ConfigMap", "seat-config-aaaaaaaa", "30d", "-", "false", "delete"}, strings.Fields(lines[1])[:8])
+	assert.Equal(t, []string{"team-b", "web", "ConfigMap", "web-config-bbbbbbbb", "3d", "2.0KiB", "false", "keep"}, strings.Fields(lines[4])[:8])
+}
+
+func TestReport_Markdown(t *testing.T) {
+	r := New(generatedAt, false)
+	r.Add("team-a", "seat,seat-canary", Candidate{
+		Resource: "ConfigMap", Family: "seat-config-{hash}", Name: "seat-config-aaaaaaaa",
+		Decision: DecisionKeep, Reason: "a|b",
+	})
+	var buf bytes.Buffer
+	require.NoError(t, r.Write(&buf, FormatMarkdown))
+
+	out := buf.String()
+	assert.Contains(t, out, "Generated at 2026-03-01T12:00:00Z (deletions performed).")
+	assert.Contains(t, out, "| NAMESPACE | ROLLOUT |")
+	assert.Contains(t, out, "| team-a | seat,seat-canary | ConfigMap | seat-config-aaaaaaaa | 0d | - | false | keep | a\\|b |")
+}
+
+func TestReport_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, New(generatedAt, true).Write(&buf, FormatJSON))
+	assert.Contains(t, buf.String(), `"namespaces": []`)
+}
+
+func TestReport_ConcurrentAdd(t *testing.T) {
+	r := New(generatedAt, true)
+	var wg sync.WaitGroup
+	for _, ns := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				r.Add(ns, "seat", Candidate{Name: "cm"})
+			}
+		}()
+	}
+	wg.Wait()
+
+	require.Len(t, r.Namespaces, 4)
+	for _, ns := range r.Namespaces {
+		assert.Len(t, ns.Rollouts[0].Candidates, 50)
+	}
+}
+
+func TestReport_UnsupportedFormat(t *testing.T) {
+	assert.Error(t, New(generatedAt, true).Write(&bytes.Buffer{}, "pdf"))
+}